@@ -0,0 +1,136 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IsCgroup2 returns true if the cgroup root is the cgroup v2 unified hierarchy
+func IsCgroup2(root string) bool {
+	_, err := os.Stat(path.Join(root, "cgroup.controllers"))
+	return err == nil
+}
+
+// Cgroup2Path returns the cgroup v2 path of the pid, relative to the cgroup root
+func Cgroup2Path(pid int) (string, error) {
+	p := fmt.Sprintf("/proc/%d/cgroup", pid)
+	file, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// 0::/kubepods.slice/kubepods-pod1.slice/cri-containerd-1.scope
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) == 3 && parts[0] == "0" && parts[1] == "" {
+			return parts[2], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("cgroup v2 path not found in %s", p)
+}
+
+// Cgroup2 reads the controller files of a cgroup v2 group
+type Cgroup2 struct {
+	Path string
+}
+
+// LoadCgroup2 returns the cgroup v2 group of the pid under the cgroup root
+func LoadCgroup2(root string, pid int) (*Cgroup2, error) {
+	p, err := Cgroup2Path(pid)
+	if err != nil {
+		return nil, err
+	}
+	groupPath := path.Join(root, p)
+	if _, err := os.Stat(groupPath); err != nil {
+		return nil, err
+	}
+	return &Cgroup2{Path: groupPath}, nil
+}
+
+// CPUUsage returns the usage_usec of cpu.stat
+func (c *Cgroup2) CPUUsage() (time.Duration, error) {
+	stat, err := c.readKeyValues("cpu.stat")
+	if err != nil {
+		return 0, err
+	}
+	usage, ok := stat["usage_usec"]
+	if !ok {
+		return 0, fmt.Errorf("usage_usec not found in %s", path.Join(c.Path, "cpu.stat"))
+	}
+	return time.Duration(usage) * time.Microsecond, nil
+}
+
+// MemoryCurrent returns the value of memory.current
+func (c *Cgroup2) MemoryCurrent() (uint64, error) {
+	return c.readUint("memory.current")
+}
+
+// MemoryMax returns the value of memory.max, math.MaxUint64 is returned if it is max
+func (c *Cgroup2) MemoryMax() (uint64, error) {
+	return c.readUint("memory.max")
+}
+
+// MemoryStat returns the key values of memory.stat
+func (c *Cgroup2) MemoryStat() (map[string]uint64, error) {
+	return c.readKeyValues("memory.stat")
+}
+
+func (c *Cgroup2) readUint(name string) (uint64, error) {
+	bytes, err := os.ReadFile(path.Join(c.Path, name))
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(bytes))
+	if value == "max" {
+		return math.MaxUint64, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+func (c *Cgroup2) readKeyValues(name string) (map[string]uint64, error) {
+	file, err := os.Open(path.Join(c.Path, name))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[fields[0]] = value
+	}
+	return values, scanner.Err()
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"math"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestCgroup2Stat(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"cgroup.controllers": "cpuset cpu io memory pids\n",
+		"cpu.stat":           "usage_usec 2500000\nuser_usec 2000000\nsystem_usec 500000\n",
+		"memory.current":     "104857600\n",
+		"memory.max":         "max\n",
+		"memory.stat":        "anon 52428800\nfile 20971520\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !IsCgroup2(dir) {
+		t.Errorf("%s should be cgroup v2", dir)
+	}
+	cgroup := &Cgroup2{Path: dir}
+	if usage, err := cgroup.CPUUsage(); err != nil || usage != 2500*time.Millisecond {
+		t.Errorf("unexpected cpu usage %v, err %v", usage, err)
+	}
+	if current, err := cgroup.MemoryCurrent(); err != nil || current != 104857600 {
+		t.Errorf("unexpected memory current %d, err %v", current, err)
+	}
+	if limit, err := cgroup.MemoryMax(); err != nil || limit != math.MaxUint64 {
+		t.Errorf("unexpected memory max %d, err %v", limit, err)
+	}
+	if stat, err := cgroup.MemoryStat(); err != nil || stat["file"] != 20971520 {
+		t.Errorf("unexpected memory stat %v, err %v", stat, err)
+	}
+}
@@ -0,0 +1,31 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+)
+
+// JoinTargetCgroup is not supported on darwin, it fails if the cgroup-path or container-id flag is specified
+func JoinTargetCgroup(ctx context.Context, flags map[string]string) (context.Context, *spec.Response) {
+	if flags[CgroupPathFlag.Name] == "" && flags[ContainerIdFlag.Name] == "" {
+		return ctx, nil
+	}
+	return ctx, spec.ResponseFailWithFlags(spec.ParameterInvalid, "cgroup-path|container-id", "", "cgroup is not supported on darwin")
+}
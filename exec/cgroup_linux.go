@@ -0,0 +1,209 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+)
+
+// the cgroup v1 controllers which the burn processes join
+var cgroupV1Controllers = []string{"cpu", "cpuacct", "cpuset", "memory", "blkio", "pids"}
+
+// TargetCgroup is the cgroup matched by the cgroup-path or container-id flag
+type TargetCgroup struct {
+	Root string
+	// Path is relative to the cgroup root for cgroup v2, or relative to the controller directory for cgroup v1
+	Path string
+	V2   bool
+}
+
+// ResolveTargetCgroup returns the cgroup of the cgroup path or the container, which supports
+// the docker layouts, for example docker/<id> or system.slice/docker-<id>.scope, and the containerd layouts,
+// for example kubepods/<pod>/<id> or kubepods.slice/.../cri-containerd-<id>.scope
+func ResolveTargetCgroup(root, cgroupPath, containerId string) (*TargetCgroup, error) {
+	if root == "" {
+		root = "/sys/fs/cgroup"
+	}
+	target := &TargetCgroup{Root: root, V2: IsCgroup2(root)}
+	base := root
+	if !target.V2 {
+		// all v1 controllers share the same layout, so the cpu controller is used to search
+		base = path.Join(root, "cpu")
+	}
+	if cgroupPath != "" {
+		target.Path = path.Join("/", cgroupPath)
+		if !isDir(path.Join(base, target.Path)) {
+			return nil, fmt.Errorf("cgroup %s not found under %s", target.Path, base)
+		}
+		return target, nil
+	}
+	if containerId == "" {
+		return nil, fmt.Errorf("less cgroup-path or container-id")
+	}
+	found := ""
+	err := filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if matchContainerCgroup(d.Name(), containerId) {
+			found = p
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == "" {
+		return nil, fmt.Errorf("cgroup of container %s not found under %s", containerId, base)
+	}
+	target.Path = path.Join("/", strings.TrimPrefix(found, base))
+	return target, nil
+}
+
+// matchContainerCgroup returns true if the cgroup directory belongs to the container,
+// the container id can be the short one
+func matchContainerCgroup(name, containerId string) bool {
+	for _, prefix := range []string{"docker-", "cri-containerd-", "crio-", "containerd-"} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	name = strings.TrimSuffix(name, ".scope")
+	return len(containerId) >= 12 && strings.HasPrefix(name, containerId) || name == containerId
+}
+
+// Dirs returns the cgroup directories of the target, one per controller for cgroup v1
+func (t *TargetCgroup) Dirs() []string {
+	if t.V2 {
+		return []string{path.Join(t.Root, t.Path)}
+	}
+	dirs := make([]string, 0)
+	for _, controller := range cgroupV1Controllers {
+		dir := path.Join(t.Root, controller, t.Path)
+		if isDir(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// Pid returns a process in the cgroup, the child cgroups are searched if the cgroup has no process
+func (t *TargetCgroup) Pid() (int, error) {
+	dirs := t.Dirs()
+	if len(dirs) == 0 {
+		return 0, fmt.Errorf("cgroup %s not found", t.Path)
+	}
+	pid := 0
+	_ = filepath.WalkDir(dirs[0], func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		bytes, err := os.ReadFile(path.Join(p, "cgroup.procs"))
+		if err != nil {
+			return nil
+		}
+		for _, field := range strings.Fields(string(bytes)) {
+			if id, err := strconv.Atoi(field); err == nil && id != os.Getpid() {
+				pid = id
+				return filepath.SkipAll
+			}
+		}
+		return nil
+	})
+	if pid == 0 {
+		return 0, fmt.Errorf("no process in cgroup %s", t.Path)
+	}
+	return pid, nil
+}
+
+// AddProc moves the process into the cgroup
+func (t *TargetCgroup) AddProc(pid int) error {
+	dirs := t.Dirs()
+	if len(dirs) == 0 {
+		return fmt.Errorf("cgroup %s not found", t.Path)
+	}
+	for _, dir := range dirs {
+		if err := os.WriteFile(path.Join(dir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+			return fmt.Errorf("add process %d to %s failed, %v", pid, dir, err)
+		}
+	}
+	return nil
+}
+
+// PidsFile returns the file of the pids controller of the cgroup, for example pids.max
+func (t *TargetCgroup) PidsFile(name string) string {
+	if t.V2 {
+		return path.Join(t.Root, t.Path, name)
+	}
+	return path.Join(t.Root, "pids", t.Path, name)
+}
+
+// FreezerFile returns the freezer control file of the cgroup together with the values to freeze and thaw it,
+// which is cgroup.freeze for cgroup v2 and freezer.state of the freezer controller for cgroup v1
+func (t *TargetCgroup) FreezerFile() (string, string, string, error) {
+	file, freeze, thaw := path.Join(t.Root, t.Path, "cgroup.freeze"), "1", "0"
+	if !t.V2 {
+		file, freeze, thaw = path.Join(t.Root, "freezer", t.Path, "freezer.state"), "FROZEN", "THAWED"
+	}
+	if _, err := os.Stat(file); err != nil {
+		return "", "", "", fmt.Errorf("the freezer of cgroup %s is not found, %v", t.Path, err)
+	}
+	return file, freeze, thaw, nil
+}
+
+// JoinTargetCgroup moves the current process into the cgroup matched by the cgroup-path or container-id flag,
+// and sets a process of the cgroup as the ns target, so that the load is calibrated against the cgroup limits.
+// The resolved cgroup path is set to the context by the cgroup-path key. It does nothing if both flags are absent.
+func JoinTargetCgroup(ctx context.Context, flags map[string]string) (context.Context, *spec.Response) {
+	cgroupPath := flags[CgroupPathFlag.Name]
+	containerId := flags[ContainerIdFlag.Name]
+	if cgroupPath == "" && containerId == "" {
+		return ctx, nil
+	}
+	target, err := ResolveTargetCgroup(flags["cgroup-root"], cgroupPath, containerId)
+	if err != nil {
+		log.Errorf(ctx, "resolve target cgroup err, %v", err)
+		return ctx, spec.ResponseFailWithFlags(spec.ParameterInvalid, "cgroup-path|container-id", cgroupPath+containerId, err)
+	}
+	pid, err := target.Pid()
+	if err != nil {
+		log.Errorf(ctx, "get the process of target cgroup err, %v", err)
+		return ctx, spec.ResponseFailWithFlags(spec.ParameterInvalid, "cgroup-path|container-id", cgroupPath+containerId, err)
+	}
+	if err := target.AddProc(os.Getpid()); err != nil {
+		log.Errorf(ctx, "join target cgroup err, %v", err)
+		return ctx, spec.ReturnFail(spec.OsCmdExecFailed, err.Error())
+	}
+	log.Infof(ctx, "process %d joined cgroup %s, target pid: %d", os.Getpid(), target.Path, pid)
+	ctx = context.WithValue(ctx, CgroupPathFlag.Name, target.Path)
+	return context.WithValue(ctx, channel.NSTargetFlagName, strconv.Itoa(pid)), nil
+}
+
+func isDir(p string) bool {
+	info, err := os.Stat(p)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestResolveTargetCgroup(t *testing.T) {
+	containerId := "4f2a9c1b7e3d5a6b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b"
+	tests := []struct {
+		name   string
+		v2     bool
+		layout string
+		id     string
+	}{
+		{"docker v1", false, "docker/" + containerId, containerId},
+		{"containerd v1 short id", false, "kubepods/burstable/pod1/" + containerId, containerId[:12]},
+		{"docker v2", true, "system.slice/docker-" + containerId + ".scope", containerId},
+		{"containerd v2", true, "kubepods.slice/kubepods-pod1.slice/cri-containerd-" + containerId + ".scope", containerId[:12]},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		base := root
+		if tt.v2 {
+			if err := os.WriteFile(path.Join(root, "cgroup.controllers"), []byte("cpu memory"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			base = path.Join(root, "cpu")
+		}
+		if err := os.MkdirAll(path.Join(base, tt.layout), 0755); err != nil {
+			t.Fatal(err)
+		}
+		target, err := ResolveTargetCgroup(root, "", tt.id)
+		if err != nil {
+			t.Errorf("%s: resolve err, %v", tt.name, err)
+			continue
+		}
+		if target.V2 != tt.v2 || target.Path != "/"+tt.layout {
+			t.Errorf("%s: unexpected target %+v", tt.name, target)
+		}
+		if _, err := ResolveTargetCgroup(root, "", "0123"); err == nil {
+			t.Errorf("%s: short id should not match", tt.name)
+		}
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"time"
 
 	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
 	"github.com/chaosblade-io/chaosblade-spec-go/log"
 
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
@@ -49,7 +50,10 @@ func NewCpuCommandModelSpec() spec.ExpModelCommandSpec {
 			ExpActions: []spec.ExpActionCommandSpec{
 				&FullLoadActionCommand{
 					spec.BaseExpActionCommandSpec{
-						ActionMatchers: []spec.ExpFlagSpec{},
+						ActionMatchers: []spec.ExpFlagSpec{
+							exec.CgroupPathFlag,
+							exec.ContainerIdFlag,
+						},
 						ActionFlags:    []spec.ExpFlagSpec{},
 						ActionExecutor: &cpuExecutor{},
 						ActionExample: `
@@ -66,7 +70,19 @@ blade create cpu load --cpu-list 0,3
 blade create cpu load --cpu-list 1-3
 
 # Specified percentage load
-blade create cpu load --cpu-percent 60`,
+blade create cpu load --cpu-percent 60
+
+# Specified percentage load of the container, the load counts against the container cpu quota
+blade create cpu load --cpu-percent 60 --container-id 4f2a9c1b7e3d
+
+# Step the load through 30%, 80% and 50%, each for 10 seconds, repeatedly
+blade create cpu load --profile step:30,80,50@10s
+
+# The load goes between 20% and 80% as a sine wave with the period of 60 seconds
+blade create cpu load --profile sine:20-80@60s
+
+# The load is 20% and spikes to 90% for 5 seconds by the probability of 10%
+blade create cpu load --profile spike:20-90@5s,10%`,
 						ActionPrograms:    []string{BurnCpuBin},
 						ActionCategories:  []string{category.SystemCpu},
 						ActionProcessHang: true,
@@ -99,6 +115,11 @@ blade create cpu load --cpu-percent 60`,
 					Desc:     "durations(s) to climb",
 					Required: false,
 				},
+				&spec.ExpFlag{
+					Name:     "profile",
+					Desc:     "the cpu percent changing over time instead of cpu-percent, step:30,80,50@10s holds each percent for 10s in turn, sawtooth:20-80@60s or sine:20-80@60s goes between 20 and 80 every 60s, spike:20-90@5s,10% spikes to 90 for 5s by 10% probability",
+					Required: false,
+				},
 				&spec.ExpFlag{
 					Name:     "cgroup-root",
 					Desc:     "cgroup root path, default value /sys/fs/cgroup",
@@ -236,13 +257,45 @@ func (ce *cpuExecutor) Exec(uid string, ctx context.Context, model *spec.ExpMode
 		}
 	}
 
+	profile := model.ActionFlags["profile"]
+	if profile != "" {
+		if cpuPercentStr != "" || climbTime != 0 {
+			log.Errorf(ctx, "`%s`: Exec-profile cannot be used with cpu-percent or climb-time", profile)
+			return spec.ResponseFailWithFlags(spec.ParameterInvalid, "profile", profile, "cannot be used with cpu-percent or climb-time")
+		}
+		if _, err := parseLoadProfile(profile); err != nil {
+			log.Errorf(ctx, "`%s`: Exec-profile is illegal, %v", profile, err)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "profile", profile, err)
+		}
+	}
+
 	ctx = context.WithValue(ctx, "cgroup-root", model.ActionFlags["cgroup-root"])
+	ctx, response := exec.JoinTargetCgroup(ctx, model.ActionFlags)
+	if response != nil {
+		return response
+	}
 
-	return ce.start(ctx, cpuList, cpuCount, cpuPercent, climbTime, model.ActionFlags["cpu-index"])
+	return ce.start(ctx, cpuList, cpuCount, cpuPercent, climbTime, model.ActionFlags["cpu-index"], profile)
+}
+
+// cpuListChildArgs returns the command of the child burning the core of the cpu list. The profile cannot be used
+// with cpu-percent or climb-time, so they are passed to the child only if the profile is not set.
+func cpuListChildArgs(ctx context.Context, core string, cpuPercent, climbTime int, profileStr string) string {
+	args := fmt.Sprintf(`%s start create cpu fullload --cpu-count 1 --cpu-index %s --uid %s`, os.Args[0], core, ctx.Value(spec.Uid))
+	if profileStr != "" {
+		args = fmt.Sprintf("%s --profile %s", args, profileStr)
+	} else {
+		args = fmt.Sprintf("%s --cpu-percent %d --climb-time %d", args, cpuPercent, climbTime)
+	}
+	// the child process inherits the cgroup, the cgroup path is used to calibrate the load
+	if cgroupPath, ok := ctx.Value(exec.CgroupPathFlag.Name).(string); ok {
+		args = fmt.Sprintf("%s --cgroup-root %s --cgroup-path %s", args, ctx.Value("cgroup-root"), cgroupPath)
+	}
+	return args
 }
 
 // start burn cpu
-func (ce *cpuExecutor) start(ctx context.Context, cpuList string, cpuCount, cpuPercent, climbTime int, cpuIndexStr string) *spec.Response {
+func (ce *cpuExecutor) start(ctx context.Context, cpuList string, cpuCount, cpuPercent, climbTime int, cpuIndexStr, profileStr string) *spec.Response {
 	ctx = context.WithValue(ctx, "cpuCount", cpuCount)
 	if cpuList != "" {
 		cores, err := util.ParseIntegerListToStringSlice("cpu-list", cpuList)
@@ -251,11 +304,7 @@ func (ce *cpuExecutor) start(ctx context.Context, cpuList string, cpuCount, cpuP
 			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "cpu-list", cpuList, err.Error())
 		}
 		for _, core := range cores {
-
-			args := fmt.Sprintf(`%s start create cpu fullload --cpu-count 1 --cpu-percent %d --climb-time %d --cpu-index %s --uid %s`,
-				os.Args[0], cpuPercent, climbTime, core, ctx.Value(spec.Uid))
-
-			args = fmt.Sprintf("-c %s %s", core, args)
+			args := fmt.Sprintf("-c %s %s", core, cpuListChildArgs(ctx, core, cpuPercent, climbTime, profileStr))
 			argsArray := strings.Split(args, " ")
 			command := os_exec.CommandContext(ctx, "taskset", argsArray...)
 			command.SysProcAttr = &syscall.SysProcAttr{}
@@ -263,6 +312,7 @@ func (ce *cpuExecutor) start(ctx context.Context, cpuList string, cpuCount, cpuP
 			if err := command.Start(); err != nil {
 				return spec.ReturnFail(spec.OsCmdExecFailed, fmt.Sprintf("start taskset exec failed, %v", err))
 			}
+			store.RecordPid(ctx, command.Process.Pid)
 		}
 		return spec.ReturnSuccess(ctx.Value(spec.Uid))
 	}
@@ -283,6 +333,19 @@ func (ce *cpuExecutor) start(ctx context.Context, cpuList string, cpuCount, cpuP
 		}
 	}
 
+	// the target percent follows the profile from the start of the load
+	var profile loadProfile
+	if profileStr != "" {
+		var err error
+		profile, err = parseLoadProfile(profileStr)
+		if err != nil {
+			log.Errorf(ctx, "`%s`: start-profile is illegal, %v", profileStr, err)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "profile", profileStr, err)
+		}
+		slopePercent = profile.percent(0)
+	}
+	startTime := time.Now()
+
 	// make CPU slowly climb to some level, to simulate slow resource competition
 	// which system faults cannot be quickly noticed by monitoring system.
 	slope(ctx, cpuPercent, climbTime, &slopePercent, percpu, cpuIndex)
@@ -293,6 +356,9 @@ func (ce *cpuExecutor) start(ctx context.Context, cpuList string, cpuCount, cpuP
 	}
 
 	for {
+		if profile != nil {
+			slopePercent = profile.percent(time.Since(startTime))
+		}
 		q := getQuota(ctx, slopePercent, percpu, cpuIndex)
 		for i := 0; i < cpuCount; i++ {
 			quota <- q
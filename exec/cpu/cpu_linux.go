@@ -45,6 +45,10 @@ func getUsed(ctx context.Context, percpu bool, cpuIndex int) float64 {
 
 		log.Debugf(ctx, "get cpu useage by cgroup, root path: %s", cgroupRoot)
 
+		if exec.IsCgroup2(cgroupRoot.(string)) {
+			return getUsedByCgroup2(ctx, cgroupRoot.(string), p, cpuCount)
+		}
+
 		cgroup, err := cgroups.Load(exec.Hierarchy(cgroupRoot.(string)), exec.PidPath(p))
 		if err != nil {
 			log.Fatalf(ctx, "get cpu usage fail, %s", err.Error())
@@ -78,3 +82,21 @@ func getUsed(ctx context.Context, percpu bool, cpuIndex int) float64 {
 	}
 	return totalCpuPercent[0]
 }
+
+// getUsedByCgroup2 returns the cpu usage of the cgroup v2 group by the usage_usec of cpu.stat
+func getUsedByCgroup2(ctx context.Context, cgroupRoot string, pid, cpuCount int) float64 {
+	cgroup, err := exec.LoadCgroup2(cgroupRoot, pid)
+	if err != nil {
+		log.Fatalf(ctx, "get cpu usage fail, %s", err.Error())
+	}
+	pre, err := cgroup.CPUUsage()
+	if err != nil {
+		log.Fatalf(ctx, "get cpu usage fail, %s", err.Error())
+	}
+	time.Sleep(time.Second)
+	next, err := cgroup.CPUUsage()
+	if err != nil {
+		log.Fatalf(ctx, "get cpu usage fail, %s", err.Error())
+	}
+	return ((next - pre).Seconds() * 100) / float64(cpuCount)
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cpu
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+)
+
+// minProfileDuration is the shortest step or period of the profile, the load is adjusted every second
+const minProfileDuration = time.Second
+
+// loadProfile is the cpu percent changing over time
+type loadProfile interface {
+	// percent returns the target cpu percent after the elapsed time since the load starts
+	percent(elapsed time.Duration) float64
+}
+
+// stepProfile holds each percent for the interval, the steps repeat after the last one
+type stepProfile struct {
+	percents []float64
+	interval time.Duration
+}
+
+func (p *stepProfile) percent(elapsed time.Duration) float64 {
+	return p.percents[int(elapsed/p.interval)%len(p.percents)]
+}
+
+// waveProfile goes between the min and max percent repeatedly, as a sawtooth or a sine wave
+type waveProfile struct {
+	min    float64
+	max    float64
+	period time.Duration
+	sine   bool
+}
+
+func (p *waveProfile) percent(elapsed time.Duration) float64 {
+	phase := float64(elapsed%p.period) / float64(p.period)
+	if p.sine {
+		return (p.min+p.max)/2 + (p.max-p.min)/2*math.Sin(2*math.Pi*phase)
+	}
+	return p.min + (p.max-p.min)*phase
+}
+
+// spikeProfile keeps the base percent and spikes to the peak percent for the duration by the probability,
+// the spike is decided once for each duration
+type spikeProfile struct {
+	base        float64
+	peak        float64
+	duration    time.Duration
+	probability int
+	window      int64
+	spiking     bool
+	random      *rand.Rand
+}
+
+func (p *spikeProfile) percent(elapsed time.Duration) float64 {
+	if window := int64(elapsed / p.duration); window != p.window {
+		p.window = window
+		p.spiking = p.random.Intn(100) < p.probability
+	}
+	if p.spiking {
+		return p.peak
+	}
+	return p.base
+}
+
+// parseLoadProfile parses the profile in the format of <shape>:<percents>@<duration>, for example
+// step:30,80,50@10s holds each percent for 10 seconds, sawtooth:20-80@60s and sine:20-80@60s go between 20 and 80
+// percent every 60 seconds, spike:20-90@5s,10% spikes from 20 to 90 percent for 5 seconds by the probability of 10%.
+// The duration without unit is seconds.
+func parseLoadProfile(value string) (loadProfile, error) {
+	shape, rest, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, fmt.Errorf("the profile %s must be <shape>:<percents>@<duration>", value)
+	}
+	levels, timing, ok := strings.Cut(rest, "@")
+	if !ok {
+		return nil, fmt.Errorf("the profile %s must be <shape>:<percents>@<duration>", value)
+	}
+	switch shape {
+	case "step":
+		percents, err := parseProfilePercents(strings.Split(levels, ","))
+		if err != nil {
+			return nil, err
+		}
+		interval, err := parseProfileDuration(timing)
+		if err != nil {
+			return nil, err
+		}
+		return &stepProfile{percents: percents, interval: interval}, nil
+	case "sawtooth", "sine":
+		percents, err := parseProfileRange(levels)
+		if err != nil {
+			return nil, err
+		}
+		period, err := parseProfileDuration(timing)
+		if err != nil {
+			return nil, err
+		}
+		return &waveProfile{min: percents[0], max: percents[1], period: period, sine: shape == "sine"}, nil
+	case "spike":
+		percents, err := parseProfileRange(levels)
+		if err != nil {
+			return nil, err
+		}
+		durationValue, probabilityValue, ok := strings.Cut(timing, ",")
+		if !ok || !strings.HasSuffix(probabilityValue, "%") {
+			return nil, fmt.Errorf("the spike profile %s must be spike:<base>-<peak>@<duration>,<probability>%%", value)
+		}
+		duration, err := parseProfileDuration(durationValue)
+		if err != nil {
+			return nil, err
+		}
+		probability, err := strconv.Atoi(strings.TrimSuffix(probabilityValue, "%"))
+		if err != nil || probability < 0 || probability > 100 {
+			return nil, fmt.Errorf("the probability %s must be in 0%%..100%%", probabilityValue)
+		}
+		return &spikeProfile{base: percents[0], peak: percents[1], duration: duration, probability: probability,
+			window: -1, random: rand.New(rand.NewSource(time.Now().UnixNano()))}, nil
+	}
+	return nil, fmt.Errorf("the profile shape %s is not supported, it must be step, sawtooth, sine or spike", shape)
+}
+
+// parseProfileRange parses the percents such as 20-80
+func parseProfileRange(value string) ([]float64, error) {
+	low, high, ok := strings.Cut(value, "-")
+	if !ok {
+		return nil, fmt.Errorf("the percents %s must be <percent>-<percent>", value)
+	}
+	return parseProfilePercents([]string{low, high})
+}
+
+func parseProfilePercents(values []string) ([]float64, error) {
+	percents := make([]float64, 0, len(values))
+	for _, value := range values {
+		percent, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("the percent %s must be in 0..100", value)
+		}
+		percents = append(percents, float64(percent))
+	}
+	return percents, nil
+}
+
+func parseProfileDuration(value string) (time.Duration, error) {
+	duration, err := exec.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("the duration %s is illegal, %v", value, err)
+	}
+	if duration < minProfileDuration {
+		return 0, fmt.Errorf("the duration %s must not be less than %s", value, minProfileDuration)
+	}
+	return duration, nil
+}
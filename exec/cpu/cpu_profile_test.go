@@ -0,0 +1,57 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cpu
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseLoadProfile(t *testing.T) {
+	tests := []struct {
+		profile string
+		elapsed time.Duration
+		expect  float64
+	}{
+		{"step:30,80,50@10s", 0, 30},
+		{"step:30,80,50@10s", 15 * time.Second, 80},
+		{"step:30,80,50@10", 25 * time.Second, 50},
+		{"step:30,80,50@10s", 35 * time.Second, 30},
+		{"sawtooth:20-80@60s", 30 * time.Second, 50},
+		{"sawtooth:20-80@60s", 75 * time.Second, 35},
+		{"sine:20-80@60s", 15 * time.Second, 80},
+		{"sine:20-80@60s", 45 * time.Second, 20},
+		{"spike:20-90@5s,0%", 7 * time.Second, 20},
+		{"spike:20-90@5s,100%", 7 * time.Second, 90},
+	}
+	for _, tt := range tests {
+		profile, err := parseLoadProfile(tt.profile)
+		if err != nil {
+			t.Fatalf("parseLoadProfile(%s) err, %v", tt.profile, err)
+		}
+		if percent := profile.percent(tt.elapsed); math.Abs(percent-tt.expect) > 0.001 {
+			t.Errorf("%s at %s is %f, want %f", tt.profile, tt.elapsed, percent, tt.expect)
+		}
+	}
+	for _, illegal := range []string{"", "step", "step:30,80", "step:30,180@10s", "sine:20@60s", "sine:20-80@500ms",
+		"spike:20-90@5s", "spike:20-90@5s,120%", "square:20-80@60s"} {
+		if _, err := parseLoadProfile(illegal); err == nil {
+			t.Errorf("parseLoadProfile(%s) expects err", illegal)
+		}
+	}
+}
@@ -17,8 +17,12 @@
 package cpu
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"testing"
 
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 	"github.com/chaosblade-io/chaosblade-spec-go/util"
 )
 
@@ -46,3 +50,21 @@ func TestParseCpuList(t *testing.T) {
 		}
 	}
 }
+
+func TestCpuListChildArgs(t *testing.T) {
+	ctx := context.WithValue(context.Background(), spec.Uid, "7c7b0f3a8d1e4c2b")
+	tests := []struct {
+		profile string
+		expect  string
+	}{
+		{"", "start create cpu fullload --cpu-count 1 --cpu-index 2 --uid 7c7b0f3a8d1e4c2b --cpu-percent 60 --climb-time 10"},
+		// the child rejects the profile with cpu-percent or climb-time
+		{"step:30,80@10s", "start create cpu fullload --cpu-count 1 --cpu-index 2 --uid 7c7b0f3a8d1e4c2b --profile step:30,80@10s"},
+	}
+	for _, tt := range tests {
+		args := cpuListChildArgs(ctx, "2", 60, 10, tt.profile)
+		if args != fmt.Sprintf("%s %s", os.Args[0], tt.expect) {
+			t.Errorf("cpuListChildArgs(%q) = %s, want %s", tt.profile, args, tt.expect)
+		}
+	}
+}
@@ -30,6 +30,8 @@ func NewDiskCommandSpec() spec.ExpModelCommandSpec {
 			ExpActions: []spec.ExpActionCommandSpec{
 				NewFillActionSpec(),
 				NewBurnActionSpec(),
+				NewDelayActionSpec(),
+				NewErrorActionSpec(),
 			},
 			ExpFlags: []spec.ExpFlagSpec{},
 		},
@@ -45,5 +47,5 @@ func (*DiskCommandSpec) ShortDesc() string {
 }
 
 func (*DiskCommandSpec) LongDesc() string {
-	return "Disk experiment contains fill disk or burn io"
+	return "Disk experiment contains fill disk, burn io, delay io or inject io errors"
 }
@@ -23,7 +23,7 @@ import (
 	"github.com/chaosblade-io/chaosblade-spec-go/channel"
 	"github.com/chaosblade-io/chaosblade-spec-go/log"
 	"path"
-	"strings"
+	"strconv"
 
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 	"github.com/chaosblade-io/chaosblade-spec-go/util"
@@ -58,6 +58,8 @@ func NewBurnActionSpec() spec.ExpActionCommandSpec {
 					Required: false,
 					Default:  "/sys/fs/cgroup",
 				},
+				exec.CgroupPathFlag,
+				exec.ContainerIdFlag,
 			},
 			ActionFlags: []spec.ExpFlagSpec{
 				&spec.ExpFlag{
@@ -68,6 +70,40 @@ func NewBurnActionSpec() spec.ExpActionCommandSpec {
 					Name: "path",
 					Desc: "The path of directory where the disk is burning, default value is /",
 				},
+				&spec.ExpFlag{
+					Name: "block-size",
+					Desc: "Block size with unit, for example 4k, 64k or 1m, it overrides the size flag",
+				},
+				&spec.ExpFlag{
+					Name: "mode",
+					Desc: "Access mode, sequential or random, default value is sequential",
+				},
+				&spec.ExpFlag{
+					Name: "read-percent",
+					Desc: "Percentage of reads when both read and write are specified, 0-100, default value is 50",
+				},
+				&spec.ExpFlag{
+					Name: "queue-depth",
+					Desc: "Number of in-flight io, default value is 1",
+				},
+				&spec.ExpFlag{
+					Name:   "direct",
+					Desc:   "Open the file with O_DIRECT, the block size must be a multiple of 4k",
+					NoArgs: true,
+				},
+				&spec.ExpFlag{
+					Name:   "dsync",
+					Desc:   "Open the file with O_DSYNC",
+					NoArgs: true,
+				},
+				&spec.ExpFlag{
+					Name: "iops",
+					Desc: "Cap of io per second, default value is 0 which means unlimited",
+				},
+				&spec.ExpFlag{
+					Name: "bandwidth",
+					Desc: "Cap of bytes per second with unit, for example 10m, default is unlimited",
+				},
 			},
 			ActionExecutor: &BurnIOExecutor{},
 			ActionExample: `
@@ -78,7 +114,10 @@ blade create disk burn --read --path /home
 blade create disk burn --write --path /home
 
 # Read and write IO load scenarios are performed at the same time. Path is not specified. The default is /
-blade create disk burn --read --write`,
+blade create disk burn --read --write
+
+# Database-like random io, 70% reads of 16k blocks with 8 in-flight io, capped at 2000 iops
+blade create disk burn --read --write --mode random --read-percent 70 --block-size 16k --queue-depth 8 --direct --iops 2000`,
 			ActionPrograms:    []string{BurnIOBin},
 			ActionCategories:  []string{category.SystemDisk},
 			ActionProcessHang: true,
@@ -115,7 +154,7 @@ func (*BurnIOExecutor) Name() string {
 var localChannel = channel.NewLocalChannel()
 
 func (be *BurnIOExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
-	commands := []string{"rm", "dd"}
+	commands := []string{"rm"}
 	// use local channel
 	if response, ok := localChannel.IsAllCommandsAvailable(ctx, commands); !ok {
 		return response
@@ -144,34 +183,128 @@ func (be *BurnIOExecutor) Exec(uid string, ctx context.Context, model *spec.ExpM
 		log.Errorf(ctx, "disk-burn-less params, read|write")
 		return spec.ResponseFailWithFlags(spec.ParameterLess, "disk-burn-read|write")
 	}
-	size := model.ActionFlags["size"]
+	pattern, response := parseIOPattern(ctx, model.ActionFlags, readExists, writeExists, directory)
+	if response != nil {
+		return response
+	}
+	engine, err := NewIOEngine(*pattern)
+	if err != nil {
+		log.Errorf(ctx, "disk-burn-illegal io pattern, %v", err)
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "disk-burn", fmt.Sprintf("%+v", *pattern), err)
+	}
+	// the io counts against the container after joining its cgroup
+	ctx, response = exec.JoinTargetCgroup(ctx, model.ActionFlags)
+	if response != nil {
+		return response
+	}
+	return be.start(ctx, engine)
+}
+
+// parseIOPattern returns the io pattern of the flags, the reads use direct io and the writes use dsync io
+// as before if neither the direct nor the dsync flag is specified
+func parseIOPattern(ctx context.Context, flags map[string]string, read, write bool, directory string) (*IOPattern, *spec.Response) {
+	pattern := &IOPattern{
+		Random:     flags["mode"] == "random",
+		QueueDepth: 1,
+		Direct:     flags["direct"] == "true",
+		Dsync:      flags["dsync"] == "true",
+	}
+	if mode := flags["mode"]; mode != "" && mode != "random" && mode != "sequential" {
+		log.Errorf(ctx, "`%s`: disk-burn-mode is illegal", mode)
+		return nil, spec.ResponseFailWithFlags(spec.ParameterIllegal, "mode", mode, "it must be random or sequential")
+	}
+	size := flags["size"]
 	if size == "" {
 		size = "10"
 	}
-	return be.start(ctx, readExists, writeExists, directory, size)
+	blockSize, err := parseBytes(size + "M")
+	if err != nil {
+		log.Errorf(ctx, "`%s`: disk-burn-size is illegal, %v", size, err)
+		return nil, spec.ResponseFailWithFlags(spec.ParameterIllegal, "size", size, err)
+	}
+	if value := flags["block-size"]; value != "" {
+		if blockSize, err = parseBytes(value); err != nil {
+			log.Errorf(ctx, "`%s`: disk-burn-block-size is illegal, %v", value, err)
+			return nil, spec.ResponseFailWithFlags(spec.ParameterIllegal, "block-size", value, err)
+		}
+	}
+	pattern.BlockSize = blockSize
+	pattern.FileSize = blockSize * count
+	if pattern.FileSize < minFileSize {
+		pattern.FileSize = minFileSize - minFileSize%blockSize
+	}
+
+	switch {
+	case read && write:
+		pattern.File = path.Join(directory, mixFile)
+		pattern.ReadPercent = 50
+		if value := flags["read-percent"]; value != "" {
+			if pattern.ReadPercent, err = strconv.Atoi(value); err != nil || pattern.ReadPercent < 0 || pattern.ReadPercent > 100 {
+				log.Errorf(ctx, "`%s`: disk-burn-read-percent is illegal", value)
+				return nil, spec.ResponseFailWithFlags(spec.ParameterIllegal, "read-percent", value, "it must be an integer in 0-100")
+			}
+		}
+	case read:
+		pattern.File = path.Join(directory, readFile)
+		pattern.ReadPercent = 100
+	default:
+		pattern.File = path.Join(directory, writeFile)
+	}
+	if !pattern.Direct && !pattern.Dsync {
+		pattern.Direct = pattern.ReadPercent == 100
+		pattern.Dsync = pattern.ReadPercent < 100
+		if pattern.Direct && blockSize%directAlignment != 0 {
+			pattern.Direct = false
+		}
+	}
+
+	integers := map[string]*int{"queue-depth": &pattern.QueueDepth, "iops": &pattern.IOPS}
+	for name, value := range integers {
+		if flags[name] == "" {
+			continue
+		}
+		if *value, err = strconv.Atoi(flags[name]); err != nil || *value < 0 {
+			log.Errorf(ctx, "`%s`: disk-burn-%s is illegal", flags[name], name)
+			return nil, spec.ResponseFailWithFlags(spec.ParameterIllegal, name, flags[name], "it must be a positive integer")
+		}
+	}
+	if value := flags["bandwidth"]; value != "" {
+		if pattern.Bandwidth, err = parseBytes(value); err != nil {
+			log.Errorf(ctx, "`%s`: disk-burn-bandwidth is illegal, %v", value, err)
+			return nil, spec.ResponseFailWithFlags(spec.ParameterIllegal, "bandwidth", value, err)
+		}
+	}
+	return pattern, nil
 }
 
-func (be *BurnIOExecutor) start(ctx context.Context, read, write bool, directory, size string) *spec.Response {
-	if read {
-		go burnRead(ctx, directory, size, be.channel)
+func (be *BurnIOExecutor) start(ctx context.Context, engine *IOEngine) *spec.Response {
+	if err := engine.Prepare(); err != nil {
+		log.Errorf(ctx, "disk-burn-prepare file %s err, %v", engine.pattern.File, err)
+		return spec.ReturnFail(spec.OsCmdExecFailed, fmt.Sprintf("prepare file %s err, %v", engine.pattern.File, err))
 	}
-	if write {
-		go burnWrite(ctx, directory, size, be.channel)
+	// the engine runs until chaos_os is killed, the experiment fails if the io fails
+	if err := engine.Run(ctx); err != nil {
+		log.Errorf(ctx, "disk-burn-run io engine err, %v", err)
+		return spec.ReturnFail(spec.OsCmdExecFailed, fmt.Sprintf("run io engine on %s err, %v", engine.pattern.File, err))
 	}
-	select {}
+	return spec.Success()
 }
 
 func (be *BurnIOExecutor) stop(ctx context.Context, read, write bool, directory string) *spec.Response {
+	files := make([]string, 0)
 	if read {
-		resp := localChannel.Run(ctx, "rm", fmt.Sprintf("-rf %s*", path.Join(directory, readFile)))
-		if !resp.Success {
-			log.Errorf(ctx, "disk-burn-stop-clean read file: %s", resp.Err)
-		}
+		files = append(files, readFile)
 	}
 	if write {
-		resp := localChannel.Run(ctx, "rm", fmt.Sprintf("-rf %s*", path.Join(directory, writeFile)))
+		files = append(files, writeFile)
+	}
+	if read && write {
+		files = append(files, mixFile)
+	}
+	for _, file := range files {
+		resp := exec.HostChannel(be.channel).Run(ctx, "rm", fmt.Sprintf("-rf %s*", path.Join(directory, file)))
 		if !resp.Success {
-			log.Errorf(ctx, "disk-burn-stop-clean write file: %s", resp.Err)
+			log.Errorf(ctx, "disk-burn-stop-clean file %s: %s", file, resp.Err)
 		}
 	}
 	ctx = context.WithValue(ctx, "bin", BurnIOBin)
@@ -184,59 +317,10 @@ func (be *BurnIOExecutor) SetChannel(channel spec.Channel) {
 
 var readFile = "chaos_burnio.read"
 var writeFile = "chaos_burnio.write"
+var mixFile = "chaos_burnio.rw"
 
+// count is the number of blocks of the burn file
 const count = 100
 
-// write burn
-func burnWrite(ctx context.Context, directory, size string, cl spec.Channel) {
-	tmpFileForWrite := path.Join(directory, writeFile)
-	_, _, ddRunningWriteArg := getArgs(ctx, localChannel)
-	for {
-		args := fmt.Sprintf(ddRunningWriteArg, tmpFileForWrite, size, count)
-		response := localChannel.Run(ctx, "dd", args)
-		if !response.Success {
-			log.Errorf(ctx, "disk-burnWrite, run dd err: %s", response.Err)
-			break
-		}
-	}
-}
-
-// read burn
-func burnRead(ctx context.Context, directory, size string, cl spec.Channel) {
-	// create a 600M file under the directory
-	tmpFileForRead := path.Join(directory, readFile)
-	ddCreateArg, ddRunningReadArg, _ := getArgs(ctx, localChannel)
-	createArgs := fmt.Sprintf(ddCreateArg, tmpFileForRead, 6, count)
-	response := localChannel.Run(ctx, "dd", createArgs)
-	if !response.Success {
-		log.Errorf(ctx, "disk-burn-read, run dd err: %s", response.Err)
-	}
-
-	for {
-		args := fmt.Sprintf(ddRunningReadArg, tmpFileForRead, size, count)
-		//run with local channel
-		response := localChannel.Run(ctx, "dd", args)
-		if !response.Success {
-			log.Errorf(ctx, "disk-burn read, run dd err: %s", response.Err)
-			break
-		}
-	}
-}
-
-func getArgs(ctx context.Context, cl spec.Channel) (string, string, string) {
-	createArgs := "if=/dev/zero of=%s bs=%dM count=%d oflag=dsync"
-	runningReadArgs := "if=%s of=/dev/null bs=%sM count=%d iflag=dsync,direct,fullblock"
-	runningWriteArgs := "if=/dev/zero of=%s bs=%sM count=%d oflag=dsync"
-	response := cl.Run(ctx, "cat", "/etc/os-release")
-	if !response.Success {
-		log.Warnf(ctx, "disk-getArgs-cat /etc/os-release failed, %v. use the default value.", response.Err)
-		return createArgs, runningReadArgs, runningWriteArgs
-	}
-	if response.Result != nil && strings.Contains(strings.ToUpper(response.Result.(string)), "ID=ALPINE") {
-		//alpine linux
-		createArgs = "if=/dev/zero of=%s bs=%dM count=%d oflag=append"
-		runningReadArgs = "if=%s of=/dev/null bs=%sM count=%d iflag=fullblock oflag=append"
-		runningWriteArgs = "if=/dev/zero of=%s bs=%sM count=%d oflag=append"
-	}
-	return createArgs, runningReadArgs, runningWriteArgs
-}
+// minFileSize is the minimum size of the burn file, 600M
+const minFileSize = 600 * 1024 * 1024
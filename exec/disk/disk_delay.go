@@ -0,0 +1,116 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package disk
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+const DelayDiskBin = "chaos_delaydisk"
+
+type DelayActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewDelayActionSpec() spec.ExpActionCommandSpec {
+	return &DelayActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{},
+			ActionFlags: append([]spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "read-delay",
+					Desc: "Read delay, ms",
+				},
+				&spec.ExpFlag{
+					Name: "write-delay",
+					Desc: "Write delay, ms",
+				},
+			}, dmFlags...),
+			ActionExecutor: &DelayActionExecutor{},
+			ActionExample: `
+# Create /dev/mapper/chaos-<uid> over a 100M loop device, reads are delayed 100ms and writes are delayed 200ms
+blade create disk delay --read-delay 100 --write-delay 200
+
+# Create /dev/mapper/chaos-<uid> over /dev/sdb, reads and writes through it are delayed 50ms
+blade create disk delay --device /dev/sdb --read-delay 50 --write-delay 50`,
+			ActionPrograms:   []string{DelayDiskBin},
+			ActionCategories: []string{category.SystemDisk},
+		},
+	}
+}
+
+func (*DelayActionSpec) Name() string {
+	return "delay"
+}
+
+func (*DelayActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*DelayActionSpec) ShortDesc() string {
+	return "Delay the io of a block device"
+}
+
+func (d *DelayActionSpec) LongDesc() string {
+	if d.ActionLongDesc != "" {
+		return d.ActionLongDesc
+	}
+	return "Delay the io of a block device by a dm-delay mapping over the specified device or a loop device, the io through /dev/mapper/chaos-<uid> is delayed"
+}
+
+type DelayActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*DelayActionExecutor) Name() string {
+	return "delay"
+}
+
+func (de *DelayActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return removeDmDevice(ctx, de.channel, uid, model.ActionFlags)
+	}
+	readDelay, writeDelay := model.ActionFlags["read-delay"], model.ActionFlags["write-delay"]
+	if readDelay == "" && writeDelay == "" {
+		log.Errorf(ctx, "disk-delay-less params, read-delay|write-delay")
+		return spec.ResponseFailWithFlags(spec.ParameterLess, "read-delay|write-delay")
+	}
+	delays := map[string]*string{"read-delay": &readDelay, "write-delay": &writeDelay}
+	for name, value := range delays {
+		if *value == "" {
+			*value = "0"
+		}
+		if delay, err := strconv.Atoi(*value); err != nil || delay < 0 {
+			log.Errorf(ctx, "`%s`: disk-delay-%s is illegal, it must be a positive integer", *value, name)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, name, *value, "it must be a positive integer")
+		}
+	}
+	return createDmDevice(ctx, de.channel, uid, model.ActionFlags, func(device, sectors string) string {
+		return fmt.Sprintf("0 %s delay %s 0 %s %s 0 %s", sectors, device, readDelay, device, writeDelay)
+	})
+}
+
+func (de *DelayActionExecutor) SetChannel(channel spec.Channel) {
+	de.channel = channel
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package disk
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/dryrun"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+)
+
+// the device-mapper flags shared by disk delay and disk error
+var dmFlags = []spec.ExpFlagSpec{
+	&spec.ExpFlag{
+		Name: "device",
+		Desc: "The block device to map, for example /dev/sdb. A loop device backed by a file under the path is created if it is absent",
+	},
+	&spec.ExpFlag{
+		Name: "path",
+		Desc: "The path of directory where the backing file of the loop device is created, default value is /tmp",
+	},
+	&spec.ExpFlag{
+		Name: "size",
+		Desc: "The size of the backing file of the loop device, MB, default value is 100",
+	},
+}
+
+// dmName returns the device-mapper name of the experiment, the mapped device is /dev/mapper/<name>
+func dmName(uid string) string {
+	return fmt.Sprintf("chaos-%s", uid)
+}
+
+// loopFile returns the backing file of the loop device created by the experiment
+func loopFile(directory, uid string) string {
+	return path.Join(directory, fmt.Sprintf("chaos_dm_%s.img", uid))
+}
+
+// createDmDevice creates the device-mapper device over the specified device or a new loop device,
+// the table function returns the mapping table by the underlying device and its size in sectors
+func createDmDevice(ctx context.Context, cl spec.Channel, uid string, flags map[string]string,
+	table func(device, sectors string) string) *spec.Response {
+	commands := []string{"dmsetup", "blockdev"}
+	device := flags["device"]
+	if device == "" {
+		commands = append(commands, "losetup", "truncate")
+	}
+	if response, ok := cl.IsAllCommandsAvailable(ctx, commands); !ok {
+		return response
+	}
+	// the loop device is not attached by dry-run, so its name and size are unknown
+	_, dryRun := cl.(*dryrun.RecordChannel)
+	loopDevice := device == ""
+	if loopDevice {
+		directory := flags["path"]
+		if directory == "" {
+			directory = "/tmp"
+		}
+		if !util.IsDir(directory) {
+			log.Errorf(ctx, "`%s`: disk-dm-path is illegal, is not a directory", directory)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "path", directory, "it must be a directory")
+		}
+		size := flags["size"]
+		if size == "" {
+			size = "100"
+		}
+		if _, err := parseBytes(size + "M"); err != nil {
+			log.Errorf(ctx, "`%s`: disk-dm-size is illegal, %v", size, err)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "size", size, "it must be a positive integer")
+		}
+		file := loopFile(directory, uid)
+		if response := cl.Run(ctx, "truncate", fmt.Sprintf(`-s %sM "%s"`, size, file)); !response.Success {
+			return response
+		}
+		response := cl.Run(ctx, "losetup", fmt.Sprintf(`-f --show "%s"`, file))
+		if !response.Success {
+			cl.Run(ctx, "rm", fmt.Sprintf(`-f "%s"`, file))
+			return response
+		}
+		device = strings.TrimSpace(response.Result.(string))
+		if dryRun {
+			device = "<loop device>"
+		}
+		store.Record(ctx, store.Resource{Kind: store.ResourceLoopDevice, Value: device, Attrs: map[string]string{"file": file}})
+	}
+	sectors := "<sectors of the loop device>"
+	if !dryRun || !loopDevice {
+		response := cl.Run(ctx, "blockdev", fmt.Sprintf("--getsz %s", device))
+		if !response.Success {
+			removeDmDevice(ctx, cl, uid, flags)
+			return response
+		}
+		sectors = strings.TrimSpace(response.Result.(string))
+	}
+	name := dmName(uid)
+	response := cl.Run(ctx, "dmsetup", fmt.Sprintf(`create %s --table "%s"`, name, table(device, sectors)))
+	if !response.Success {
+		removeDmDevice(ctx, cl, uid, flags)
+		return response
+	}
+	store.Record(ctx, store.Resource{Kind: store.ResourceDmDevice, Value: name, Attrs: map[string]string{"device": device}})
+	return spec.ReturnSuccess(path.Join("/dev/mapper", name))
+}
+
+// removeDmDevice removes the device-mapper device, the loop device and its backing file created by the experiment
+func removeDmDevice(ctx context.Context, cl spec.Channel, uid string, flags map[string]string) *spec.Response {
+	name := dmName(uid)
+	// the device recorded by the dry-run of create is not created, so its removal is planned without checking it
+	_, dryRun := cl.(*dryrun.RecordChannel)
+	planned := dryRun && len(store.Resources(ctx, store.ResourceDmDevice)) > 0
+	if planned || cl.Run(ctx, "dmsetup", fmt.Sprintf("info %s", name)).Success {
+		if response := cl.Run(ctx, "dmsetup", fmt.Sprintf("remove %s", name)); !response.Success {
+			return response
+		}
+	}
+	loops := store.Resources(ctx, store.ResourceLoopDevice)
+	if len(loops) == 0 && flags["device"] == "" {
+		// the loop device is not recorded, find it by the backing file
+		directory := flags["path"]
+		if directory == "" {
+			directory = "/tmp"
+		}
+		file := loopFile(directory, uid)
+		response := cl.Run(ctx, "losetup", fmt.Sprintf(`-j "%s"`, file))
+		if response.Success && !util.IsNil(response.Result) {
+			// /dev/loop0: [2049]:1234 (/tmp/chaos_dm_uid.img)
+			for _, line := range strings.Split(response.Result.(string), "\n") {
+				if device := strings.TrimSpace(strings.Split(line, ":")[0]); device != "" {
+					loops = append(loops, store.Resource{Value: device, Attrs: map[string]string{"file": file}})
+				}
+			}
+		}
+		if len(loops) == 0 && util.IsExist(file) {
+			loops = append(loops, store.Resource{Attrs: map[string]string{"file": file}})
+		}
+	}
+	for _, loop := range loops {
+		if loop.Value != "" {
+			if response := cl.Run(ctx, "losetup", fmt.Sprintf("-d %s", loop.Value)); !response.Success {
+				log.Warnf(ctx, "detach loop device %s err, %s", loop.Value, response.Err)
+			}
+		}
+		if response := cl.Run(ctx, "rm", fmt.Sprintf(`-f "%s"`, loop.Attrs["file"])); !response.Success {
+			return response
+		}
+	}
+	return spec.Success()
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package disk
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/dryrun"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+)
+
+func TestDmDeviceDryRun(t *testing.T) {
+	recorder := dryrun.NewRecordChannel(channel.NewLocalChannel())
+	if _, ok := recorder.IsAllCommandsAvailable(context.Background(), []string{"dmsetup", "blockdev", "losetup", "truncate"}); !ok {
+		t.Skip("the device-mapper commands are not found")
+	}
+	experiment := &store.Experiment{Uid: "dm01"}
+	ctx := store.WithExperiment(context.Background(), nil, experiment)
+	directory := t.TempDir()
+	flags := map[string]string{"path": directory}
+	response := createDmDevice(ctx, recorder, "dm01", flags, func(device, sectors string) string {
+		return fmt.Sprintf("0 %s linear %s 0", sectors, device)
+	})
+	if !response.Success {
+		t.Fatalf("createDmDevice() fails in dry-run, %s", response.Err)
+	}
+	if response := removeDmDevice(ctx, recorder, "dm01", flags); !response.Success {
+		t.Fatalf("removeDmDevice() fails in dry-run, %s", response.Err)
+	}
+	file := loopFile(directory, "dm01")
+	expect := []string{
+		fmt.Sprintf(`truncate -s 100M "%s"`, file),
+		fmt.Sprintf(`losetup -f --show "%s"`, file),
+		`dmsetup create chaos-dm01 --table "0 <sectors of the loop device> linear <loop device> 0"`,
+		"dmsetup remove chaos-dm01",
+		"losetup -d <loop device>",
+		fmt.Sprintf(`rm -f "%s"`, file),
+	}
+	operations := recorder.Operations()
+	if len(operations) != len(expect) {
+		t.Fatalf("dry-run records %+v, want %v", operations, expect)
+	}
+	for i, operation := range operations {
+		if operation.Command != expect[i] {
+			t.Errorf("operation %d is %s, want %s", i, operation.Command, expect[i])
+		}
+	}
+	if util.IsExist(file) {
+		t.Errorf("the backing file is created by dry-run")
+	}
+}
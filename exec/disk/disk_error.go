@@ -0,0 +1,147 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package disk
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+const ErrorDiskBin = "chaos_errordisk"
+
+// the dm-flakey features of the modes, the io is not touched in the up interval
+var flakeyFeatures = map[string]string{
+	// all reads and writes fail
+	"error": "",
+	// the writes fail and the reads succeed
+	"error-writes": "1 error_writes",
+	// the writes are ignored silently
+	"drop-writes": "1 drop_writes",
+	// the first byte of the read data is corrupted, the data on the device is not changed
+	"corrupt": "5 corrupt_bio_byte 1 r 0 0",
+}
+
+type ErrorActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewErrorActionSpec() spec.ExpActionCommandSpec {
+	return &ErrorActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{},
+			ActionFlags: append([]spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "up-interval",
+					Desc: "Seconds the device is available in each cycle, default value is 0",
+				},
+				&spec.ExpFlag{
+					Name:     "down-interval",
+					Desc:     "Seconds the device is faulty in each cycle",
+					Required: true,
+				},
+				&spec.ExpFlag{
+					Name: "mode",
+					Desc: "Fault in the down interval, error, error-writes, drop-writes or corrupt, default value is error",
+				},
+			}, dmFlags...),
+			ActionExecutor: &ErrorActionExecutor{},
+			ActionExample: `
+# Create /dev/mapper/chaos-<uid> over a 100M loop device, it works 10s and then fails all io 5s in turn
+blade create disk error --up-interval 10 --down-interval 5
+
+# Create /dev/mapper/chaos-<uid> over /dev/sdb, the reads through it return corrupted data all the time
+blade create disk error --device /dev/sdb --down-interval 60 --mode corrupt`,
+			ActionPrograms:   []string{ErrorDiskBin},
+			ActionCategories: []string{category.SystemDisk},
+		},
+	}
+}
+
+func (*ErrorActionSpec) Name() string {
+	return "error"
+}
+
+func (*ErrorActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*ErrorActionSpec) ShortDesc() string {
+	return "Inject io errors into a block device"
+}
+
+func (e *ErrorActionSpec) LongDesc() string {
+	if e.ActionLongDesc != "" {
+		return e.ActionLongDesc
+	}
+	return "Inject io errors into a block device by a dm-flakey mapping over the specified device or a loop device, the io through /dev/mapper/chaos-<uid> fails in the down interval"
+}
+
+type ErrorActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*ErrorActionExecutor) Name() string {
+	return "error"
+}
+
+func (ee *ErrorActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return removeDmDevice(ctx, ee.channel, uid, model.ActionFlags)
+	}
+	upInterval, downInterval := model.ActionFlags["up-interval"], model.ActionFlags["down-interval"]
+	if downInterval == "" {
+		log.Errorf(ctx, "disk-error-less params, down-interval")
+		return spec.ResponseFailWithFlags(spec.ParameterLess, "down-interval")
+	}
+	if upInterval == "" {
+		upInterval = "0"
+	}
+	if up, err := strconv.Atoi(upInterval); err != nil || up < 0 {
+		log.Errorf(ctx, "`%s`: disk-error-up-interval is illegal, it must be a positive integer", upInterval)
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "up-interval", upInterval, "it must be a positive integer")
+	}
+	if down, err := strconv.Atoi(downInterval); err != nil || down <= 0 {
+		log.Errorf(ctx, "`%s`: disk-error-down-interval is illegal, it must be a positive integer", downInterval)
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "down-interval", downInterval, "it must be a positive integer")
+	}
+	mode := model.ActionFlags["mode"]
+	if mode == "" {
+		mode = "error"
+	}
+	features, ok := flakeyFeatures[mode]
+	if !ok {
+		log.Errorf(ctx, "`%s`: disk-error-mode is illegal", mode)
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "mode", mode, "it must be error, error-writes, drop-writes or corrupt")
+	}
+	return createDmDevice(ctx, ee.channel, uid, model.ActionFlags, func(device, sectors string) string {
+		table := fmt.Sprintf("0 %s flakey %s 0 %s %s", sectors, device, upInterval, downInterval)
+		if features != "" {
+			table = fmt.Sprintf("%s %s", table, features)
+		}
+		return table
+	})
+}
+
+func (ee *ErrorActionExecutor) SetChannel(channel spec.Channel) {
+	ee.channel = channel
+}
@@ -0,0 +1,251 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package disk
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+)
+
+// the alignment of the buffer and block size required by O_DIRECT
+const directAlignment = 4096
+
+// IOPattern describes the io generated by the IOEngine
+type IOPattern struct {
+	// File is the file to read and write, it is prepared with FileSize bytes
+	File     string
+	FileSize int64
+	// BlockSize is the size of each read or write
+	BlockSize int64
+	// ReadPercent is the percentage of reads, 100 means read only and 0 means write only
+	ReadPercent int
+	Random      bool
+	// QueueDepth is the number of in-flight io
+	QueueDepth int
+	Direct     bool
+	Dsync      bool
+	// IOPS and Bandwidth, bytes per second, cap the io rate, 0 means unlimited
+	IOPS      int
+	Bandwidth int64
+}
+
+// Validate checks the pattern
+func (p *IOPattern) Validate() error {
+	if p.BlockSize <= 0 {
+		return fmt.Errorf("block size must be positive")
+	}
+	if p.Direct && p.BlockSize%directAlignment != 0 {
+		return fmt.Errorf("block size must be a multiple of %d with direct io", directAlignment)
+	}
+	if p.FileSize < p.BlockSize {
+		return fmt.Errorf("file size must not be less than block size")
+	}
+	if p.ReadPercent < 0 || p.ReadPercent > 100 {
+		return fmt.Errorf("read percent must be in 0-100")
+	}
+	if p.QueueDepth <= 0 {
+		return fmt.Errorf("queue depth must be positive")
+	}
+	if p.IOPS < 0 || p.Bandwidth < 0 {
+		return fmt.Errorf("iops and bandwidth must not be negative")
+	}
+	return nil
+}
+
+// IOEngine generates the io of the pattern by pread and pwrite, instead of dd
+type IOEngine struct {
+	pattern IOPattern
+	blocks  int64
+	cursor  int64
+	limiter *rateLimiter
+}
+
+// NewIOEngine returns the engine of the pattern
+func NewIOEngine(pattern IOPattern) (*IOEngine, error) {
+	if err := pattern.Validate(); err != nil {
+		return nil, err
+	}
+	engine := &IOEngine{pattern: pattern, blocks: pattern.FileSize / pattern.BlockSize}
+	var interval time.Duration
+	if pattern.IOPS > 0 {
+		interval = time.Second / time.Duration(pattern.IOPS)
+	}
+	if pattern.Bandwidth > 0 {
+		if i := time.Duration(float64(time.Second) * float64(pattern.BlockSize) / float64(pattern.Bandwidth)); i > interval {
+			interval = i
+		}
+	}
+	if interval > 0 {
+		engine.limiter = &rateLimiter{interval: interval}
+	}
+	return engine, nil
+}
+
+// Prepare creates the file, it is filled with data if there are reads,
+// because reading the holes of a sparse file does not touch the disk
+func (e *IOEngine) Prepare() error {
+	file, err := os.OpenFile(e.pattern.File, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if e.pattern.ReadPercent == 0 {
+		return file.Truncate(e.pattern.FileSize)
+	}
+	chunk := make([]byte, 1024*1024)
+	rand.Read(chunk)
+	for written := int64(0); written < e.pattern.FileSize; {
+		n := int64(len(chunk))
+		if e.pattern.FileSize-written < n {
+			n = e.pattern.FileSize - written
+		}
+		if _, err := file.WriteAt(chunk[:n], written); err != nil {
+			return err
+		}
+		written += n
+	}
+	return file.Sync()
+}
+
+// Run starts the workers of the queue depth and blocks until the context is done or a worker fails
+func (e *IOEngine) Run(ctx context.Context) error {
+	// the other workers are stopped once a worker fails, so the error is returned at once
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	errs := make(chan error, e.pattern.QueueDepth)
+	for i := 0; i < e.pattern.QueueDepth; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			if err := e.work(ctx, worker); err != nil {
+				log.Errorf(ctx, "disk-io-engine-worker %d of %s err, %v", worker, e.pattern.File, err)
+				errs <- err
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+func (e *IOEngine) work(ctx context.Context, worker int) error {
+	file, err := openFile(e.pattern.File, e.pattern.Direct, e.pattern.Dsync)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	buf := alignedBuffer(e.pattern.BlockSize)
+	rand.Read(buf)
+	random := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		if e.limiter != nil {
+			e.limiter.wait()
+		}
+		offset := e.nextOffset(random)
+		if random.Intn(100) < e.pattern.ReadPercent {
+			_, err = file.ReadAt(buf, offset)
+		} else {
+			_, err = file.WriteAt(buf, offset)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (e *IOEngine) nextOffset(random *rand.Rand) int64 {
+	if e.pattern.Random {
+		return random.Int63n(e.blocks) * e.pattern.BlockSize
+	}
+	block := atomic.AddInt64(&e.cursor, 1) - 1
+	return (block % e.blocks) * e.pattern.BlockSize
+}
+
+// alignedBuffer returns the buffer aligned for O_DIRECT
+func alignedBuffer(size int64) []byte {
+	buf := make([]byte, size+directAlignment)
+	offset := 0
+	if remainder := int(uintptr(unsafe.Pointer(&buf[0])) & (directAlignment - 1)); remainder != 0 {
+		offset = directAlignment - remainder
+	}
+	return buf[offset : int64(offset)+size]
+}
+
+// rateLimiter spaces the io by the interval
+type rateLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time
+}
+
+func (r *rateLimiter) wait() {
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	sleep := r.next.Sub(now)
+	r.next = r.next.Add(r.interval)
+	r.mu.Unlock()
+	if sleep > 0 {
+		time.Sleep(sleep)
+	}
+}
+
+// parseBytes parses the size such as 4096, 4k, 64K, 10M or 1G
+func parseBytes(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	unit := int64(1)
+	if value != "" {
+		switch strings.ToUpper(value[len(value)-1:]) {
+		case "K":
+			unit = 1024
+		case "M":
+			unit = 1024 * 1024
+		case "G":
+			unit = 1024 * 1024 * 1024
+		}
+		if unit > 1 {
+			value = value[:len(value)-1]
+		}
+	}
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if size <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return size * unit, nil
+}
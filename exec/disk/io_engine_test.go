@@ -0,0 +1,80 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package disk
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestParseBytes(t *testing.T) {
+	tests := map[string]int64{"4096": 4096, "4k": 4096, "64K": 65536, "10M": 10485760, "1g": 1073741824}
+	for value, expect := range tests {
+		if size, err := parseBytes(value); err != nil || size != expect {
+			t.Errorf("parse %s, expect %d, got %d, err %v", value, expect, size, err)
+		}
+	}
+	for _, value := range []string{"", "k", "-1M", "0"} {
+		if _, err := parseBytes(value); err == nil {
+			t.Errorf("parse %s should fail", value)
+		}
+	}
+}
+
+func TestIOEngineRun(t *testing.T) {
+	pattern := IOPattern{
+		File:        path.Join(t.TempDir(), mixFile),
+		FileSize:    64 * 4096,
+		BlockSize:   4096,
+		ReadPercent: 50,
+		Random:      true,
+		QueueDepth:  2,
+		IOPS:        200,
+	}
+	engine, err := NewIOEngine(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(pattern.File); err != nil || info.Size() != pattern.FileSize {
+		t.Fatalf("unexpected prepared file %v, err %v", info, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := engine.Run(ctx); err != nil {
+		t.Errorf("run err, %v", err)
+	}
+	missing := &IOEngine{pattern: IOPattern{File: path.Join(t.TempDir(), "missing", mixFile), BlockSize: 4096, QueueDepth: 2}, blocks: 1}
+	if err := missing.Run(context.Background()); err == nil {
+		t.Errorf("run on the missing file should fail")
+	}
+
+	sequential := &IOEngine{pattern: IOPattern{BlockSize: 4096}, blocks: 2}
+	for i, expect := range []int64{0, 4096, 0} {
+		if offset := sequential.nextOffset(nil); offset != expect {
+			t.Errorf("offset %d, expect %d, got %d", i, expect, offset)
+		}
+	}
+	if _, err := NewIOEngine(IOPattern{FileSize: 8192, BlockSize: 1000, QueueDepth: 1, Direct: true}); err == nil {
+		t.Errorf("unaligned block size should fail with direct io")
+	}
+}
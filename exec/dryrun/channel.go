@@ -0,0 +1,185 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dryrun
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+)
+
+const (
+	OperationCommand   = "command"
+	OperationFileWrite = "file-write"
+	OperationProcess   = "process"
+)
+
+// Operation is a host mutation which would be performed by the experiment
+type Operation struct {
+	Type    string `json:"type"`
+	Command string `json:"command"`
+	Path    string `json:"path,omitempty"`
+}
+
+// RecordChannel records the commands instead of executing them. The read-only commands,
+// such as stat, grep or tc qdisc show, are still executed by the wrapped channel because
+// the executors depend on their results to build the following commands.
+type RecordChannel struct {
+	spec.Channel
+	mu         sync.Mutex
+	operations []Operation
+}
+
+// NewRecordChannel returns the record channel which wraps the channel
+func NewRecordChannel(channel spec.Channel) *RecordChannel {
+	return &RecordChannel{Channel: channel, operations: make([]Operation, 0)}
+}
+
+func (r *RecordChannel) Name() string {
+	return "dryrun"
+}
+
+func (r *RecordChannel) Run(ctx context.Context, script, args string) *spec.Response {
+	command := strings.TrimSpace(fmt.Sprintf("%s %s", script, args))
+	if isReadOnly(command) {
+		return r.Channel.Run(ctx, script, args)
+	}
+	operation := Operation{Type: OperationCommand, Command: command}
+	if path := writtenFile(command); path != "" {
+		operation.Type = OperationFileWrite
+		operation.Path = path
+	}
+	r.Record(operation)
+	return spec.ReturnSuccess("")
+}
+
+// Record appends the operation
+func (r *RecordChannel) Record(operation Operation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.operations = append(r.operations, operation)
+}
+
+// Operations returns the recorded operations in order
+func (r *RecordChannel) Operations() []Operation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Operation{}, r.operations...)
+}
+
+var readOnlyCommands = map[string]bool{
+	"stat":    true,
+	"grep":    true,
+	"cat":     true,
+	"head":    true,
+	"tail":    true,
+	"awk":     true,
+	"test":    true,
+	"ss":      true,
+	"netstat": true,
+	"ps":      true,
+	"ls":      true,
+	"uname":   true,
+}
+
+// isReadOnly returns true if the command only queries the host
+func isReadOnly(command string) bool {
+	// the file test built by exec.CheckFilepathExists
+	if strings.HasPrefix(command, "[ ") && strings.HasSuffix(command, "] && echo true || echo false") &&
+		writtenFile(command) == "" && strings.Count(command, "&&") == 1 {
+		return true
+	}
+	if writtenFile(command) != "" || strings.ContainsAny(command, ";&") {
+		return false
+	}
+	for _, segment := range strings.Split(command, "|") {
+		fields := strings.Fields(segment)
+		if len(fields) == 0 {
+			return false
+		}
+		switch fields[0] {
+		case "tc":
+			if len(fields) < 3 || fields[2] != "show" && fields[2] != "list" && fields[2] != "ls" {
+				return false
+			}
+		case "dmsetup":
+			if len(fields) < 2 || fields[1] != "info" && fields[1] != "ls" && fields[1] != "table" && fields[1] != "status" {
+				return false
+			}
+		case "blockdev":
+			if len(fields) < 2 || !strings.HasPrefix(fields[1], "--get") {
+				return false
+			}
+		case "losetup":
+			if len(fields) < 2 || fields[1] != "-j" && fields[1] != "-a" {
+				return false
+			}
+		case "iptables", "ip6tables":
+			if len(fields) < 2 || fields[1] != "-L" && fields[1] != "-S" && fields[1] != "-C" && fields[1] != "-nL" && fields[1] != "-V" {
+				return false
+			}
+		case "nft":
+			if len(fields) < 2 || fields[1] != "list" && fields[1] != "--version" {
+				return false
+			}
+		default:
+			if !readOnlyCommands[fields[0]] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// writtenFile returns the file written by redirection, tee or sed -i in the command
+func writtenFile(command string) string {
+	fields := strings.Fields(command)
+	for i, field := range fields {
+		file := ""
+		switch {
+		case field == ">" || field == ">>":
+			file = nextArg(fields, i)
+		case strings.HasPrefix(field, ">>"):
+			file = field[2:]
+		case strings.HasPrefix(field, ">") && !strings.HasPrefix(field, ">&"):
+			file = field[1:]
+		case field == "tee":
+			for j := i + 1; j < len(fields) && file == ""; j++ {
+				if !strings.HasPrefix(fields[j], "-") {
+					file = fields[j]
+				}
+			}
+		case field == "sed" && nextArg(fields, i) == "-i":
+			file = fields[len(fields)-1]
+		}
+		file = strings.Trim(file, `"';`)
+		if file != "" && file != "/dev/null" {
+			return file
+		}
+	}
+	return ""
+}
+
+func nextArg(fields []string, i int) string {
+	if i+1 < len(fields) {
+		return fields[i+1]
+	}
+	return ""
+}
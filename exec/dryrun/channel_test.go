@@ -0,0 +1,56 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dryrun
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+)
+
+func TestRecordChannelRun(t *testing.T) {
+	mock := channel.NewMockLocalChannel().(*channel.MockLocalChannel)
+	executed := make([]string, 0)
+	mock.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+		executed = append(executed, script+" "+args)
+		return spec.ReturnSuccess("qdisc noqueue 0: root refcnt 2")
+	}
+	recorder := NewRecordChannel(mock)
+	ctx := context.Background()
+
+	recorder.Run(ctx, "tc", "qdisc show dev eth0")
+	recorder.Run(ctx, "tc", "qdisc add dev eth0 root handle 1: prio bands 4")
+	recorder.Run(ctx, "echo", `"1.1.1.1 a.com #chaosblade" >> /etc/hosts`)
+	recorder.Run(ctx, "sed", `-i '/#chaosblade/d' /etc/hosts`)
+	recorder.Run(ctx, "iptables", "-A INPUT -p tcp --dport 80 -j DROP")
+
+	if !reflect.DeepEqual(executed, []string{"tc qdisc show dev eth0"}) {
+		t.Errorf("unexpected executed commands %v", executed)
+	}
+	expect := []Operation{
+		{Type: OperationCommand, Command: "tc qdisc add dev eth0 root handle 1: prio bands 4"},
+		{Type: OperationFileWrite, Command: `echo "1.1.1.1 a.com #chaosblade" >> /etc/hosts`, Path: "/etc/hosts"},
+		{Type: OperationFileWrite, Command: `sed -i '/#chaosblade/d' /etc/hosts`, Path: "/etc/hosts"},
+		{Type: OperationCommand, Command: "iptables -A INPUT -p tcp --dport 80 -j DROP"},
+	}
+	if operations := recorder.Operations(); !reflect.DeepEqual(operations, expect) {
+		t.Errorf("unexpected operations %v", operations)
+	}
+}
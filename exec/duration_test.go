@@ -0,0 +1,42 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	for value, expect := range map[string]time.Duration{
+		"":      0,
+		"10":    10 * time.Second,
+		"0":     0,
+		"10m":   10 * time.Minute,
+		"1m30s": 90 * time.Second,
+		"500ms": 500 * time.Millisecond,
+	} {
+		if duration, err := ParseDuration(value); err != nil || duration != expect {
+			t.Errorf("ParseDuration(%s) = %v, %v, want %v", value, duration, err, expect)
+		}
+	}
+	for _, illegal := range []string{"-1", "-5s", "1x", "abc", "10 m"} {
+		if _, err := ParseDuration(illegal); err == nil {
+			t.Errorf("ParseDuration(%s) expects err", illegal)
+		}
+	}
+}
@@ -19,6 +19,8 @@ package exec
 import (
 	"context"
 	"fmt"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/dryrun"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
 	"github.com/chaosblade-io/chaosblade-spec-go/channel"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 	"strings"
@@ -27,8 +29,41 @@ import (
 // todo
 var cl = channel.NewLocalChannel()
 
+var CgroupPathFlag = &spec.ExpFlag{
+	Name:     "cgroup-path",
+	Desc:     "The cgroup path relative to the cgroup root, the load processes are moved into it, for example /kubepods/burstable/pod1/c1",
+	NoArgs:   false,
+	Required: false,
+}
+
+var ContainerIdFlag = &spec.ExpFlag{
+	Name:     "container-id",
+	Desc:     "The docker or containerd container id, the load processes are moved into the cgroup of the container",
+	NoArgs:   false,
+	Required: false,
+}
+
+// HostChannel returns the channel changing the processes and files of the host, it is the local channel
+// unless the commands are recorded for dry-run.
+func HostChannel(c spec.Channel) spec.Channel {
+	if _, ok := c.(*dryrun.RecordChannel); ok {
+		return c
+	}
+	return cl
+}
+
 // stop hang process
 func Destroy(ctx context.Context, c spec.Channel, action string) *spec.Response {
+	// The chaos_os processes run on the host, so they are killed by the host channel.
+	killer := HostChannel(c)
+	// The pids recorded at creation are killed exactly, instead of searching by process name.
+	if resources := store.Resources(ctx, store.ResourcePid); len(resources) > 0 {
+		pids := store.AlivePids(ctx)
+		if len(pids) == 0 {
+			return spec.Success()
+		}
+		return killer.Run(ctx, "kill", fmt.Sprintf(`-9 %s`, strings.Join(pids, " ")))
+	}
 	suid := ctx.Value(spec.Uid)
 	/* If suid is specified, it will be deleted exactly
 	 * according to suid, otherwise it will be based on action. */
@@ -52,7 +87,7 @@ func Destroy(ctx context.Context, c spec.Channel, action string) *spec.Response
 		sprintf := fmt.Sprintf("destory experiment failed, cannot get the chaos_os program")
 		return spec.ReturnFail(spec.OsCmdExecFailed, sprintf)
 	}
-	return cl.Run(ctx, "kill", fmt.Sprintf(`-9 %s`, strings.Join(pids, " ")))
+	return killer.Run(ctx, "kill", fmt.Sprintf(`-9 %s`, strings.Join(pids, " ")))
 }
 
 func CheckFilepathExists(ctx context.Context, cl spec.Channel, filepath string) bool {
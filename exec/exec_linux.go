@@ -23,6 +23,7 @@ import (
 	"os"
 )
 
+// PidPath returns the cgroup v1 controller paths of the pid, see Cgroup2Path for cgroup v2
 func PidPath(pid int) cgroups.Path {
 	p := fmt.Sprintf("/proc/%d/cgroup", pid)
 	paths, err := cgroups.ParseCgroupFile(p)
@@ -44,6 +45,7 @@ func PidPath(pid int) cgroups.Path {
 	}
 }
 
+// Hierarchy returns the cgroup v1 subsystems mounted under the root, see IsCgroup2 for cgroup v2
 func Hierarchy(root string) func() ([]cgroups.Subsystem, error) {
 	return func() ([]cgroups.Subsystem, error) {
 		subsystems, err := defaults(root)
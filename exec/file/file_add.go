@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"github.com/chaosblade-io/chaosblade-exec-os/exec"
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/dryrun"
 	"github.com/chaosblade-io/chaosblade-spec-go/log"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 	"io/ioutil"
@@ -149,13 +150,16 @@ func (f *FileAddActionExecutor) start(cl spec.Channel, filepath, content string,
 					content = string(decodeBytes)
 				}
 			}
-			err := ioutil.WriteFile(filepath, []byte(content), 0777) //写入文件(字节数组)
-			var ret *spec.Response
-			if err != nil {
-				ret.Code = 47001
-				ret.Success = false
-				ret.Result = err.Error()
-				return ret
+			// the content is written by the process, so it is recorded for dry-run
+			if recorder, ok := f.channel.(*dryrun.RecordChannel); ok {
+				recorder.Record(dryrun.Operation{
+					Type:    dryrun.OperationFileWrite,
+					Command: fmt.Sprintf("write %d bytes to %s", len(content), filepath),
+					Path:    filepath,
+				})
+			} else if err := ioutil.WriteFile(filepath, []byte(content), 0777); err != nil { //写入文件(字节数组)
+				log.Errorf(ctx, "`%s`: file-add-start-write file err, %s", filepath, err.Error())
+				return spec.ResponseFailWithFlags(spec.OsCmdExecFailed, "write "+filepath, err)
 			}
 			return f.channel.Run(ctx, "chmod", fmt.Sprintf("777 %s", filepath))
 		}
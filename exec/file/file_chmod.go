@@ -26,6 +26,7 @@ import (
 	"github.com/chaosblade-io/chaosblade-spec-go/log"
 
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 )
 
@@ -121,10 +122,23 @@ func (f *FileChmodActionExecutor) Exec(uid string, ctx context.Context, model *s
 	if !response.Success {
 		return response
 	}
+	store.Record(ctx, store.Resource{
+		Kind:  store.ResourcePermission,
+		Value: filepath,
+		Attrs: map[string]string{"mode": strings.TrimSpace(originMark)},
+	})
 	return f.channel.Run(ctx, "chmod", fmt.Sprintf(`%s "%s"`, mark, filepath))
 }
 
 func (f *FileChmodActionExecutor) stopChmodFile(ctx context.Context, filepath, mark string) *spec.Response {
+	// restore the origin mark recorded by the experiment
+	for _, resource := range store.Resources(ctx, store.ResourcePermission) {
+		if resource.Value == filepath && resource.Attrs["mode"] != "" {
+			response := f.channel.Run(ctx, "chmod", fmt.Sprintf(`%s "%s"`, resource.Attrs["mode"], filepath))
+			f.clearTempFile(filepath, response, ctx)
+			return response
+		}
+	}
 	// get origin mark
 	response := f.channel.Run(ctx, "grep", fmt.Sprintf(`%s: %s | awk -F ':' '{printf $2}'`, filepath, tmpFileChmod))
 	if !response.Success {
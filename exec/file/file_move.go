@@ -23,6 +23,7 @@ import (
 
 	"github.com/chaosblade-io/chaosblade-exec-os/exec"
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
 	"github.com/chaosblade-io/chaosblade-spec-go/log"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 )
@@ -140,19 +141,57 @@ func (f *FileMoveActionExecutor) start(filepath, target string, force, autoCreat
 		}
 	}
 
+	targetFile := path.Join(target, path.Base(filepath))
 	if force {
 		// backup
-		_ = f.channel.Run(ctx, "cp", fmt.Sprintf(`"%s" "%s"`, path.Join(target, path.Base(filepath)),
-			path.Join(target, path.Base(filepath)+suffix)))
+		backup := f.channel.Run(ctx, "cp", fmt.Sprintf(`"%s" "%s"`, targetFile, targetFile+suffix))
+		if backup.Success {
+			store.Record(ctx, store.Resource{
+				Kind:  store.ResourceBackupFile,
+				Value: targetFile + suffix,
+				Attrs: map[string]string{"origin": targetFile},
+			})
+		}
 
 		response = f.channel.Run(ctx, "mv", fmt.Sprintf(`-f "%s" "%s"`, filepath, target))
 	} else {
 		response = f.channel.Run(ctx, "mv", fmt.Sprintf(`"%s" "%s"`, filepath, target))
 	}
+	if response.Success {
+		store.Record(ctx, store.Resource{
+			Kind:  store.ResourceMovedFile,
+			Value: filepath,
+			Attrs: map[string]string{"target": targetFile},
+		})
+	}
 	return response
 }
 
+// stop moves the files back and restores the backups recorded at creation, the flags are used only if nothing
+// is recorded, such as the experiments created by the former versions
 func (f *FileMoveActionExecutor) stop(filepath, target string, ctx context.Context) *spec.Response {
+	moved := store.Resources(ctx, store.ResourceMovedFile)
+	if len(moved) == 0 {
+		return f.stopByFlags(filepath, target, ctx)
+	}
+	for _, resource := range moved {
+		response := f.channel.Run(ctx, "mv", fmt.Sprintf(`-f "%s" "%s"`, resource.Attrs["target"], resource.Value))
+		if !response.Success {
+			log.Errorf(ctx, "file-move-stop-move %s back to %s err, %s", resource.Attrs["target"], resource.Value, response.Err)
+			return response
+		}
+	}
+	for _, resource := range store.Resources(ctx, store.ResourceBackupFile) {
+		response := f.channel.Run(ctx, "mv", fmt.Sprintf(`"%s" "%s"`, resource.Value, resource.Attrs["origin"]))
+		if !response.Success {
+			log.Errorf(ctx, "file-move-stop-restore the backup %s err, %s", resource.Value, response.Err)
+			return response
+		}
+	}
+	return spec.Success()
+}
+
+func (f *FileMoveActionExecutor) stopByFlags(filepath, target string, ctx context.Context) *spec.Response {
 	origin := path.Join(target, "/", path.Base(filepath))
 	response := f.channel.Run(ctx, "mv", fmt.Sprintf(`-f "%s" "%s"`, origin, path.Dir(filepath)))
 	if response.Success {
@@ -0,0 +1,59 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package file
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+)
+
+func TestFileMoveStopRecorded(t *testing.T) {
+	directory := t.TempDir()
+	filepath := path.Join(directory, "origin", "data")
+	target := path.Join(directory, "target")
+	for file, content := range map[string]string{filepath: "moved", path.Join(target, "data"): "overwritten"} {
+		if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	experiment := &store.Experiment{Uid: "mv01"}
+	ctx := store.WithExperiment(context.Background(), nil, experiment)
+	executor := &FileMoveActionExecutor{channel: channel.NewLocalChannel()}
+	if response := executor.start(filepath, target, true, false, ctx); !response.Success {
+		t.Fatalf("start() err, %s", response.Err)
+	}
+	// the flags of destroy are not used, the files are restored by the recorded resources
+	if response := executor.stop(path.Join(directory, "other"), path.Join(directory, "other"), ctx); !response.Success {
+		t.Fatalf("stop() err, %s", response.Err)
+	}
+	for file, expect := range map[string]string{filepath: "moved", path.Join(target, "data"): "overwritten"} {
+		if content, err := os.ReadFile(file); err != nil || string(content) != expect {
+			t.Errorf("%s is %q, %v, want %q", file, content, err, expect)
+		}
+	}
+	if _, err := os.Stat(path.Join(target, "data"+suffix)); !os.IsNotExist(err) {
+		t.Errorf("the backup is not restored, %v", err)
+	}
+}
@@ -135,7 +135,7 @@ func (impl *HttpDelayExecutor) GetTargetDelay(ctx context.Context, url string, t
 	client := http.Client{}
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		log.Errorf(ctx, "http-delay-GetTargetDelay-get-request-url-failed", err)
+		log.Errorf(ctx, "http-delay-GetTargetDelay-get-request-url-failed, %v", err)
 		return spec.ReturnFail(spec.ActionNotSupport, fmt.Sprintf("get Request failed %s ", target))
 	}
 	duration := time.Duration(t) * time.Millisecond
@@ -145,7 +145,7 @@ func (impl *HttpDelayExecutor) GetTargetDelay(ctx context.Context, url string, t
 
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Errorf(ctx, "http-delay-GetTargetDelay-get-client-url-failed", err)
+		log.Errorf(ctx, "http-delay-GetTargetDelay-get-client-url-failed, %v", err)
 		return spec.ReturnFail(spec.ActionNotSupport, fmt.Sprintf("get client Request failed %s ", target))
 	}
 	defer resp.Body.Close()
@@ -155,7 +155,7 @@ func (impl *HttpDelayExecutor) GetTargetDelay(ctx context.Context, url string, t
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Errorf(ctx, "http-delay-GetTargetDelay-Failed to read response body", err)
+		log.Errorf(ctx, "http-delay-GetTargetDelay-Failed to read response body, %v", err)
 		return spec.ReturnFail(spec.ActionNotSupport, fmt.Sprintf("get client response body failed %s ", string(body)))
 	}
 
@@ -164,7 +164,7 @@ func (impl *HttpDelayExecutor) GetTargetDelay(ctx context.Context, url string, t
 			return spec.ReturnSuccess(resp.StatusCode)
 		}
 	}
-	return spec.ReturnFail(spec.ParameterRequestFailed, fmt.Sprintf("get response failed %s ", resp.StatusCode))
+	return spec.ReturnFail(spec.ParameterRequestFailed, fmt.Sprintf("get response failed %d ", resp.StatusCode))
 }
 
 func (impl *HttpDelayExecutor) stop(ctx context.Context, uid string) *spec.Response {
@@ -122,14 +122,14 @@ func (impl *HttpTimeoutExecutor) start(ctx context.Context, url string, t int) *
 
 	req, err := http.NewRequestWithContext(ctxNew, http.MethodGet, url, nil)
 	if err != nil {
-		log.Errorf(ctxNew, "http-timeout-start-http NewRequestWithContext creation failed:", err.Error())
+		log.Errorf(ctxNew, "http-timeout-start-http NewRequestWithContext creation failed: %s", err.Error())
 		return spec.ResponseFailWithFlags(spec.ActionNotSupport, "NewRequestWithContext timeout", t)
 	}
 
 	client := http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Errorf(ctxNew, "http-timeout-start-Request failed:", err.Error())
+		log.Errorf(ctxNew, "http-timeout-start-Request failed: %s", err.Error())
 		return spec.ResponseFailWithFlags(spec.ActionNotSupport, "the interrupt HTTP connection timeout", t)
 	}
 	defer resp.Body.Close()
@@ -139,7 +139,7 @@ func (impl *HttpTimeoutExecutor) start(ctx context.Context, url string, t int) *
 			return spec.ReturnSuccess(resp.StatusCode)
 		}
 	}
-	return spec.ReturnFail(spec.ParameterRequestFailed, fmt.Sprintf("get response failed %s ", resp.StatusCode))
+	return spec.ReturnFail(spec.ParameterRequestFailed, fmt.Sprintf("get response failed %d ", resp.StatusCode))
 }
 
 func (impl *HttpTimeoutExecutor) stop(ctx context.Context, uid string) *spec.Response {
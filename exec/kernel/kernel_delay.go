@@ -19,13 +19,9 @@ package kernel
 import (
 	"context"
 	"fmt"
-	"github.com/chaosblade-io/chaosblade-exec-os/exec"
 	"github.com/chaosblade-io/chaosblade-spec-go/log"
-	"path"
-	"strings"
 
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
-	"github.com/chaosblade-io/chaosblade-spec-go/util"
 
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
 )
@@ -56,12 +52,12 @@ func NewStraceDelayActionSpec() spec.ExpActionCommandSpec {
 			ActionFlags: []spec.ExpFlagSpec{
 				&spec.ExpFlag{
 					Name:     "syscall-name",
-					Desc:     "The target syscall which will be injected",
+					Desc:     "The target syscalls which will be injected, separated by ',', for example openat,write",
 					Required: true,
 				},
 				&spec.ExpFlag{
 					Name:     "time",
-					Desc:     "sleep time, the unit of time can be specified: s,ms,us,ns",
+					Desc:     "sleep time, the unit of time can be specified: s,ms,us,ns, the number without unit is us",
 					Required: true,
 				},
 				&spec.ExpFlag{
@@ -81,11 +77,29 @@ func NewStraceDelayActionSpec() spec.ExpActionCommandSpec {
 					Name: "step",
 					Desc: "the fault will be injected intervally",
 				},
+				&spec.ExpFlag{
+					Name: "path",
+					Desc: "only the syscalls accessing the files under the paths are injected, the path argument or the file of the fd argument is matched, separated by ',', for example /data/wal",
+				},
+				&spec.ExpFlag{
+					Name: "address",
+					Desc: "only the syscalls such as connect, bind and sendto to the addresses are injected, the address is ip, cidr, ip:port, :port or the path of the unix socket, separated by ','",
+				},
+				&spec.ExpFlag{
+					Name: "percent",
+					Desc: "the percentage of the matched invocations to inject, in 1..100, all matched invocations are injected by default",
+				},
 			},
 			ActionExecutor: &StraceDelayActionExecutor{},
 			ActionExample: `
 # Create a strace 10s delay experiment to the process
-blade create strace delay --pid 1 --syscall-name mmap --time 10s --delay-loc enter --first=1`,
+blade create strace delay --pid 1 --syscall-name mmap --time 10s --delay-loc enter --first=1
+
+# Delay 50% of the writes and fsyncs to the files under /data/wal by 100ms
+blade create strace delay --pid 1 --syscall-name write,fsync --time 100ms --delay-loc exit --path /data/wal --percent 50
+
+# Delay the connections to the port 3306 of 10.0.0.1 by 3s
+blade create strace delay --pid 1 --syscall-name connect --time 3s --delay-loc enter --address 10.0.0.1:3306`,
 			ActionPrograms:    []string{StraceDelayBin},
 			ActionCategories:  []string{category.SystemKernel},
 			ActionProcessHang: true,
@@ -128,78 +142,68 @@ func (dae *StraceDelayActionExecutor) Exec(uid string, ctx context.Context, mode
 	if dae.channel == nil {
 		return spec.ResponseFailWithFlags(spec.ChannelNil)
 	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return dae.stop(ctx)
+	}
 
-	var pidList string
-	var delay_loc_flag string
-	var first_flag string
-	var end_flag string
-	var step string
-	pidStr := model.ActionFlags["pid"]
-	if pidStr != "" {
-		pids, err := util.ParseIntegerListToStringSlice("pid", pidStr)
-		if err != nil {
-			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "pid", pidStr, err)
-		}
-		pidList = strings.Join(pids, ",")
+	if response := checkSyscallFaultSupported(ctx); response != nil {
+		return response
+	}
+	pids, response := parseSyscallPids(ctx, model.ActionFlags["pid"])
+	if response != nil {
+		return response
 	}
 	time := model.ActionFlags["time"]
 	if time == "" {
 		log.Errorf(ctx, "kernel-delay-Exec-time is nil")
 		return spec.ResponseFailWithFlags(spec.ParameterLess, "time")
 	}
+	delay, err := parseSyscallDelay(time)
+	if err != nil {
+		log.Errorf(ctx, "`%s`: kernel-delay-Exec-time is illegal, %v", time, err)
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "time", time, "it must be a positive number with the s, ms, us or ns unit")
+	}
+	filter, response := parseFaultFilter(ctx, model.ActionFlags)
+	if response != nil {
+		return response
+	}
 	syscallName := model.ActionFlags["syscall-name"]
-	if syscallName == "" {
-		log.Errorf(ctx, "kernel-delay-Exec-syscall-name is nil")
-		return spec.ResponseFailWithFlags(spec.ParameterLess, "syscall-name")
+	syscalls, response := parseSyscallNames(ctx, syscallName, filter)
+	if response != nil {
+		return response
 	}
 
-	delay_loc_flag = model.ActionFlags["delay-loc"]
-	if delay_loc_flag == "" {
+	delayLoc := model.ActionFlags["delay-loc"]
+	if delayLoc == "" {
 		log.Errorf(ctx, "kernel-delay-Exec-delay-loc is nil")
 		return spec.ResponseFailWithFlags(spec.ParameterLess, "delay-loc")
 	}
-	first_flag = model.ActionFlags["first"]
-	end_flag = model.ActionFlags["end"]
-	step = model.ActionFlags["step"]
-	if _, ok := spec.IsDestroy(ctx); ok {
-		return dae.stop(ctx, pidList, syscallName)
+	if delayLoc != "enter" && delayLoc != "exit" {
+		log.Errorf(ctx, "`%s`: kernel-delay-Exec-delay-loc is illegal", delayLoc)
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "delay-loc", delayLoc, "it must be enter or exit")
 	}
-	return dae.start(ctx, pidList, time, syscallName, delay_loc_flag, first_flag, end_flag, step)
+	when, err := parseInjectWhen(model.ActionFlags["first"], model.ActionFlags["end"], model.ActionFlags["step"])
+	if err != nil {
+		log.Errorf(ctx, "kernel-delay-Exec-first|end|step is illegal, %v", err)
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "first|end|step",
+			fmt.Sprintf("%s|%s|%s", model.ActionFlags["first"], model.ActionFlags["end"], model.ActionFlags["step"]), err)
+	}
+	return dae.start(ctx, pids, &syscallFault{
+		syscall:    syscallName,
+		syscalls:   syscalls,
+		delay:      delay,
+		delayEnter: delayLoc == "enter",
+		when:       when,
+		filter:     filter,
+	})
 }
 
 // start strace delay
-func (dae *StraceDelayActionExecutor) start(ctx context.Context, pidList string, time string, syscallName string, delayLoc string, first string, end string, step string) *spec.Response {
-	if pidList != "" {
-		pids := strings.Split(pidList, ",")
-
-		var args = ""
-		if delayLoc == "enter" {
-			args = fmt.Sprintf("-f -e inject=%s:delay_enter=%s", syscallName, time)
-		} else if delayLoc == "exit" {
-			args = fmt.Sprintf("-f -e inject=%s:delay_exit=%s", syscallName, time)
-		}
-
-		if first != "" {
-			args = fmt.Sprintf("%s:when=%s", args, first)
-			if step != "" && end != "" {
-				args = fmt.Sprintf("%s..%s+%s", args, end, step)
-			} else if step != "" {
-				args = fmt.Sprintf("%s+%s", args, step)
-			} else if end != "" {
-				args = fmt.Sprintf("%s..%s", args, end)
-			}
-		}
-
-		for _, pid := range pids {
-			args = fmt.Sprintf("-p %s %s", pid, args)
-		}
-
-		return dae.channel.Run(ctx, path.Join(util.GetProgramPath(), "strace"), args)
-	}
-	return spec.ResponseFailWithFlags(spec.ParameterInvalid, "pid", pidList, "pid is nil")
+func (dae *StraceDelayActionExecutor) start(ctx context.Context, pids []int, fault *syscallFault) *spec.Response {
+	return runSyscallFault(ctx, pids, fault)
 }
 
-func (dae *StraceDelayActionExecutor) stop(ctx context.Context, pidList string, syscallName string) *spec.Response {
+func (dae *StraceDelayActionExecutor) stop(ctx context.Context) *spec.Response {
 	ctx = context.WithValue(ctx, "bin", StraceDelayBin)
-	return exec.Destroy(ctx, dae.channel, "strace delay")
+	return stopSyscallFault(ctx, dae.channel, "strace delay")
 }
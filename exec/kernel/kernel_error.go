@@ -19,19 +19,20 @@ package kernel
 import (
 	"context"
 	"fmt"
-	"github.com/chaosblade-io/chaosblade-exec-os/exec"
 	"github.com/chaosblade-io/chaosblade-spec-go/log"
-	"path"
-	"strings"
 
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
-	"github.com/chaosblade-io/chaosblade-spec-go/util"
 
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
 )
 
 const StraceErrorBin = "chaos_straceerror"
 
+const (
+	ReturnModeError  = "error"
+	ReturnModeRetval = "retval"
+)
+
 type StraceErrorActionSpec struct {
 	spec.BaseExpActionCommandSpec
 }
@@ -56,14 +57,19 @@ func NewStraceErrorActionSpec() spec.ExpActionCommandSpec {
 			ActionFlags: []spec.ExpFlagSpec{
 				&spec.ExpFlag{
 					Name:     "syscall-name",
-					Desc:     "The target syscall which will be injected",
+					Desc:     "The target syscalls which will be injected, separated by ',', for example openat,write",
 					Required: true,
 				},
 				&spec.ExpFlag{
 					Name:     "return-value",
-					Desc:     "the return-value the syscall will return",
+					Desc:     "the value the syscall will return, it is the errno name such as ENOSPC or the errno number such as 28 in the error mode, and the non-negative result in the retval mode",
 					Required: true,
 				},
+				&spec.ExpFlag{
+					Name:    "return-mode",
+					Desc:    "the mode of the return value, error or retval, the syscall is not executed and fails with the errno in the error mode, or succeeds with the result in the retval mode",
+					Default: ReturnModeError,
+				},
 				&spec.ExpFlag{
 					Name: "first",
 					Desc: "if the flag is true, the fault will be injected to the first met syscall",
@@ -76,11 +82,32 @@ func NewStraceErrorActionSpec() spec.ExpActionCommandSpec {
 					Name: "step",
 					Desc: "the fault will be injected intervally",
 				},
+				&spec.ExpFlag{
+					Name: "path",
+					Desc: "only the syscalls accessing the files under the paths are injected, the path argument or the file of the fd argument is matched, separated by ',', for example /data/wal",
+				},
+				&spec.ExpFlag{
+					Name: "address",
+					Desc: "only the syscalls such as connect, bind and sendto to the addresses are injected, the address is ip, cidr, ip:port, :port or the path of the unix socket, separated by ','",
+				},
+				&spec.ExpFlag{
+					Name: "percent",
+					Desc: "the percentage of the matched invocations to inject, in 1..100, all matched invocations are injected by default",
+				},
 			},
 			ActionExecutor: &StraceErrorActionExecutor{},
 			ActionExample: `
 # Create a strace error experiment to the process
-blade create strace error --pid 1 --syscall-name mmap --return-value XX --delay-loc enter --first=1`,
+blade create strace error --pid 1 --syscall-name mmap --return-value ENOMEM --first=1
+
+# The first write of the process returns 0 without writing anything
+blade create strace error --pid 1 --syscall-name write --return-value 0 --return-mode retval --first=1
+
+# 5% of the writes to the files under /data/wal return EIO
+blade create strace error --pid 1 --syscall-name write --return-value EIO --path /data/wal --percent 5
+
+# Opening the files under /data returns ENOENT
+blade create strace error --pid 1 --syscall-name openat --return-value ENOENT --path /data`,
 			ActionPrograms:    []string{StraceErrorBin},
 			ActionCategories:  []string{category.SystemKernel},
 			ActionProcessHang: true,
@@ -120,69 +147,68 @@ func (*StraceErrorActionExecutor) Name() string {
 }
 
 func (dae *StraceErrorActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return dae.stop(ctx)
+	}
 
-	var pidList string
-	var first_flag string
-	var end_flag string
-	var step string
-
-	pidStr := model.ActionFlags["pid"]
-	if pidStr != "" {
-		pids, err := util.ParseIntegerListToStringSlice("pid", pidStr)
-		if err != nil {
-			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "pid", pidStr, err)
-		}
-		pidList = strings.Join(pids, ",")
+	if response := checkSyscallFaultSupported(ctx); response != nil {
+		return response
+	}
+	pids, response := parseSyscallPids(ctx, model.ActionFlags["pid"])
+	if response != nil {
+		return response
 	}
-	return_value := model.ActionFlags["return-value"]
-	if return_value == "" {
+	returnValue := model.ActionFlags["return-value"]
+	if returnValue == "" {
 		log.Errorf(ctx, "kernel-error-Exec-return-value is nil")
 		return spec.ResponseFailWithFlags(spec.ParameterLess, "return-value")
 	}
+	fault := &syscallFault{}
+	switch returnMode := model.ActionFlags["return-mode"]; returnMode {
+	case "", ReturnModeError:
+		errno, err := parseErrno(returnValue)
+		if err != nil {
+			log.Errorf(ctx, "`%s`: kernel-error-Exec-return-value is illegal, %v", returnValue, err)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "return-value", returnValue, err)
+		}
+		fault.errno = errno
+	case ReturnModeRetval:
+		retval, err := parseRetval(returnValue)
+		if err != nil {
+			log.Errorf(ctx, "`%s`: kernel-error-Exec-return-value is illegal, %v", returnValue, err)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "return-value", returnValue, err)
+		}
+		fault.retval, fault.setRetval = retval, true
+	default:
+		log.Errorf(ctx, "`%s`: kernel-error-Exec-return-mode is illegal", returnMode)
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "return-mode", returnMode,
+			fmt.Sprintf("it must be %s or %s", ReturnModeError, ReturnModeRetval))
+	}
+	filter, response := parseFaultFilter(ctx, model.ActionFlags)
+	if response != nil {
+		return response
+	}
 	syscallName := model.ActionFlags["syscall-name"]
-	if syscallName == "" {
-		log.Errorf(ctx, "kernel-error-Exec-syscall-name is nil")
-		return spec.ResponseFailWithFlags(spec.ParameterLess, "syscall-name")
+	syscalls, response := parseSyscallNames(ctx, syscallName, filter)
+	if response != nil {
+		return response
 	}
-
-	first_flag = model.ActionFlags["first"]
-	end_flag = model.ActionFlags["end"]
-
-	step = model.ActionFlags["step"]
-
-	if _, ok := spec.IsDestroy(ctx); ok {
-		return dae.stop(ctx, pidList, syscallName)
+	when, err := parseInjectWhen(model.ActionFlags["first"], model.ActionFlags["end"], model.ActionFlags["step"])
+	if err != nil {
+		log.Errorf(ctx, "kernel-error-Exec-first|end|step is illegal, %v", err)
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "first|end|step",
+			fmt.Sprintf("%s|%s|%s", model.ActionFlags["first"], model.ActionFlags["end"], model.ActionFlags["step"]), err)
 	}
-	// fmt.Printf("%s,%s,%s",first_flag,end_flag,step)
-	return dae.start(ctx, pidList, return_value, syscallName, first_flag, end_flag, step)
+	fault.syscall, fault.syscalls, fault.when, fault.filter = syscallName, syscalls, when, filter
+	return dae.start(ctx, pids, fault)
 }
 
 // start strace Error
-func (dae *StraceErrorActionExecutor) start(ctx context.Context, pidList string, returnValue string, syscallName string, first string, end string, step string) *spec.Response {
-	if pidList != "" {
-		pids := strings.Split(pidList, ",")
-		args := fmt.Sprintf("-f -e inject=%s:error=%s", syscallName, returnValue)
-
-		if first != "" {
-			args = fmt.Sprintf("%s:when=%s", args, first)
-			if step != "" && end != "" {
-				args = fmt.Sprintf("%s..%s+%s", args, end, step)
-			} else if step != "" {
-				args = fmt.Sprintf("%s+%s", args, step)
-			} else if end != "" {
-				args = fmt.Sprintf("%s..%s", args, end)
-			}
-		}
-
-		for _, pid := range pids {
-			args = fmt.Sprintf("-p %s %s", pid, args)
-		}
-
-		return dae.channel.Run(ctx, path.Join(util.GetProgramPath(), "strace"), args)
-	}
-	return spec.ResponseFailWithFlags(spec.ParameterInvalid, "pid", pidList, "pid is nil")
+func (dae *StraceErrorActionExecutor) start(ctx context.Context, pids []int, fault *syscallFault) *spec.Response {
+	return runSyscallFault(ctx, pids, fault)
 }
 
-func (dae *StraceErrorActionExecutor) stop(ctx context.Context, pidList string, syscallName string) *spec.Response {
-	return exec.Destroy(ctx, dae.channel, "strace delay")
+func (dae *StraceErrorActionExecutor) stop(ctx context.Context) *spec.Response {
+	ctx = context.WithValue(ctx, "bin", StraceErrorBin)
+	return stopSyscallFault(ctx, dae.channel, "strace error")
 }
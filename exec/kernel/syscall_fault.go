@@ -0,0 +1,263 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/dryrun"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"golang.org/x/sys/unix"
+)
+
+// maxErrno is the max errno returned by the syscalls, the negative return values greater than -4096 are errors
+const maxErrno = 4095
+
+// detachTimeout is the time waiting for the injector to detach from the target processes when destroyed
+const detachTimeout = 10 * time.Second
+
+// syscallFault is the fault injected into the syscall of the target processes
+type syscallFault struct {
+	syscall string
+	// syscalls are the names of the target syscalls by their numbers
+	syscalls map[int]string
+	// delay sleeps before the syscall is executed if delayEnter is true, otherwise after it is executed
+	delay      time.Duration
+	delayEnter bool
+	// errno is returned instead of executing the syscall if it is not 0
+	errno syscall.Errno
+	// retval is returned as the successful result instead of executing the syscall if setRetval is true
+	retval    int64
+	setRetval bool
+	when      injectWhen
+	filter    syscallFilter
+}
+
+// injectWhen is the invocations of the syscall to inject, which is the first..end+step expression of strace.
+// The invocations matching the filter are counted from 1 for each syscall of each thread,
+// all invocations are injected if first is 0.
+type injectWhen struct {
+	first int
+	end   int
+	step  int
+}
+
+// parseInjectWhen parses the first, end and step flags, the first invocation is 1 if only end or step is specified
+func parseInjectWhen(first, end, step string) (injectWhen, error) {
+	var when injectWhen
+	for _, flag := range []struct {
+		name  string
+		value string
+		field *int
+	}{{"first", first, &when.first}, {"end", end, &when.end}, {"step", step, &when.step}} {
+		if flag.value == "" {
+			continue
+		}
+		value, err := strconv.Atoi(flag.value)
+		if err != nil || value <= 0 {
+			return when, fmt.Errorf("the %s flag must be a positive integer", flag.name)
+		}
+		*flag.field = value
+	}
+	if when.first == 0 && (when.end > 0 || when.step > 0) {
+		when.first = 1
+	}
+	if when.end > 0 && when.end < when.first {
+		return when, fmt.Errorf("the end flag must not be less than the first flag")
+	}
+	return when, nil
+}
+
+// match returns true if the count-th invocation is injected
+func (w injectWhen) match(count int) bool {
+	if w.first == 0 {
+		return true
+	}
+	if count < w.first || w.end > 0 && count > w.end {
+		return false
+	}
+	if w.step > 0 {
+		return (count-w.first)%w.step == 0
+	}
+	// only the first invocation is injected without the end and step
+	return w.end > 0 || count == w.first
+}
+
+// parseSyscallDelay parses the delay with the s, ms, us or ns unit, the number without unit is microseconds like strace
+func parseSyscallDelay(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if us, err := strconv.Atoi(value); err == nil {
+		value = fmt.Sprintf("%dus", us)
+	}
+	delay, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if delay <= 0 {
+		return 0, fmt.Errorf("the delay must be positive")
+	}
+	return delay, nil
+}
+
+// parseErrno returns the errno of the name such as ENOSPC or the number, the name is case insensitive
+func parseErrno(value string) (syscall.Errno, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return 0, fmt.Errorf("the errno is empty")
+	}
+	if number, err := strconv.Atoi(value); err == nil {
+		if number <= 0 || number > maxErrno {
+			return 0, fmt.Errorf("the errno must be in 1..%d", maxErrno)
+		}
+		return syscall.Errno(number), nil
+	}
+	for number := 1; number <= maxErrno; number++ {
+		if unix.ErrnoName(syscall.Errno(number)) == value {
+			return syscall.Errno(number), nil
+		}
+	}
+	return 0, fmt.Errorf("the errno %s is not found", value)
+}
+
+// parseRetval returns the successful result of the syscall, the negative values are not allowed
+// because they are treated as the errno by the callers
+func parseRetval(value string) (int64, error) {
+	retval, err := strconv.ParseInt(strings.TrimSpace(value), 0, 64)
+	if err != nil || retval < 0 {
+		return 0, fmt.Errorf("the retval must be a non-negative integer")
+	}
+	return retval, nil
+}
+
+// checkSyscallFaultSupported returns the failed response if the syscall fault is not supported on the platform
+func checkSyscallFaultSupported(ctx context.Context) *spec.Response {
+	if err := syscallFaultSupported(); err != nil {
+		log.Errorf(ctx, "kernel-%v", err)
+		return spec.ReturnFail(spec.ActionNotSupport, err.Error())
+	}
+	return nil
+}
+
+// parseSyscallNames returns the target syscalls of the syscall-name flag separated by commas,
+// the syscalls must be found on the current architecture and be able to be filtered by the filter
+func parseSyscallNames(ctx context.Context, value string, filter syscallFilter) (map[int]string, *spec.Response) {
+	syscalls := make(map[int]string)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		nr, ok := lookupSyscall(name)
+		if !ok {
+			log.Errorf(ctx, "`%s`: kernel-syscall-name is illegal", name)
+			return nil, spec.ResponseFailWithFlags(spec.ParameterIllegal, "syscall-name", value, fmt.Sprintf("the syscall %s is not found", name))
+		}
+		if err := filter.check(name); err != nil {
+			log.Errorf(ctx, "`%s`: kernel-syscall-name can not be filtered, %v", name, err)
+			return nil, spec.ResponseFailWithFlags(spec.ParameterIllegal, "syscall-name", value, err)
+		}
+		syscalls[nr] = name
+	}
+	if len(syscalls) == 0 {
+		log.Errorf(ctx, "kernel-syscall-name is nil")
+		return nil, spec.ResponseFailWithFlags(spec.ParameterLess, "syscall-name")
+	}
+	return syscalls, nil
+}
+
+// parseFaultFilter returns the filter of the path, address and percent flags
+func parseFaultFilter(ctx context.Context, flags map[string]string) (syscallFilter, *spec.Response) {
+	filter, err := parseSyscallFilter(flags["path"], flags["address"], flags["percent"])
+	if err != nil {
+		log.Errorf(ctx, "kernel-path|address|percent is illegal, %v", err)
+		return filter, spec.ResponseFailWithFlags(spec.ParameterIllegal, "path|address|percent",
+			fmt.Sprintf("%s|%s|%s", flags["path"], flags["address"], flags["percent"]), err)
+	}
+	return filter, nil
+}
+
+// parseSyscallPids returns the pids of the pid flag separated by commas
+func parseSyscallPids(ctx context.Context, pidStr string) ([]int, *spec.Response) {
+	pids := make([]int, 0)
+	for _, value := range strings.Split(pidStr, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		pid, err := strconv.Atoi(value)
+		if err != nil || pid <= 0 {
+			log.Errorf(ctx, "`%s`: kernel-pid is illegal", pidStr)
+			return nil, spec.ResponseFailWithFlags(spec.ParameterIllegal, "pid", pidStr, "it must be positive integers separated by ','")
+		}
+		pids = append(pids, pid)
+	}
+	if len(pids) == 0 {
+		log.Errorf(ctx, "kernel-pid is nil")
+		return nil, spec.ResponseFailWithFlags(spec.ParameterLess, "pid")
+	}
+	return pids, nil
+}
+
+// runSyscallFault injects the fault into the processes until the injector is terminated by the destroy command
+func runSyscallFault(ctx context.Context, pids []int, fault *syscallFault) *spec.Response {
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+	if err := injectSyscallFault(ctx, pids, fault, stop); err != nil {
+		log.Errorf(ctx, "kernel-runSyscallFault-inject %s fault err, %v", fault.syscall, err)
+		return spec.ResponseFailWithFlags(spec.OsCmdExecFailed, "ptrace", err)
+	}
+	return spec.Success()
+}
+
+// stopSyscallFault terminates the injector process, which detaches from the target processes and leaves them running.
+// The injector is killed if it does not exit in time, or it is not recorded.
+func stopSyscallFault(ctx context.Context, cl spec.Channel, action string) *spec.Response {
+	pids := store.AlivePids(ctx)
+	if len(pids) == 0 {
+		return exec.Destroy(ctx, cl, action)
+	}
+	// the injector runs on the host, so it is terminated by the local channel unless the commands are recorded
+	if recorder, ok := cl.(*dryrun.RecordChannel); ok {
+		return recorder.Run(ctx, "kill", fmt.Sprintf("-TERM %s", strings.Join(pids, " ")))
+	}
+	if response := channel.NewLocalChannel().Run(ctx, "kill", fmt.Sprintf("-TERM %s", strings.Join(pids, " "))); !response.Success {
+		return response
+	}
+	for deadline := time.Now().Add(detachTimeout); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		if len(store.AlivePids(ctx)) == 0 {
+			return spec.Success()
+		}
+	}
+	log.Warnf(ctx, "kernel-stopSyscallFault-injector %v does not exit in %s, kill it", pids, detachTimeout)
+	return exec.Destroy(ctx, cl, action)
+}
@@ -0,0 +1,36 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kernel
+
+import (
+	"context"
+	"errors"
+)
+
+// syscallFaultSupported returns the error because the syscall fault is only supported on linux
+func syscallFaultSupported() error {
+	return errors.New("the syscall fault is not supported on darwin")
+}
+
+// lookupSyscall returns false because the syscall fault is only supported on linux
+func lookupSyscall(name string) (int, bool) {
+	return 0, false
+}
+
+func injectSyscallFault(ctx context.Context, pids []int, fault *syscallFault, stop <-chan struct{}) error {
+	return errors.New("the syscall fault is not supported on darwin")
+}
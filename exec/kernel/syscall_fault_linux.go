@@ -0,0 +1,356 @@
+//go:build linux && (amd64 || arm64)
+
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"golang.org/x/sys/unix"
+)
+
+// the options of the tracees, the children and threads created by the tracees are traced too.
+// PTRACE_O_EXITKILL is not set, so the tracees keep running if the injector is killed.
+const ptraceOptions = unix.PTRACE_O_TRACESYSGOOD | unix.PTRACE_O_TRACECLONE | unix.PTRACE_O_TRACEFORK |
+	unix.PTRACE_O_TRACEVFORK | unix.PTRACE_O_TRACEEXEC
+
+// syscallFaultSupported returns nil, the syscall fault is supported on linux amd64 and arm64
+func syscallFaultSupported() error {
+	return nil
+}
+
+// lookupSyscall returns the number of the syscall on the current architecture
+func lookupSyscall(name string) (int, bool) {
+	nr, ok := syscallNumbers[name]
+	return nr, ok
+}
+
+// tracee is a traced thread
+type tracee struct {
+	tid int
+	// inSyscall is true between the syscall-enter-stop and the syscall-exit-stop
+	inSyscall bool
+	// counts are the matched invocations of the target syscalls by the thread
+	counts map[int]int
+	// injected is true if the syscall is skipped at enter, the return value is set at exit
+	injected bool
+	// delayExit is true if the thread is delayed at the exit of the syscall
+	delayExit bool
+	// resumeAt is the time to resume the delayed thread, it is zero if the thread is not delayed
+	resumeAt time.Time
+}
+
+func newTracee(tid int) *tracee {
+	return &tracee{tid: tid, counts: make(map[int]int)}
+}
+
+type waitEvent struct {
+	tid    int
+	status unix.WaitStatus
+	err    error
+}
+
+// syscallInjector injects the fault into the syscall of the tracees. All ptrace requests are made by the thread
+// which attached to the tracees, so the injector runs on a locked os thread.
+type syscallInjector struct {
+	ctx      context.Context
+	fault    *syscallFault
+	tracees  map[int]*tracee
+	stopping bool
+}
+
+// injectSyscallFault attaches to all threads of the processes by ptrace and injects the fault into the syscall
+// until the stop channel is closed, then it detaches from the processes which keep running.
+// The seccomp user notification is not used because it can not be installed into the running processes.
+func injectSyscallFault(ctx context.Context, pids []int, fault *syscallFault, stop <-chan struct{}) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	si := &syscallInjector{ctx: ctx, fault: fault, tracees: make(map[int]*tracee)}
+	attachErr := si.attach(pids)
+	if attachErr != nil {
+		log.Errorf(ctx, "kernel-injectSyscallFault-attach err, %v", attachErr)
+		si.startDetach()
+	} else {
+		log.Infof(ctx, "kernel-injectSyscallFault-attached to %d threads of %v", len(si.tracees), pids)
+	}
+
+	// the tracees of the locked thread can be waited by the other threads of the process
+	events := make(chan waitEvent, 64)
+	go func() {
+		for {
+			var status unix.WaitStatus
+			tid, err := unix.Wait4(-1, &status, unix.WALL, nil)
+			if err == unix.EINTR {
+				continue
+			}
+			events <- waitEvent{tid: tid, status: status, err: err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for len(si.tracees) > 0 {
+		var wake <-chan time.Time
+		if next := si.nextResume(); !next.IsZero() {
+			wake = time.After(time.Until(next))
+		}
+		select {
+		case <-stop:
+			stop = nil
+			si.startDetach()
+		case now := <-wake:
+			si.resumeDelayed(now)
+		case event := <-events:
+			if event.err != nil {
+				return fmt.Errorf("wait the tracees err, %v", event.err)
+			}
+			si.handle(event.tid, event.status)
+		}
+	}
+	if attachErr != nil {
+		return attachErr
+	}
+	log.Infof(ctx, "kernel-injectSyscallFault-detached from %v", pids)
+	return nil
+}
+
+// attach seizes all threads of the processes, the threads are listed again until no new thread is found
+// because they may be created before the creators are seized
+func (si *syscallInjector) attach(pids []int) error {
+	for _, pid := range pids {
+		for {
+			entries, err := os.ReadDir(fmt.Sprintf("/proc/%d/task", pid))
+			if err != nil {
+				return fmt.Errorf("the process %d is not found", pid)
+			}
+			seized := false
+			for _, entry := range entries {
+				tid, err := strconv.Atoi(entry.Name())
+				if err != nil || si.tracees[tid] != nil {
+					continue
+				}
+				if _, _, errno := unix.Syscall6(unix.SYS_PTRACE, unix.PTRACE_SEIZE, uintptr(tid), 0, ptraceOptions, 0, 0); errno != 0 {
+					if errno == unix.ESRCH {
+						// the thread exits
+						continue
+					}
+					return fmt.Errorf("seize the thread %d of the process %d err, %v", tid, pid, errno)
+				}
+				si.tracees[tid] = newTracee(tid)
+				// the seized thread is stopped to start tracing the syscalls
+				if err := unix.PtraceInterrupt(tid); err != nil {
+					log.Warnf(si.ctx, "kernel-attach-interrupt the thread %d err, %v", tid, err)
+				}
+				seized = true
+			}
+			if !seized {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// handle handles the stop or exit of the tracee
+func (si *syscallInjector) handle(tid int, status unix.WaitStatus) {
+	t := si.tracees[tid]
+	if t == nil {
+		// the new thread or child is attached automatically
+		t = newTracee(tid)
+		si.tracees[tid] = t
+	}
+	if status.Exited() || status.Signaled() {
+		delete(si.tracees, tid)
+		return
+	}
+	if !status.Stopped() {
+		return
+	}
+	sig := status.StopSignal()
+	event := int(status) >> 16
+	switch {
+	case sig == syscall.SIGTRAP|0x80:
+		si.handleSyscall(t)
+	case event == unix.PTRACE_EVENT_STOP:
+		switch sig {
+		case syscall.SIGSTOP, syscall.SIGTSTP, syscall.SIGTTIN, syscall.SIGTTOU:
+			// group-stop, the tracee keeps stopped until it is continued by SIGCONT
+			if si.stopping {
+				si.detach(t, 0)
+			} else if _, _, errno := unix.Syscall6(unix.SYS_PTRACE, unix.PTRACE_LISTEN, uintptr(tid), 0, 0, 0, 0); errno != 0 {
+				log.Warnf(si.ctx, "kernel-handle-listen the thread %d err, %v", tid, errno)
+			}
+		default:
+			// the stop of the interrupt or the new tracee
+			si.resume(t, 0)
+		}
+	case event == unix.PTRACE_EVENT_EXEC:
+		// the thread calling execve takes over the tid of the thread group leader
+		if former, err := unix.PtraceGetEventMsg(tid); err == nil && int(former) != tid {
+			if f := si.tracees[int(former)]; f != nil {
+				t.inSyscall, t.counts = f.inSyscall, f.counts
+				delete(si.tracees, int(former))
+			}
+		}
+		si.resume(t, 0)
+	case event != 0:
+		si.resume(t, 0)
+	default:
+		// signal-delivery-stop, the signal is delivered to the tracee
+		si.resume(t, int(sig))
+	}
+}
+
+// handleSyscall injects the fault at the syscall-enter-stop or the syscall-exit-stop
+func (si *syscallInjector) handleSyscall(t *tracee) {
+	t.inSyscall = !t.inSyscall
+	if !t.inSyscall {
+		if t.injected {
+			t.injected = false
+			si.setReturn(t)
+		} else if t.delayExit {
+			t.delayExit = false
+			if !si.stopping {
+				si.hold(t)
+				return
+			}
+		}
+		si.resume(t, 0)
+		return
+	}
+	if si.stopping {
+		si.detach(t, 0)
+		return
+	}
+	var regs syscall.PtraceRegs
+	if err := syscall.PtraceGetRegs(t.tid, &regs); err != nil {
+		log.Warnf(si.ctx, "kernel-handleSyscall-get the registers of %d err, %v", t.tid, err)
+		si.resume(t, 0)
+		return
+	}
+	nr := syscallNr(&regs)
+	name, ok := si.fault.syscalls[nr]
+	if !ok || !matchTarget(t.tid, &regs, name, si.fault.filter) {
+		si.resume(t, 0)
+		return
+	}
+	t.counts[nr]++
+	if !si.fault.when.match(t.counts[nr]) || !si.fault.filter.roll() {
+		si.resume(t, 0)
+		return
+	}
+	switch {
+	case si.fault.errno != 0 || si.fault.setRetval:
+		if err := skipSyscall(t.tid, &regs); err != nil {
+			log.Warnf(si.ctx, "kernel-handleSyscall-skip the syscall of %d err, %v", t.tid, err)
+		} else {
+			t.injected = true
+		}
+	case si.fault.delayEnter:
+		si.hold(t)
+		return
+	default:
+		t.delayExit = true
+	}
+	si.resume(t, 0)
+}
+
+// setReturn sets the errno or the retval of the skipped syscall at the syscall-exit-stop
+func (si *syscallInjector) setReturn(t *tracee) {
+	value := -int64(si.fault.errno)
+	if si.fault.setRetval {
+		value = si.fault.retval
+	}
+	var regs syscall.PtraceRegs
+	if err := syscall.PtraceGetRegs(t.tid, &regs); err != nil {
+		log.Warnf(si.ctx, "kernel-setReturn-get the registers of %d err, %v", t.tid, err)
+		return
+	}
+	if err := setSyscallReturn(t.tid, &regs, value); err != nil {
+		log.Warnf(si.ctx, "kernel-setReturn-set the return value of %d err, %v", t.tid, err)
+	}
+}
+
+// hold keeps the tracee stopped until the delay expires
+func (si *syscallInjector) hold(t *tracee) {
+	t.resumeAt = time.Now().Add(si.fault.delay)
+}
+
+// nextResume returns the earliest time to resume the delayed tracees
+func (si *syscallInjector) nextResume() time.Time {
+	var next time.Time
+	for _, t := range si.tracees {
+		if !t.resumeAt.IsZero() && (next.IsZero() || t.resumeAt.Before(next)) {
+			next = t.resumeAt
+		}
+	}
+	return next
+}
+
+func (si *syscallInjector) resumeDelayed(now time.Time) {
+	for _, t := range si.tracees {
+		if !t.resumeAt.IsZero() && !t.resumeAt.After(now) {
+			t.resumeAt = time.Time{}
+			si.resume(t, 0)
+		}
+	}
+}
+
+// resume restarts the tracee until the next syscall stop, or detaches from it when stopping
+// unless the return value of the skipped syscall is not set yet
+func (si *syscallInjector) resume(t *tracee, sig int) {
+	if si.stopping && !t.injected {
+		si.detach(t, sig)
+		return
+	}
+	if err := unix.PtraceSyscall(t.tid, sig); err != nil {
+		// the thread is killed, its exit is reported later
+		log.Debugf(si.ctx, "kernel-resume-restart the thread %d err, %v", t.tid, err)
+	}
+}
+
+func (si *syscallInjector) detach(t *tracee, sig int) {
+	if _, _, errno := unix.Syscall6(unix.SYS_PTRACE, unix.PTRACE_DETACH, uintptr(t.tid), 0, uintptr(sig), 0, 0); errno != 0 {
+		log.Debugf(si.ctx, "kernel-detach-detach the thread %d err, %v", t.tid, errno)
+	}
+	delete(si.tracees, t.tid)
+}
+
+// startDetach detaches from the delayed tracees and interrupts the running tracees, which are detached at their next stop
+func (si *syscallInjector) startDetach() {
+	si.stopping = true
+	for _, t := range si.tracees {
+		if !t.resumeAt.IsZero() {
+			t.resumeAt = time.Time{}
+			si.detach(t, 0)
+			continue
+		}
+		if err := unix.PtraceInterrupt(t.tid); err != nil {
+			log.Debugf(si.ctx, "kernel-startDetach-interrupt the thread %d err, %v", t.tid, err)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+//go:build linux && !amd64 && !arm64
+
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+// syscallFaultSupported returns the error because the syscall table and the registers are only
+// provided for amd64 and arm64
+func syscallFaultSupported() error {
+	return fmt.Errorf("the syscall fault is not supported on the %s architecture", runtime.GOARCH)
+}
+
+// lookupSyscall returns false because the syscall table of the architecture is not provided
+func lookupSyscall(name string) (int, bool) {
+	return 0, false
+}
+
+func injectSyscallFault(ctx context.Context, pids []int, fault *syscallFault, stop <-chan struct{}) error {
+	return syscallFaultSupported()
+}
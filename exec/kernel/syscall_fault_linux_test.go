@@ -0,0 +1,105 @@
+//go:build linux && (amd64 || arm64)
+
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kernel
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestInjectSyscallFault(t *testing.T) {
+	command := exec.Command("sh", "-c", `while :; do if cat /proc/self/stat >/dev/null 2>&1; then echo ok; else echo err; fi; sleep 0.02; done`)
+	stdout, _ := command.StdoutPipe()
+	if err := command.Start(); err != nil {
+		t.Skipf("start sh err, %v", err)
+	}
+	defer command.Process.Kill()
+	var mu sync.Mutex
+	lines := make([]string, 0)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			mu.Lock()
+			lines = append(lines, scanner.Text())
+			mu.Unlock()
+		}
+	}()
+	// counts returns the ok and err lines after the offset
+	counts := func(offset int) (int, int, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		joined := strings.Join(lines[offset:], "\n")
+		return strings.Count(joined, "ok"), strings.Count(joined, "err"), len(lines)
+	}
+
+	// the shell is attached after it runs, otherwise the loader fails to open the libraries
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		if _, _, n := counts(0); n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Skipf("sh does not run")
+		}
+	}
+
+	nr, _ := lookupSyscall("openat")
+	fault := &syscallFault{syscall: "openat", syscalls: map[int]string{nr: "openat"}, errno: syscall.ENOENT}
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- injectSyscallFault(context.Background(), []int{command.Process.Pid}, fault, stop)
+	}()
+	time.Sleep(500 * time.Millisecond)
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatalf("injectSyscallFault() returns before stopped")
+		}
+		t.Skipf("ptrace is not permitted, %v", err)
+	default:
+	}
+	_, _, offset := counts(0)
+	time.Sleep(300 * time.Millisecond)
+	if ok, errs, _ := counts(offset); ok != 0 || errs == 0 {
+		t.Errorf("injected: ok %d, err %d, want only err", ok, errs)
+	}
+
+	close(stop)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("injectSyscallFault() err, %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("injectSyscallFault() does not detach in time")
+	}
+	// the lines printed before detached may be still in the pipe
+	time.Sleep(100 * time.Millisecond)
+	_, _, offset = counts(0)
+	time.Sleep(300 * time.Millisecond)
+	if ok, errs, _ := counts(offset); ok == 0 || errs != 0 {
+		t.Errorf("detached: ok %d, err %d, want only ok", ok, errs)
+	}
+}
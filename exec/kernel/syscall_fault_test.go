@@ -0,0 +1,98 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kernel
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestInjectWhen(t *testing.T) {
+	tests := []struct {
+		first, end, step string
+		expect           []int
+	}{
+		{"", "", "", []int{1, 2, 3, 4, 5, 6, 7, 8}},
+		{"3", "", "", []int{3}},
+		{"2", "5", "", []int{2, 3, 4, 5}},
+		{"2", "", "3", []int{2, 5, 8}},
+		{"2", "6", "2", []int{2, 4, 6}},
+		{"", "3", "", []int{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		when, err := parseInjectWhen(tt.first, tt.end, tt.step)
+		if err != nil {
+			t.Fatalf("parseInjectWhen(%q, %q, %q) err, %v", tt.first, tt.end, tt.step, err)
+		}
+		matched := make([]int, 0)
+		for count := 1; count <= 8; count++ {
+			if when.match(count) {
+				matched = append(matched, count)
+			}
+		}
+		if len(matched) != len(tt.expect) {
+			t.Errorf("when %+v matches %v, want %v", when, matched, tt.expect)
+			continue
+		}
+		for i := range matched {
+			if matched[i] != tt.expect[i] {
+				t.Errorf("when %+v matches %v, want %v", when, matched, tt.expect)
+				break
+			}
+		}
+	}
+	for _, illegal := range [][3]string{{"0", "", ""}, {"a", "", ""}, {"5", "3", ""}, {"1", "", "-1"}} {
+		if _, err := parseInjectWhen(illegal[0], illegal[1], illegal[2]); err == nil {
+			t.Errorf("parseInjectWhen(%v) expects err", illegal)
+		}
+	}
+}
+
+func TestParseSyscallDelay(t *testing.T) {
+	for value, expect := range map[string]time.Duration{"10s": 10 * time.Second, "5ms": 5 * time.Millisecond, "100": 100 * time.Microsecond, "20us": 20 * time.Microsecond} {
+		if delay, err := parseSyscallDelay(value); err != nil || delay != expect {
+			t.Errorf("parseSyscallDelay(%s) = %v, %v, want %v", value, delay, err, expect)
+		}
+	}
+	for _, illegal := range []string{"", "0", "-1s", "1m1x"} {
+		if _, err := parseSyscallDelay(illegal); err == nil {
+			t.Errorf("parseSyscallDelay(%s) expects err", illegal)
+		}
+	}
+}
+
+func TestParseErrno(t *testing.T) {
+	for value, expect := range map[string]syscall.Errno{"ENOSPC": syscall.ENOSPC, "eio": syscall.EIO, "ECONNREFUSED": syscall.ECONNREFUSED, "28": syscall.Errno(28)} {
+		if errno, err := parseErrno(value); err != nil || errno != expect {
+			t.Errorf("parseErrno(%s) = %d, %v, want %d", value, errno, err, expect)
+		}
+	}
+	for _, illegal := range []string{"", "ENOSPACE", "0", "-5", "4096"} {
+		if _, err := parseErrno(illegal); err == nil {
+			t.Errorf("parseErrno(%s) expects err", illegal)
+		}
+	}
+	if retval, err := parseRetval("42"); err != nil || retval != 42 {
+		t.Errorf("parseRetval(42) = %d, %v, want 42", retval, err)
+	}
+	for _, illegal := range []string{"", "-1", "ENOSPC"} {
+		if _, err := parseRetval(illegal); err == nil {
+			t.Errorf("parseRetval(%s) expects err", illegal)
+		}
+	}
+}
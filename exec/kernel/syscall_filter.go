@@ -0,0 +1,267 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kernel
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// targetPath is the path argument, it is relative to the dirfd argument if the syscall has one
+	targetPath = iota + 1
+	// targetFd is the fd argument, the path of the file opened by the fd is matched
+	targetFd
+	// targetSockaddr is the socket address argument followed by its length
+	targetSockaddr
+)
+
+// atFdcwd is the AT_FDCWD dirfd, the relative path is resolved from the current working directory
+const atFdcwd = -100
+
+// syscallTarget is the argument of the syscall referring to the file or the socket address
+type syscallTarget struct {
+	kind int
+	// arg is the index of the path, fd or socket address argument
+	arg int
+	// dirfd is the index of the dirfd argument, it is -1 if the syscall has no dirfd argument
+	dirfd int
+}
+
+// syscallTargets are the syscalls which can be filtered by the path or the address
+var syscallTargets = map[string]syscallTarget{
+	"open":            {kind: targetPath, arg: 0, dirfd: -1},
+	"creat":           {kind: targetPath, arg: 0, dirfd: -1},
+	"openat":          {kind: targetPath, arg: 1, dirfd: 0},
+	"openat2":         {kind: targetPath, arg: 1, dirfd: 0},
+	"stat":            {kind: targetPath, arg: 0, dirfd: -1},
+	"lstat":           {kind: targetPath, arg: 0, dirfd: -1},
+	"newfstatat":      {kind: targetPath, arg: 1, dirfd: 0},
+	"statx":           {kind: targetPath, arg: 1, dirfd: 0},
+	"access":          {kind: targetPath, arg: 0, dirfd: -1},
+	"faccessat":       {kind: targetPath, arg: 1, dirfd: 0},
+	"faccessat2":      {kind: targetPath, arg: 1, dirfd: 0},
+	"truncate":        {kind: targetPath, arg: 0, dirfd: -1},
+	"mkdir":           {kind: targetPath, arg: 0, dirfd: -1},
+	"mkdirat":         {kind: targetPath, arg: 1, dirfd: 0},
+	"rmdir":           {kind: targetPath, arg: 0, dirfd: -1},
+	"unlink":          {kind: targetPath, arg: 0, dirfd: -1},
+	"unlinkat":        {kind: targetPath, arg: 1, dirfd: 0},
+	"rename":          {kind: targetPath, arg: 0, dirfd: -1},
+	"renameat":        {kind: targetPath, arg: 1, dirfd: 0},
+	"renameat2":       {kind: targetPath, arg: 1, dirfd: 0},
+	"chmod":           {kind: targetPath, arg: 0, dirfd: -1},
+	"fchmodat":        {kind: targetPath, arg: 1, dirfd: 0},
+	"chown":           {kind: targetPath, arg: 0, dirfd: -1},
+	"fchownat":        {kind: targetPath, arg: 1, dirfd: 0},
+	"readlink":        {kind: targetPath, arg: 0, dirfd: -1},
+	"readlinkat":      {kind: targetPath, arg: 1, dirfd: 0},
+	"execve":          {kind: targetPath, arg: 0, dirfd: -1},
+	"read":            {kind: targetFd, arg: 0},
+	"write":           {kind: targetFd, arg: 0},
+	"pread64":         {kind: targetFd, arg: 0},
+	"pwrite64":        {kind: targetFd, arg: 0},
+	"readv":           {kind: targetFd, arg: 0},
+	"writev":          {kind: targetFd, arg: 0},
+	"preadv":          {kind: targetFd, arg: 0},
+	"pwritev":         {kind: targetFd, arg: 0},
+	"preadv2":         {kind: targetFd, arg: 0},
+	"pwritev2":        {kind: targetFd, arg: 0},
+	"fsync":           {kind: targetFd, arg: 0},
+	"fdatasync":       {kind: targetFd, arg: 0},
+	"sync_file_range": {kind: targetFd, arg: 0},
+	"ftruncate":       {kind: targetFd, arg: 0},
+	"fallocate":       {kind: targetFd, arg: 0},
+	"fstat":           {kind: targetFd, arg: 0},
+	"lseek":           {kind: targetFd, arg: 0},
+	"getdents64":      {kind: targetFd, arg: 0},
+	"flock":           {kind: targetFd, arg: 0},
+	"close":           {kind: targetFd, arg: 0},
+	"connect":         {kind: targetSockaddr, arg: 1},
+	"bind":            {kind: targetSockaddr, arg: 1},
+	"sendto":          {kind: targetSockaddr, arg: 4},
+}
+
+// syscallFilter selects the invocations of the syscall to inject by the target of the syscall and the probability
+type syscallFilter struct {
+	// paths are the path prefixes matched with the path argument or the file of the fd argument
+	paths []string
+	// addresses are matched with the socket address argument
+	addresses []addressMatcher
+	// percent is the probability of injecting the matched invocation, all are injected if it is 0 or 100
+	percent int
+}
+
+// addressMatcher matches the ip and port, or the path of the unix socket
+type addressMatcher struct {
+	network *net.IPNet
+	port    int
+	path    string
+}
+
+// parseSyscallFilter parses the path, address and percent flags, the path and address are separated by commas,
+// the address is the ip, cidr, ip:port, :port or the path of the unix socket
+func parseSyscallFilter(path, address, percent string) (syscallFilter, error) {
+	var filter syscallFilter
+	for _, value := range strings.Split(path, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !filepath.IsAbs(value) {
+			return filter, fmt.Errorf("the path %s must be absolute", value)
+		}
+		filter.paths = append(filter.paths, filepath.Clean(value))
+	}
+	for _, value := range strings.Split(address, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		matcher, err := parseAddressMatcher(value)
+		if err != nil {
+			return filter, err
+		}
+		filter.addresses = append(filter.addresses, matcher)
+	}
+	if percent != "" {
+		value, err := strconv.Atoi(percent)
+		if err != nil || value <= 0 || value > 100 {
+			return filter, fmt.Errorf("the percent must be in 1..100")
+		}
+		filter.percent = value
+	}
+	return filter, nil
+}
+
+func parseAddressMatcher(value string) (addressMatcher, error) {
+	if strings.HasPrefix(value, "/") || strings.HasPrefix(value, "@") {
+		return addressMatcher{path: value}, nil
+	}
+	host, port := value, 0
+	if h, p, err := net.SplitHostPort(value); err == nil {
+		number, err := strconv.Atoi(p)
+		if err != nil || number <= 0 || number > 65535 {
+			return addressMatcher{}, fmt.Errorf("the port of the address %s is illegal", value)
+		}
+		host, port = h, number
+	}
+	if host == "" {
+		return addressMatcher{port: port}, nil
+	}
+	if !strings.Contains(host, "/") {
+		if ip := net.ParseIP(host); ip != nil && ip.To4() != nil {
+			host = host + "/32"
+		} else {
+			host = host + "/128"
+		}
+	}
+	_, network, err := net.ParseCIDR(host)
+	if err != nil {
+		return addressMatcher{}, fmt.Errorf("the address %s is illegal", value)
+	}
+	return addressMatcher{network: network, port: port}, nil
+}
+
+// check returns an error if the filter can not be applied to the syscall, the syscalls with the path or fd
+// argument are filtered by the paths, and the syscalls with the socket address argument are filtered by the addresses
+func (f syscallFilter) check(name string) error {
+	if len(f.paths) == 0 && len(f.addresses) == 0 {
+		return nil
+	}
+	target, ok := syscallTargets[name]
+	if !ok {
+		return fmt.Errorf("the syscall %s has no path, fd or socket address argument", name)
+	}
+	if target.kind == targetSockaddr && len(f.addresses) == 0 {
+		return fmt.Errorf("the syscall %s can only be filtered by the address", name)
+	}
+	if target.kind != targetSockaddr && len(f.paths) == 0 {
+		return fmt.Errorf("the syscall %s can only be filtered by the path", name)
+	}
+	return nil
+}
+
+// matchPath returns true if the path is one of the path prefixes or under them
+func (f syscallFilter) matchPath(path string) bool {
+	for _, prefix := range f.paths {
+		if path == prefix || prefix == "/" || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAddress returns true if the socket address matches one of the addresses
+func (f syscallFilter) matchAddress(ip net.IP, port int, path string) bool {
+	for _, matcher := range f.addresses {
+		if matcher.path != "" {
+			if path == matcher.path {
+				return true
+			}
+			continue
+		}
+		if ip == nil || matcher.port != 0 && matcher.port != port {
+			continue
+		}
+		if matcher.network == nil || matcher.network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// roll returns true if the matched invocation is injected by the probability
+func (f syscallFilter) roll() bool {
+	return f.percent == 0 || f.percent == 100 || rand.Intn(100) < f.percent
+}
+
+// parseSockaddr returns the ip and port of the inet socket address, or the path of the unix socket address,
+// the abstract unix socket path starts with @. The family is in the native byte order, which is little endian
+// on the supported architectures, and the port is in the network byte order.
+func parseSockaddr(data []byte) (net.IP, int, string) {
+	if len(data) < 2 {
+		return nil, 0, ""
+	}
+	switch binary.LittleEndian.Uint16(data) {
+	case 1: // AF_UNIX
+		path := data[2:]
+		if len(path) > 0 && path[0] == 0 {
+			return nil, 0, "@" + strings.TrimRight(string(path[1:]), "\x00")
+		}
+		if end := strings.IndexByte(string(path), 0); end >= 0 {
+			path = path[:end]
+		}
+		return nil, 0, string(path)
+	case 2: // AF_INET
+		if len(data) < 8 {
+			return nil, 0, ""
+		}
+		return net.IP(append([]byte{}, data[4:8]...)), int(binary.BigEndian.Uint16(data[2:])), ""
+	case 10: // AF_INET6
+		if len(data) < 24 {
+			return nil, 0, ""
+		}
+		return net.IP(append([]byte{}, data[8:24]...)), int(binary.BigEndian.Uint16(data[2:])), ""
+	}
+	return nil, 0, ""
+}
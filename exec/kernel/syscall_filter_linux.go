@@ -0,0 +1,127 @@
+//go:build linux && (amd64 || arm64)
+
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kernel
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// maxPathLen is the PATH_MAX of linux
+	maxPathLen = 4096
+	// maxSockaddrLen is the size of sockaddr_storage
+	maxSockaddrLen = 128
+)
+
+// matchTarget returns true if the target argument of the syscall matches the filter at the syscall-enter-stop,
+// the syscall is not matched if its argument can not be read
+func matchTarget(tid int, regs *syscall.PtraceRegs, name string, filter syscallFilter) bool {
+	if len(filter.paths) == 0 && len(filter.addresses) == 0 {
+		return true
+	}
+	target := syscallTargets[name]
+	switch target.kind {
+	case targetPath:
+		path, err := readTraceeString(tid, uintptr(syscallArg(regs, target.arg)))
+		if err != nil || path == "" {
+			return false
+		}
+		if !filepath.IsAbs(path) {
+			dirfd := atFdcwd
+			if target.dirfd >= 0 {
+				dirfd = int(int32(syscallArg(regs, target.dirfd)))
+			}
+			dir, err := traceeDir(tid, dirfd)
+			if err != nil {
+				return false
+			}
+			path = filepath.Join(dir, path)
+		}
+		return filter.matchPath(filepath.Clean(path))
+	case targetFd:
+		path, err := os.Readlink(fmt.Sprintf("/proc/%d/fd/%d", tid, int32(syscallArg(regs, target.arg))))
+		return err == nil && filepath.IsAbs(path) && filter.matchPath(path)
+	case targetSockaddr:
+		size := int(syscallArg(regs, target.arg+1))
+		if size <= 0 || size > maxSockaddrLen {
+			return false
+		}
+		data := make([]byte, size)
+		if err := readTracee(tid, uintptr(syscallArg(regs, target.arg)), data); err != nil {
+			return false
+		}
+		return filter.matchAddress(parseSockaddr(data))
+	}
+	return false
+}
+
+// traceeDir returns the directory of the dirfd, it is the current working directory of the tracee for AT_FDCWD
+func traceeDir(tid, dirfd int) (string, error) {
+	if dirfd == atFdcwd {
+		return os.Readlink(fmt.Sprintf("/proc/%d/cwd", tid))
+	}
+	return os.Readlink(fmt.Sprintf("/proc/%d/fd/%d", tid, dirfd))
+}
+
+// readTraceeString reads the nul terminated string from the memory of the tracee, the string is read
+// page by page because the memory after the string may not be mapped
+func readTraceeString(tid int, addr uintptr) (string, error) {
+	if addr == 0 {
+		return "", fmt.Errorf("the address is null")
+	}
+	pageSize := uintptr(os.Getpagesize())
+	value := make([]byte, 0, 256)
+	for len(value) < maxPathLen {
+		size := int(pageSize - addr%pageSize)
+		if size > maxPathLen-len(value) {
+			size = maxPathLen - len(value)
+		}
+		chunk := make([]byte, size)
+		if err := readTracee(tid, addr, chunk); err != nil {
+			return "", err
+		}
+		if end := bytes.IndexByte(chunk, 0); end >= 0 {
+			return string(append(value, chunk[:end]...)), nil
+		}
+		value = append(value, chunk...)
+		addr += uintptr(size)
+	}
+	return "", fmt.Errorf("the string is too long")
+}
+
+// readTracee reads the memory of the tracee by process_vm_readv, which is allowed for the tracer
+func readTracee(tid int, addr uintptr, data []byte) error {
+	local := []unix.Iovec{{Base: &data[0]}}
+	local[0].SetLen(len(data))
+	remote := []unix.RemoteIovec{{Base: addr, Len: len(data)}}
+	n, err := unix.ProcessVMReadv(tid, local, remote, 0)
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return fmt.Errorf("read %d of %d bytes", n, len(data))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kernel
+
+import (
+	"net"
+	"testing"
+)
+
+func TestSyscallFilterMatch(t *testing.T) {
+	filter, err := parseSyscallFilter("/data/wal/, /tmp", "10.0.0.0/24:3306,[::1]:80,:6379,/run/app.sock", "5")
+	if err != nil {
+		t.Fatalf("parseSyscallFilter() err, %v", err)
+	}
+	if filter.percent != 5 {
+		t.Errorf("percent = %d, want 5", filter.percent)
+	}
+	for path, expect := range map[string]bool{"/data/wal": true, "/data/wal/000001.log": true, "/data/wal2": false, "/tmp/a": true, "/etc/hosts": false} {
+		if filter.matchPath(path) != expect {
+			t.Errorf("matchPath(%s) = %v, want %v", path, !expect, expect)
+		}
+	}
+	addresses := []struct {
+		ip     string
+		port   int
+		path   string
+		expect bool
+	}{
+		{"10.0.0.8", 3306, "", true},
+		{"10.0.0.8", 3307, "", false},
+		{"10.0.1.8", 3306, "", false},
+		{"::1", 80, "", true},
+		{"192.168.0.1", 6379, "", true},
+		{"", 0, "/run/app.sock", true},
+		{"", 0, "/run/other.sock", false},
+	}
+	for _, address := range addresses {
+		if filter.matchAddress(net.ParseIP(address.ip), address.port, address.path) != address.expect {
+			t.Errorf("matchAddress(%s, %d, %s) = %v, want %v", address.ip, address.port, address.path, !address.expect, address.expect)
+		}
+	}
+
+	if err := filter.check("openat"); err != nil {
+		t.Errorf("check(openat) err, %v", err)
+	}
+	if err := filter.check("connect"); err != nil {
+		t.Errorf("check(connect) err, %v", err)
+	}
+	if err := filter.check("mmap"); err == nil {
+		t.Errorf("check(mmap) expects err")
+	}
+	pathOnly, _ := parseSyscallFilter("/data", "", "")
+	if err := pathOnly.check("connect"); err == nil {
+		t.Errorf("check(connect) expects err without the address")
+	}
+	for _, illegal := range [][3]string{{"data", "", ""}, {"", "10.0.0.1:0", ""}, {"", "host", ""}, {"", "", "0"}, {"", "", "101"}} {
+		if _, err := parseSyscallFilter(illegal[0], illegal[1], illegal[2]); err == nil {
+			t.Errorf("parseSyscallFilter(%v) expects err", illegal)
+		}
+	}
+}
+
+func TestParseSockaddr(t *testing.T) {
+	inet := []byte{2, 0, 0x0c, 0xea, 10, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0}
+	if ip, port, _ := parseSockaddr(inet); !ip.Equal(net.ParseIP("10.0.0.1")) || port != 3306 {
+		t.Errorf("parseSockaddr(inet) = %s:%d, want 10.0.0.1:3306", ip, port)
+	}
+	inet6 := append([]byte{10, 0, 0, 80, 0, 0, 0, 0}, net.ParseIP("::1")...)
+	if ip, port, _ := parseSockaddr(append(inet6, 0, 0, 0, 0)); !ip.Equal(net.ParseIP("::1")) || port != 80 {
+		t.Errorf("parseSockaddr(inet6) = %s:%d, want [::1]:80", ip, port)
+	}
+	if _, _, path := parseSockaddr(append([]byte{1, 0}, "/run/app.sock\x00"...)); path != "/run/app.sock" {
+		t.Errorf("parseSockaddr(unix) = %s, want /run/app.sock", path)
+	}
+	if _, _, path := parseSockaddr(append([]byte{1, 0, 0}, "app"...)); path != "@app" {
+		t.Errorf("parseSockaddr(abstract unix) = %s, want @app", path)
+	}
+}
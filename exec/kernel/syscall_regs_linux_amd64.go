@@ -0,0 +1,43 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kernel
+
+import (
+	"syscall"
+)
+
+// syscallNr returns the number of the syscall at the syscall-enter-stop
+func syscallNr(regs *syscall.PtraceRegs) int {
+	return int(int64(regs.Orig_rax))
+}
+
+// syscallArg returns the i-th argument of the syscall at the syscall-enter-stop
+func syscallArg(regs *syscall.PtraceRegs, i int) uint64 {
+	return [...]uint64{regs.Rdi, regs.Rsi, regs.Rdx, regs.R10, regs.R8, regs.R9}[i]
+}
+
+// skipSyscall replaces the syscall with the invalid syscall -1, so it is not executed by the kernel
+func skipSyscall(tid int, regs *syscall.PtraceRegs) error {
+	regs.Orig_rax = ^uint64(0)
+	return syscall.PtraceSetRegs(tid, regs)
+}
+
+// setSyscallReturn sets the return value at the syscall-exit-stop
+func setSyscallReturn(tid int, regs *syscall.PtraceRegs, value int64) error {
+	regs.Rax = uint64(value)
+	return syscall.PtraceSetRegs(tid, regs)
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kernel
+
+import (
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ntArmSystemCall is the register set of the syscall number, the x8 register is not used by the kernel
+// after the syscall-enter-stop
+const ntArmSystemCall = 0x404
+
+// syscallNr returns the number of the syscall at the syscall-enter-stop
+func syscallNr(regs *syscall.PtraceRegs) int {
+	return int(int64(regs.Regs[8]))
+}
+
+// syscallArg returns the i-th argument of the syscall at the syscall-enter-stop
+func syscallArg(regs *syscall.PtraceRegs, i int) uint64 {
+	return regs.Regs[i]
+}
+
+// skipSyscall replaces the syscall with the invalid syscall -1, so it is not executed by the kernel
+func skipSyscall(tid int, regs *syscall.PtraceRegs) error {
+	nr := int32(-1)
+	iov := unix.Iovec{Base: (*byte)(unsafe.Pointer(&nr))}
+	iov.SetLen(int(unsafe.Sizeof(nr)))
+	if _, _, errno := unix.Syscall6(unix.SYS_PTRACE, unix.PTRACE_SETREGSET, uintptr(tid), ntArmSystemCall,
+		uintptr(unsafe.Pointer(&iov)), 0, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// setSyscallReturn sets the return value at the syscall-exit-stop
+func setSyscallReturn(tid int, regs *syscall.PtraceRegs, value int64) error {
+	regs.Regs[0] = uint64(value)
+	return syscall.PtraceSetRegs(tid, regs)
+}
@@ -44,7 +44,10 @@ func NewMemCommandModelSpec() spec.ExpModelCommandSpec {
 			ExpActions: []spec.ExpActionCommandSpec{
 				&MemLoadActionCommand{
 					spec.BaseExpActionCommandSpec{
-						ActionMatchers: []spec.ExpFlagSpec{},
+						ActionMatchers: []spec.ExpFlagSpec{
+							exec.CgroupPathFlag,
+							exec.ContainerIdFlag,
+						},
 						ActionFlags:    []spec.ExpFlagSpec{},
 						ActionExecutor: &memExecutor{},
 						ActionExample: `
@@ -239,6 +242,10 @@ func (ce *memExecutor) Exec(uid string, ctx context.Context, model *spec.ExpMode
 		}
 	}
 	ctx = context.WithValue(ctx, "cgroup-root", model.ActionFlags["cgroup-root"])
+	ctx, response := exec.JoinTargetCgroup(ctx, model.ActionFlags)
+	if response != nil {
+		return response
+	}
 	ce.start(ctx, memPercent, memReserve, memRate, burnMemModeStr, includeBufferCache, avoidBeingKilled, ce.channel, bigMem)
 	return spec.Success()
 }
@@ -297,7 +304,7 @@ func burnMemWithCache(ctx context.Context, memPercent, memReserve, memRate int,
 			}
 			fileCount++
 		} else {
-			log.Infof(ctx, "mem-big-mem-burnMemWithCache-info", "expectMem", expectMem, "total", total)
+			log.Infof(ctx, "mem-big-mem-burnMemWithCache-info, expectMem: %d, total: %d", expectMem, total)
 		}
 	}
 }
@@ -339,7 +346,7 @@ func (ce *memExecutor) start(ctx context.Context, memPercent, memReserve, memRat
 			if result > 10 {
 				result -= 10
 			}
-			log.Infof(ctx, "mem-start-big-mem-expectMem-info", "expectMem", expectMem, "result", result, !(int(result) > memPercent))
+			log.Infof(ctx, "mem-start-big-mem-expectMem-info, expectMem: %d, result: %f, fill: %t", expectMem, result, !(int(result) > memPercent))
 			if expectMem > 0 && (!(int(result) > memPercent)) {
 				if expectMem > int64(memRate) {
 					fillMem = int64(memRate)
@@ -360,7 +367,7 @@ func (ce *memExecutor) start(ctx context.Context, memPercent, memReserve, memRat
 				log.Debugf(ctx, "mem-start-count: %d, len(buf): %d, cap(buf): %d, expect mem: %d, fill size: %d", count, len(buf), cap(buf), expectMem, fillSize)
 				caches[count] = append(buf, make([]Blocks, fillSize)...)
 			} else {
-				log.Infof(ctx, "mem-start-big-mem-expectMem-info", "expectMem", expectMem)
+				log.Infof(ctx, "mem-start-big-mem-expectMem-info, expectMem: %d", expectMem)
 			}
 		}
 	} else {
@@ -392,7 +399,7 @@ func (ce *memExecutor) start(ctx context.Context, memPercent, memReserve, memRat
 				log.Debugf(ctx, "mem-start-count: %d, len(buf): %d, cap(buf): %d, expect mem: %d, fill size: %d", count, len(buf), cap(buf), expectMem, fillSize)
 				cache[count] = append(buf, make([]Block, fillSize)...)
 			} else {
-				log.Infof(ctx, "mem-start-normal-expectMem-info", "expectMem", expectMem)
+				log.Infof(ctx, "mem-start-normal-expectMem-info, expectMem: %d", expectMem)
 			}
 		}
 	}
@@ -46,21 +46,31 @@ func getAvailableAndTotal(ctx context.Context, burnMemMode string, includeBuffer
 
 		log.Debugf(ctx, "mem-linux-get mem useage by cgroup, root path: %s", cgroupRoot)
 
-		cgroup, err := cgroups.Load(exec.Hierarchy(cgroupRoot.(string)), exec.PidPath(p))
-		if err != nil {
-			return 0, 0, fmt.Errorf("load cgroup error, %v", err)
-		}
-		stats, err := cgroup.Stat(cgroups.IgnoreNotExist)
-		if err != nil {
-			return 0, 0, fmt.Errorf("load cgroup stat error, %v", err)
-		}
-		if stats != nil && stats.Memory.Usage.Limit < PageCounterMax {
-			total = int64(stats.Memory.Usage.Limit)
-			available = total - int64(stats.Memory.Usage.Usage)
-			if burnMemMode == "ram" && !includeBufferCache {
-				available = available + int64(stats.Memory.Cache)
+		if exec.IsCgroup2(cgroupRoot.(string)) {
+			total, available, err := getAvailableAndTotalByCgroup2(cgroupRoot.(string), p, burnMemMode, includeBufferCache)
+			if err != nil {
+				return 0, 0, err
+			}
+			if total > 0 {
+				return total, available, nil
+			}
+		} else {
+			cgroup, err := cgroups.Load(exec.Hierarchy(cgroupRoot.(string)), exec.PidPath(p))
+			if err != nil {
+				return 0, 0, fmt.Errorf("load cgroup error, %v", err)
+			}
+			stats, err := cgroup.Stat(cgroups.IgnoreNotExist)
+			if err != nil {
+				return 0, 0, fmt.Errorf("load cgroup stat error, %v", err)
+			}
+			if stats != nil && stats.Memory.Usage.Limit < PageCounterMax {
+				total = int64(stats.Memory.Usage.Limit)
+				available = total - int64(stats.Memory.Usage.Usage)
+				if burnMemMode == "ram" && !includeBufferCache {
+					available = available + int64(stats.Memory.Cache)
+				}
+				return total, available, nil
 			}
-			return total, available, nil
 		}
 	}
 
@@ -75,3 +85,33 @@ func getAvailableAndTotal(ctx context.Context, burnMemMode string, includeBuffer
 	}
 	return total, available, nil
 }
+
+// getAvailableAndTotalByCgroup2 returns the memory limit and available of the cgroup v2 group by
+// memory.max, memory.current and memory.stat, the total is 0 if the group has no memory limit
+func getAvailableAndTotalByCgroup2(cgroupRoot string, pid int, burnMemMode string, includeBufferCache bool) (int64, int64, error) {
+	cgroup, err := exec.LoadCgroup2(cgroupRoot, pid)
+	if err != nil {
+		return 0, 0, fmt.Errorf("load cgroup error, %v", err)
+	}
+	limit, err := cgroup.MemoryMax()
+	if err != nil {
+		return 0, 0, fmt.Errorf("load cgroup stat error, %v", err)
+	}
+	if limit >= PageCounterMax {
+		return 0, 0, nil
+	}
+	usage, err := cgroup.MemoryCurrent()
+	if err != nil {
+		return 0, 0, fmt.Errorf("load cgroup stat error, %v", err)
+	}
+	total := int64(limit)
+	available := total - int64(usage)
+	if burnMemMode == "ram" && !includeBufferCache {
+		stat, err := cgroup.MemoryStat()
+		if err != nil {
+			return 0, 0, fmt.Errorf("load cgroup stat error, %v", err)
+		}
+		available = available + int64(stat["file"])
+	}
+	return total, available, nil
+}
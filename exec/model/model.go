@@ -77,6 +77,18 @@ var DebugFlag = spec.ExpFlag{
 	Default: "",
 }
 
+var TimeoutFlag = spec.ExpFlag{
+	Name:    "timeout",
+	Desc:    "the experiment is destroyed automatically after the timeout, in seconds or a duration such as 10m",
+	Default: "",
+}
+
+var DryRunFlag = spec.ExpFlag{
+	Name:    "dry-run",
+	Desc:    "return the commands and file writes of the experiment without executing them",
+	Default: "false",
+}
+
 var ChannelFlag = spec.ExpFlag{
 	Name:    "channel",
 	Desc:    "channel",
@@ -23,6 +23,7 @@ import (
 
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/network/tc"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
 	"github.com/chaosblade-io/chaosblade-spec-go/log"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 )
@@ -39,22 +40,51 @@ func NewDnsActionSpec() spec.ExpActionCommandSpec {
 			ActionMatchers: []spec.ExpFlagSpec{},
 			ActionFlags: []spec.ExpFlagSpec{
 				&spec.ExpFlag{
-					Name:                  "domain",
-					Desc:                  "Domain name",
-					Required:              true,
-					RequiredWhenDestroyed: true,
+					Name: "domain",
+					Desc: "Domain name, multiple domains separated by ','. The wildcard pattern such as *.example.com is supported in the stub mode",
 				},
 				&spec.ExpFlag{
-					Name:                  "ip",
-					Desc:                  "Domain ip",
-					Required:              true,
-					RequiredWhenDestroyed: true,
+					Name: "ip",
+					Desc: "Domain ip, or the ips of the wrong answers separated by ',' in the stub mode",
+				},
+				&spec.ExpFlag{
+					Name: "mode",
+					Desc: "The mode of dns experiment, hosts or stub, default value is hosts. The hosts mode appends the domain to /etc/hosts, the stub mode redirects the dns queries of the host to a stub dns server",
+				},
+				&spec.ExpFlag{
+					Name: "behavior",
+					Desc: "The behavior of the stub dns server for the domains, nxdomain, servfail, timeout, delay, wrong or truncate",
+				},
+				&spec.ExpFlag{
+					Name: "delay",
+					Desc: "The delay of the answers in milliseconds for the delay behavior, default value is 1000",
+				},
+				&spec.ExpFlag{
+					Name: "rules",
+					Desc: "The behaviors of different domains separated by ';', each rule is domain=behavior[:argument], the argument is milliseconds for delay and ips separated by '|' for wrong, for example *.a.com=nxdomain;b.com=delay:500;c.com=wrong:10.0.0.1|fd00::1",
+				},
+				&spec.ExpFlag{
+					Name: "upstream",
+					Desc: "The upstream dns server of the queries not matched in the stub mode, ip or ip:port, default value is the first non-loopback nameserver in resolv.conf",
+				},
+				&spec.ExpFlag{
+					Name: "backend",
+					Desc: "The firewall redirecting the dns queries to the stub dns server, iptables or nftables, default value is detected by the host",
 				},
 			},
 			ActionExecutor: &NetworkDnsExecutor{},
 			ActionExample: `
 # The domain name www.baidu.com is not accessible
-blade create network dns --domain www.baidu.com --ip 10.0.0.0`,
+blade create network dns --domain www.baidu.com --ip 10.0.0.0
+
+# Resolve all subdomains of example.com to NXDOMAIN by the stub dns server, other domains are resolved by the upstream
+blade create network dns --mode stub --domain *.example.com --behavior nxdomain
+
+# Delay the answers of api.example.com for 2 seconds and time out the queries of db.example.com
+blade create network dns --mode stub --rules "api.example.com=delay:2000;db.example.com=timeout"
+
+# Answer the A and AAAA queries of www.example.com with wrong ips
+blade create network dns --mode stub --domain www.example.com --behavior wrong --ip 10.0.0.1,fd00::1`,
 			ActionPrograms:   []string{tc.TcNetworkBin},
 			ActionCategories: []string{category.SystemNetwork},
 		},
@@ -77,7 +107,8 @@ func (d *DnsActionSpec) LongDesc() string {
 	if d.ActionLongDesc != "" {
 		return d.ActionLongDesc
 	}
-	return "Dns experiment"
+	return "Dns experiment. The hosts mode pins the domain to the ip by /etc/hosts, the stub mode answers the queries " +
+		"of the matched domains with NXDOMAIN, SERVFAIL, timeout, delayed, wrong or truncated answers even if the resolver bypasses /etc/hosts"
 }
 
 type NetworkDnsExecutor struct {
@@ -91,6 +122,14 @@ func (*NetworkDnsExecutor) Name() string {
 var changeDnsBin = "chaos_changedns"
 
 func (ns *NetworkDnsExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	switch model.ActionFlags["mode"] {
+	case "", DnsModeHosts:
+	case DnsModeStub:
+		return ns.execStub(uid, ctx, model)
+	default:
+		log.Errorf(ctx, "`%s`: network-dns-mode is illegal", model.ActionFlags["mode"])
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "mode", model.ActionFlags["mode"], "it must be hosts or stub")
+	}
 	commands := []string{"grep", "cat", "rm", "echo"}
 	if response, ok := ns.channel.IsAllCommandsAvailable(ctx, commands); !ok {
 		return response
@@ -109,6 +148,44 @@ func (ns *NetworkDnsExecutor) Exec(uid string, ctx context.Context, model *spec.
 	return ns.start(ctx, domain, ip)
 }
 
+// execStub redirects the dns queries to the stub dns server answering the domains by the behaviors
+func (ns *NetworkDnsExecutor) execStub(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if model.ActionFlags["channel"] == spec.NSExecBin {
+		log.Errorf(ctx, "network-dns-stub mode is not supported by the nsexec channel")
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "channel", spec.NSExecBin, "the stub mode only runs on the host")
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return ns.stopDnsStub(ctx, uid, model.ActionFlags["backend"])
+	}
+	var rules []dnsRule
+	var err error
+	if model.ActionFlags["rules"] != "" {
+		rules, err = parseDnsRules(model.ActionFlags["rules"])
+		if err != nil {
+			log.Errorf(ctx, "`%s`: network-dns-rules is illegal, %v", model.ActionFlags["rules"], err)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "rules", model.ActionFlags["rules"], err)
+		}
+	}
+	if domain := model.ActionFlags["domain"]; domain != "" {
+		behavior := model.ActionFlags["behavior"]
+		if behavior == "" {
+			log.Errorf(ctx, "network-dns-behavior is nil")
+			return spec.ResponseFailWithFlags(spec.ParameterLess, "behavior")
+		}
+		domainRules, err := newDnsRules(domain, behavior, model.ActionFlags["delay"], model.ActionFlags["ip"])
+		if err != nil {
+			log.Errorf(ctx, "`%s`: network-dns-behavior is illegal, %v", behavior, err)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "behavior", behavior, err)
+		}
+		rules = append(rules, domainRules...)
+	}
+	if len(rules) == 0 {
+		log.Errorf(ctx, "network-dns-domain|rules is nil")
+		return spec.ResponseFailWithFlags(spec.ParameterLess, "domain|rules")
+	}
+	return ns.startDnsStub(ctx, uid, rules, model.ActionFlags["upstream"], model.ActionFlags["backend"])
+}
+
 const hosts = "/etc/hosts"
 const tmpHosts = "/tmp/chaos-hosts.tmp"
 
@@ -119,7 +196,11 @@ func (ns *NetworkDnsExecutor) start(ctx context.Context, domain, ip string) *spe
 	if response.Success {
 		return spec.ReturnFail(spec.OsCmdExecFailed, fmt.Sprintf("%s has been exist", dnsPair))
 	}
-	return ns.channel.Run(ctx, "echo", fmt.Sprintf(`"%s" >> %s`, dnsPair, hosts))
+	response = ns.channel.Run(ctx, "echo", fmt.Sprintf(`"%s" >> %s`, dnsPair, hosts))
+	if response.Success {
+		store.Record(ctx, store.Resource{Kind: store.ResourceHostsEntry, Value: dnsPair, Attrs: map[string]string{"file": hosts}})
+	}
+	return response
 }
 
 func (ns *NetworkDnsExecutor) stop(ctx context.Context, domain, ip string) *spec.Response {
@@ -0,0 +1,461 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+)
+
+// DnsStubMode is the mode of chaos_os running the stub dns server, the listeners are inherited from the parent
+// example => dns-stub 7c7b0f3a8d1e4c2b '{"upstream":"10.0.0.2:53","rules":[...],"listeners":2}'
+const DnsStubMode = "dns-stub"
+
+// the behaviors of the stub dns server for the matched domains
+const (
+	DnsBehaviorNxdomain = "nxdomain"
+	DnsBehaviorServfail = "servfail"
+	DnsBehaviorTimeout  = "timeout"
+	DnsBehaviorDelay    = "delay"
+	DnsBehaviorWrong    = "wrong"
+	DnsBehaviorTruncate = "truncate"
+)
+
+const (
+	dnsRcodeServfail = 2
+	dnsRcodeNxdomain = 3
+	dnsTypeA         = 1
+	dnsTypeAAAA      = 28
+	dnsClassIN       = 1
+	dnsHeaderLen     = 12
+	// dnsAnswerTTL is the ttl of the wrong answers, it is short so the caches recover soon after the experiment
+	dnsAnswerTTL = 30
+	// dnsDefaultDelay is the delay of the delayed answers in milliseconds if not specified
+	dnsDefaultDelay = 1000
+	// dnsUpstreamTimeout is the time waiting for the upstream answers
+	dnsUpstreamTimeout = 5 * time.Second
+	// dnsTcpIdleTimeout is the time keeping the idle tcp connection of the client
+	dnsTcpIdleTimeout = 10 * time.Second
+	// dnsStubMark marks the packets sent to the upstream by the stub, so they are not redirected to the stub again
+	dnsStubMark = 0xcb53
+)
+
+// dnsRule is the behavior of the domains matching the wildcard pattern, for example *.example.com
+type dnsRule struct {
+	Pattern  string   `json:"pattern"`
+	Behavior string   `json:"behavior"`
+	Delay    int      `json:"delay,omitempty"`
+	Ips      []string `json:"ips,omitempty"`
+}
+
+// dnsStubConfig is passed to the stub dns server process
+type dnsStubConfig struct {
+	Upstream  string    `json:"upstream"`
+	Rules     []dnsRule `json:"rules"`
+	Listeners int       `json:"listeners"`
+}
+
+// match returns the first rule matching the domain
+func matchDnsRule(rules []dnsRule, domain string) *dnsRule {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	for i := range rules {
+		if ok, _ := path.Match(rules[i].Pattern, domain); ok {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// parseDnsRules parses the rules separated by semicolons, each rule is domain=behavior[:argument],
+// the argument of delay is milliseconds and the argument of wrong is the ips separated by |, for example
+// *.example.com=nxdomain;api.example.com=delay:2000;db.example.com=wrong:10.0.0.1|fd00::1
+func parseDnsRules(rules string) ([]dnsRule, error) {
+	parsed := make([]dnsRule, 0)
+	for _, item := range strings.Split(rules, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		idx := strings.Index(item, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("%s is not domain=behavior", item)
+		}
+		behavior, argument := item[idx+1:], ""
+		if i := strings.Index(behavior, ":"); i >= 0 {
+			behavior, argument = behavior[:i], behavior[i+1:]
+		}
+		delay, ips := "", ""
+		switch behavior {
+		case DnsBehaviorDelay:
+			delay = argument
+		case DnsBehaviorWrong:
+			ips = strings.ReplaceAll(argument, "|", ",")
+		default:
+			if argument != "" {
+				return nil, fmt.Errorf("the %s behavior has no argument", behavior)
+			}
+		}
+		rule, err := newDnsRules(item[:idx], behavior, delay, ips)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, rule...)
+	}
+	if len(parsed) == 0 {
+		return nil, errors.New("no rule is specified")
+	}
+	return parsed, nil
+}
+
+// newDnsRules returns the rules of the comma separated domains with the same behavior
+func newDnsRules(domains, behavior, delay, ips string) ([]dnsRule, error) {
+	rule := dnsRule{Behavior: behavior}
+	switch behavior {
+	case DnsBehaviorNxdomain, DnsBehaviorServfail, DnsBehaviorTimeout, DnsBehaviorTruncate:
+	case DnsBehaviorDelay:
+		rule.Delay = dnsDefaultDelay
+		if delay != "" {
+			value, err := strconv.Atoi(delay)
+			if err != nil || value <= 0 {
+				return nil, fmt.Errorf("the delay %s must be a positive integer", delay)
+			}
+			rule.Delay = value
+		}
+	case DnsBehaviorWrong:
+		for _, ip := range strings.Split(ips, ",") {
+			ip = strings.TrimSpace(ip)
+			if ip == "" {
+				continue
+			}
+			if net.ParseIP(ip) == nil {
+				return nil, fmt.Errorf("the ip %s is illegal", ip)
+			}
+			rule.Ips = append(rule.Ips, ip)
+		}
+		if len(rule.Ips) == 0 {
+			return nil, errors.New("the wrong behavior needs the ips of the answers")
+		}
+	default:
+		return nil, fmt.Errorf("the behavior %s must be nxdomain, servfail, timeout, delay, wrong or truncate", behavior)
+	}
+	rules := make([]dnsRule, 0)
+	for _, domain := range strings.Split(domains, sep) {
+		domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain == "" {
+			continue
+		}
+		if _, err := path.Match(domain, ""); err != nil {
+			return nil, fmt.Errorf("the domain %s is not a legal wildcard pattern", domain)
+		}
+		r := rule
+		r.Pattern = domain
+		rules = append(rules, r)
+	}
+	if len(rules) == 0 {
+		return nil, errors.New("the domain is empty")
+	}
+	return rules, nil
+}
+
+// dnsQuestion is the first question of the query
+type dnsQuestion struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+	// end is the offset after the question in the query
+	end int
+}
+
+// parseDnsQuestion returns the first question of the query, the compressed names are not supported
+// because the queries never contain them
+func parseDnsQuestion(query []byte) (*dnsQuestion, error) {
+	if len(query) < dnsHeaderLen || binary.BigEndian.Uint16(query[4:6]) == 0 {
+		return nil, errors.New("no question")
+	}
+	labels := make([]string, 0)
+	offset := dnsHeaderLen
+	for {
+		if offset >= len(query) {
+			return nil, errors.New("truncated name")
+		}
+		length := int(query[offset])
+		offset++
+		if length == 0 {
+			break
+		}
+		if length&0xc0 != 0 || offset+length > len(query) {
+			return nil, errors.New("illegal label")
+		}
+		labels = append(labels, string(query[offset:offset+length]))
+		offset += length
+	}
+	if offset+4 > len(query) {
+		return nil, errors.New("truncated question")
+	}
+	return &dnsQuestion{
+		name:   strings.ToLower(strings.Join(labels, ".")),
+		qtype:  binary.BigEndian.Uint16(query[offset : offset+2]),
+		qclass: binary.BigEndian.Uint16(query[offset+2 : offset+4]),
+		end:    offset + 4,
+	}, nil
+}
+
+// buildDnsReply returns the reply of the query with the first question, the rcode and the answers
+func buildDnsReply(query []byte, question *dnsQuestion, rcode byte, truncated bool, answers [][]byte) []byte {
+	reply := make([]byte, 0, question.end+len(answers)*28)
+	reply = append(reply, query[0], query[1])
+	// keep the opcode and the recursion desired bit of the query
+	flags := 0x80 | query[2]&0x79
+	if truncated {
+		flags |= 0x02
+	}
+	// recursion available
+	reply = append(reply, flags, 0x80|rcode&0x0f)
+	reply = binary.BigEndian.AppendUint16(reply, 1)
+	reply = binary.BigEndian.AppendUint16(reply, uint16(len(answers)))
+	reply = binary.BigEndian.AppendUint16(reply, 0)
+	reply = binary.BigEndian.AppendUint16(reply, 0)
+	reply = append(reply, query[dnsHeaderLen:question.end]...)
+	for _, answer := range answers {
+		reply = append(reply, answer...)
+	}
+	return reply
+}
+
+// wrongAnswers returns the answers of the ips matching the question type, the name points to the question
+func wrongAnswers(question *dnsQuestion, ips []string) [][]byte {
+	answers := make([][]byte, 0)
+	for _, value := range ips {
+		ip := net.ParseIP(value)
+		rdata := ip.To4()
+		if question.qtype == dnsTypeAAAA {
+			if rdata != nil {
+				continue
+			}
+			rdata = ip.To16()
+		}
+		if rdata == nil {
+			continue
+		}
+		answer := []byte{0xc0, dnsHeaderLen}
+		answer = binary.BigEndian.AppendUint16(answer, question.qtype)
+		answer = binary.BigEndian.AppendUint16(answer, dnsClassIN)
+		answer = binary.BigEndian.AppendUint32(answer, dnsAnswerTTL)
+		answer = binary.BigEndian.AppendUint16(answer, uint16(len(rdata)))
+		answers = append(answers, append(answer, rdata...))
+	}
+	return answers
+}
+
+// dnsStub answers the queries of the matched domains by the rules and forwards the others to the upstream
+type dnsStub struct {
+	upstream string
+	rules    []dnsRule
+	dialer   *net.Dialer
+}
+
+func newDnsStub(config dnsStubConfig) *dnsStub {
+	return &dnsStub{
+		upstream: config.Upstream,
+		rules:    config.Rules,
+		dialer:   &net.Dialer{Timeout: dnsUpstreamTimeout, Control: dnsMarkControl},
+	}
+}
+
+// handle returns the reply of the query, nil means no reply
+func (s *dnsStub) handle(ctx context.Context, query []byte, network string) []byte {
+	question, err := parseDnsQuestion(query)
+	if err != nil {
+		log.Warnf(ctx, "dns-stub-handle-parse query err, %v, forward it", err)
+		return s.forward(ctx, query, network)
+	}
+	rule := matchDnsRule(s.rules, question.name)
+	if rule == nil {
+		return s.forward(ctx, query, network)
+	}
+	log.Debugf(ctx, "dns-stub-handle-%s %d matches %s, %s", question.name, question.qtype, rule.Pattern, rule.Behavior)
+	switch rule.Behavior {
+	case DnsBehaviorNxdomain:
+		return buildDnsReply(query, question, dnsRcodeNxdomain, false, nil)
+	case DnsBehaviorServfail:
+		return buildDnsReply(query, question, dnsRcodeServfail, false, nil)
+	case DnsBehaviorTimeout:
+		return nil
+	case DnsBehaviorDelay:
+		time.Sleep(time.Duration(rule.Delay) * time.Millisecond)
+	case DnsBehaviorWrong:
+		if question.qclass == dnsClassIN && (question.qtype == dnsTypeA || question.qtype == dnsTypeAAAA) {
+			return buildDnsReply(query, question, 0, false, wrongAnswers(question, rule.Ips))
+		}
+	case DnsBehaviorTruncate:
+		// the client retries by tcp after the truncated udp reply, the tcp queries are forwarded
+		if network == "udp" {
+			return buildDnsReply(query, question, 0, true, nil)
+		}
+	}
+	return s.forward(ctx, query, network)
+}
+
+// forward sends the query to the upstream and returns the reply
+func (s *dnsStub) forward(ctx context.Context, query []byte, network string) []byte {
+	conn, err := s.dialer.Dial(network, s.upstream)
+	if err != nil {
+		log.Warnf(ctx, "dns-stub-forward-dial %s %s err, %v", network, s.upstream, err)
+		return nil
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dnsUpstreamTimeout))
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			log.Warnf(ctx, "dns-stub-forward-write to %s err, %v", s.upstream, err)
+			return nil
+		}
+		reply := make([]byte, 65535)
+		n, err := conn.Read(reply)
+		if err != nil {
+			log.Warnf(ctx, "dns-stub-forward-read from %s err, %v", s.upstream, err)
+			return nil
+		}
+		return reply[:n]
+	}
+	if err := writeTcpMessage(conn, query); err != nil {
+		log.Warnf(ctx, "dns-stub-forward-write to %s err, %v", s.upstream, err)
+		return nil
+	}
+	reply, err := readTcpMessage(conn)
+	if err != nil {
+		log.Warnf(ctx, "dns-stub-forward-read from %s err, %v", s.upstream, err)
+		return nil
+	}
+	return reply
+}
+
+// serveUdp answers the udp queries, each query is handled in its own goroutine so the delayed
+// queries do not block the others
+func (s *dnsStub) serveUdp(ctx context.Context, conn net.PacketConn) error {
+	for {
+		buf := make([]byte, 65535)
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		go func(query []byte, addr net.Addr) {
+			if reply := s.handle(ctx, query, "udp"); reply != nil {
+				if _, err := conn.WriteTo(reply, addr); err != nil {
+					log.Warnf(ctx, "dns-stub-serveUdp-reply to %s err, %v", addr, err)
+				}
+			}
+		}(buf[:n], addr)
+	}
+}
+
+// serveTcp answers the tcp queries, the length of each message is prefixed by two bytes
+func (s *dnsStub) serveTcp(ctx context.Context, listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			for {
+				conn.SetReadDeadline(time.Now().Add(dnsTcpIdleTimeout))
+				query, err := readTcpMessage(conn)
+				if err != nil {
+					return
+				}
+				if reply := s.handle(ctx, query, "tcp"); reply != nil {
+					if err := writeTcpMessage(conn, reply); err != nil {
+						return
+					}
+				}
+			}
+		}(conn)
+	}
+}
+
+func readTcpMessage(conn net.Conn) ([]byte, error) {
+	length := make([]byte, 2)
+	if _, err := io.ReadFull(conn, length); err != nil {
+		return nil, err
+	}
+	message := make([]byte, binary.BigEndian.Uint16(length))
+	if _, err := io.ReadFull(conn, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func writeTcpMessage(conn net.Conn, message []byte) error {
+	_, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(message))), message...))
+	return err
+}
+
+// RunDnsStub runs the stub dns server on the udp and tcp listeners inherited from the parent,
+// the listeners are the extra files in order of udp and tcp of each family. It never returns
+// until the process is killed by the destroy command.
+func RunDnsStub(args []string) {
+	util.InitLog(util.Bin)
+	if len(args) < 2 {
+		log.Errorf(context.Background(), "dns-stub-uid|config is nil")
+		os.Exit(1)
+	}
+	ctx := context.WithValue(context.Background(), spec.Uid, args[0])
+	var config dnsStubConfig
+	if err := json.Unmarshal([]byte(args[1]), &config); err != nil {
+		log.Errorf(ctx, "dns-stub-unmarshal config %s err, %v", args[1], err)
+		os.Exit(1)
+	}
+	stub := newDnsStub(config)
+	errs := make(chan error, config.Listeners)
+	for i := 0; i < config.Listeners; i++ {
+		file := os.NewFile(uintptr(3+i), fmt.Sprintf("dns-stub-listener-%d", i))
+		if i%2 == 0 {
+			conn, err := net.FilePacketConn(file)
+			if err != nil {
+				log.Errorf(ctx, "dns-stub-inherit udp listener %d err, %v", i, err)
+				os.Exit(1)
+			}
+			go func() { errs <- stub.serveUdp(ctx, conn) }()
+		} else {
+			listener, err := net.FileListener(file)
+			if err != nil {
+				log.Errorf(ctx, "dns-stub-inherit tcp listener %d err, %v", i, err)
+				os.Exit(1)
+			}
+			go func() { errs <- stub.serveTcp(ctx, listener) }()
+		}
+	}
+	log.Infof(ctx, "dns-stub-serving %d listeners, upstream %s, rules %+v", config.Listeners, config.Upstream, config.Rules)
+	err := <-errs
+	log.Errorf(ctx, "dns-stub-serve err, %v", err)
+	os.Exit(1)
+}
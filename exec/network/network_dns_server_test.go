@@ -0,0 +1,118 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseDnsRules(t *testing.T) {
+	rules, err := parseDnsRules("*.Example.com.=nxdomain; api.a.com,api.b.com=delay:200;c.com=wrong:10.0.0.1|fd00::1;d.com=delay")
+	if err != nil {
+		t.Fatalf("parseDnsRules() err, %v", err)
+	}
+	expect := []dnsRule{
+		{Pattern: "*.example.com", Behavior: DnsBehaviorNxdomain},
+		{Pattern: "api.a.com", Behavior: DnsBehaviorDelay, Delay: 200},
+		{Pattern: "api.b.com", Behavior: DnsBehaviorDelay, Delay: 200},
+		{Pattern: "c.com", Behavior: DnsBehaviorWrong, Ips: []string{"10.0.0.1", "fd00::1"}},
+		{Pattern: "d.com", Behavior: DnsBehaviorDelay, Delay: dnsDefaultDelay},
+	}
+	if !reflect.DeepEqual(rules, expect) {
+		t.Errorf("parseDnsRules() = %+v, want %+v", rules, expect)
+	}
+	for _, illegal := range []string{"", "a.com", "a.com=unknown", "a.com=wrong", "a.com=wrong:1.2.3", "a.com=delay:-1", "a.com=nxdomain:1", "[a.com=timeout"} {
+		if _, err := parseDnsRules(illegal); err == nil {
+			t.Errorf("parseDnsRules(%q) expects err", illegal)
+		}
+	}
+
+	if rule := matchDnsRule(rules, "www.EXAMPLE.com."); rule == nil || rule.Pattern != "*.example.com" {
+		t.Errorf("matchDnsRule() = %v, want *.example.com", rule)
+	}
+	if rule := matchDnsRule(rules, "example.com"); rule != nil {
+		t.Errorf("matchDnsRule() = %v, want nil", rule)
+	}
+}
+
+// buildDnsQuery returns the query of the name with the recursion desired bit
+func buildDnsQuery(name string, qtype uint16) []byte {
+	query := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	for _, label := range []string{name[:len(name)-4], "com"} {
+		query = append(append(query, byte(len(label))), label...)
+	}
+	query = append(query, 0)
+	query = binary.BigEndian.AppendUint16(query, qtype)
+	return binary.BigEndian.AppendUint16(query, dnsClassIN)
+}
+
+func TestDnsStubHandle(t *testing.T) {
+	upstream, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("listen upstream err, %v", err)
+	}
+	defer upstream.Close()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := upstream.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			// the upstream answers every query with the refused rcode
+			reply := append([]byte{}, buf[:n]...)
+			reply[2], reply[3] = 0x81, 0x85
+			upstream.WriteTo(reply, addr)
+		}
+	}()
+	rules, _ := parseDnsRules("*.nx.com=nxdomain;sf.com=servfail;to.com=timeout;wr.com=wrong:10.0.0.1|fd00::1;tc.com=truncate")
+	stub := newDnsStub(dnsStubConfig{Upstream: upstream.LocalAddr().String(), Rules: rules})
+	stub.dialer.Control = nil
+	ctx := context.Background()
+
+	rcode := func(reply []byte) byte { return reply[3] & 0x0f }
+	answers := func(reply []byte) uint16 { return binary.BigEndian.Uint16(reply[6:8]) }
+
+	if reply := stub.handle(ctx, buildDnsQuery("a.nx.com", dnsTypeA), "udp"); rcode(reply) != dnsRcodeNxdomain || reply[0] != 0x12 {
+		t.Errorf("nxdomain reply = %v", reply)
+	}
+	if reply := stub.handle(ctx, buildDnsQuery("sf.com", dnsTypeA), "udp"); rcode(reply) != dnsRcodeServfail {
+		t.Errorf("servfail reply = %v", reply)
+	}
+	if reply := stub.handle(ctx, buildDnsQuery("to.com", dnsTypeA), "udp"); reply != nil {
+		t.Errorf("timeout reply = %v, want nil", reply)
+	}
+	reply := stub.handle(ctx, buildDnsQuery("wr.com", dnsTypeA), "udp")
+	if rcode(reply) != 0 || answers(reply) != 1 || !net.IP(reply[len(reply)-4:]).Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("wrong A reply = %v", reply)
+	}
+	reply = stub.handle(ctx, buildDnsQuery("wr.com", dnsTypeAAAA), "udp")
+	if answers(reply) != 1 || !net.IP(reply[len(reply)-16:]).Equal(net.ParseIP("fd00::1")) {
+		t.Errorf("wrong AAAA reply = %v", reply)
+	}
+	if reply := stub.handle(ctx, buildDnsQuery("tc.com", dnsTypeA), "udp"); reply[2]&0x02 == 0 || answers(reply) != 0 {
+		t.Errorf("truncated reply = %v", reply)
+	}
+	// the queries not matched are answered by the upstream
+	if reply := stub.handle(ctx, buildDnsQuery("ok.com", dnsTypeA), "udp"); reply == nil || rcode(reply) != 5 {
+		t.Errorf("forwarded reply = %v", reply)
+	}
+}
@@ -0,0 +1,296 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	osexec "os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/dryrun"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+)
+
+const (
+	DnsModeHosts = "hosts"
+	DnsModeStub  = "stub"
+)
+
+// the resolv.conf of systemd-resolved lists the real upstream servers instead of the local stub 127.0.0.53
+var resolvConfs = []string{"/etc/resolv.conf", "/run/systemd/resolve/resolv.conf"}
+
+// dnsRedirect is the port of the stub listener which the dns packets of the family and protocol are redirected to
+type dnsRedirect struct {
+	family   string
+	protocol string
+	port     int
+}
+
+// dnsStubListeners are the sockets of the stub dns server, they are bound by chaos_os and inherited
+// by the stub process, so the stub is ready to receive the packets once the redirect rules are added
+type dnsStubListeners struct {
+	files     []*os.File
+	redirects []dnsRedirect
+}
+
+func (l *dnsStubListeners) close() {
+	for _, file := range l.files {
+		file.Close()
+	}
+}
+
+// listenDnsStub binds the udp and tcp sockets on a random port of the loopback address of each family,
+// the ipv6 family is skipped if it is not enabled
+func listenDnsStub(ctx context.Context, ipv6 bool) (*dnsStubListeners, error) {
+	listeners := &dnsStubListeners{files: make([]*os.File, 0)}
+	families := []string{"ip"}
+	if ipv6 {
+		families = append(families, "ip6")
+	}
+	for _, family := range families {
+		address, suffix := "127.0.0.1:0", "4"
+		if family == "ip6" {
+			address, suffix = "[::1]:0", "6"
+		}
+		conn, err := net.ListenPacket("udp"+suffix, address)
+		if err != nil {
+			if family == "ip6" {
+				log.Warnf(ctx, "network-dns-listenDnsStub-ipv6 is not enabled, %v", err)
+				break
+			}
+			listeners.close()
+			return nil, err
+		}
+		listener, err := net.Listen("tcp"+suffix, address)
+		if err != nil {
+			conn.Close()
+			listeners.close()
+			return nil, err
+		}
+		for _, socket := range []interface{ File() (*os.File, error) }{conn.(*net.UDPConn), listener.(*net.TCPListener)} {
+			file, err := socket.File()
+			if err != nil {
+				conn.Close()
+				listener.Close()
+				listeners.close()
+				return nil, err
+			}
+			listeners.files = append(listeners.files, file)
+		}
+		listeners.redirects = append(listeners.redirects,
+			dnsRedirect{family: family, protocol: "udp", port: conn.LocalAddr().(*net.UDPAddr).Port},
+			dnsRedirect{family: family, protocol: "tcp", port: listener.Addr().(*net.TCPAddr).Port})
+		// the duplicated files keep the sockets open
+		conn.Close()
+		listener.Close()
+	}
+	return listeners, nil
+}
+
+// startDnsStub starts the stub dns server process and redirects the dns packets sent by the host to it
+func (ns *NetworkDnsExecutor) startDnsStub(ctx context.Context, uid string, rules []dnsRule, upstream, backend string) *spec.Response {
+	upstream, response := resolveDnsUpstream(ctx, ns.channel, upstream)
+	if response != nil {
+		return response
+	}
+	backend, response = selectFirewallBackend(ctx, ns.channel, backend, false)
+	if response != nil {
+		return response
+	}
+	ipv6 := backend == BackendNftables || ns.channel.IsCommandAvailable(ctx, "ip6tables")
+	listeners, err := listenDnsStub(ctx, ipv6)
+	if err != nil {
+		log.Errorf(ctx, "network-dns-startDnsStub-listen err, %v", err)
+		return spec.ResponseFailWithFlags(spec.OsCmdExecFailed, "listen", err)
+	}
+	defer listeners.close()
+
+	config, _ := json.Marshal(dnsStubConfig{Upstream: upstream, Rules: rules, Listeners: len(listeners.files)})
+	bin, err := os.Executable()
+	if err != nil {
+		return spec.ResponseFailWithFlags(spec.OsCmdExecFailed, "executable", err)
+	}
+	if recorder, ok := ns.channel.(*dryrun.RecordChannel); ok {
+		recorder.Record(dryrun.Operation{
+			Type:    dryrun.OperationProcess,
+			Command: fmt.Sprintf("%s %s %s '%s'", bin, DnsStubMode, uid, config),
+		})
+	} else {
+		command := osexec.Command(bin, DnsStubMode, uid, string(config))
+		command.ExtraFiles = listeners.files
+		// the stub survives the caller because it runs in a new session
+		command.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+		if err := command.Start(); err != nil {
+			log.Errorf(ctx, "network-dns-startDnsStub-start stub err, %v", err)
+			return spec.ResponseFailWithFlags(spec.OsCmdExecFailed, "dns-stub", err)
+		}
+		store.RecordPid(ctx, command.Process.Pid)
+		command.Process.Release()
+	}
+
+	if backend == BackendNftables {
+		response = addNftChains(ctx, ns.channel, buildNftDnsRedirectChains(uid, listeners.redirects))
+	} else {
+		response = ns.addIptablesDnsRedirects(ctx, uid, listeners.redirects)
+	}
+	if !response.Success {
+		ns.stopDnsStub(ctx, uid, backend)
+		return response
+	}
+	return spec.ReturnSuccess(uid)
+}
+
+// stopDnsStub deletes the redirect rules before killing the stub, so the dns packets are never redirected
+// to the closed port
+func (ns *NetworkDnsExecutor) stopDnsStub(ctx context.Context, uid, backend string) *spec.Response {
+	backend, response := selectFirewallBackend(ctx, ns.channel, backend, false)
+	if response != nil {
+		return response
+	}
+	if backend == BackendNftables {
+		response = deleteNftChains(ctx, ns.channel, buildNftDnsRedirectChains(uid, nil))
+	} else {
+		response = ns.deleteIptablesDnsRedirects(ctx, uid)
+	}
+	if !response.Success {
+		return response
+	}
+	// the stub is not started by dry-run, so its pid is unknown
+	if recorder, ok := ns.channel.(*dryrun.RecordChannel); ok && len(store.Resources(ctx, store.ResourcePid)) == 0 {
+		recorder.Record(dryrun.Operation{Type: dryrun.OperationCommand, Command: "kill -9 <pid of the dns stub process>"})
+		return spec.Success()
+	}
+	return exec.Destroy(ctx, ns.channel, "network dns")
+}
+
+// resolveDnsUpstream returns the address of the upstream server, the first non-loopback nameserver
+// of the resolv.conf files is used if not specified
+func resolveDnsUpstream(ctx context.Context, cl spec.Channel, upstream string) (string, *spec.Response) {
+	if upstream != "" {
+		if _, _, err := net.SplitHostPort(upstream); err == nil {
+			return upstream, nil
+		}
+		if net.ParseIP(upstream) == nil {
+			log.Errorf(ctx, "`%s`: network-dns-upstream is illegal", upstream)
+			return "", spec.ResponseFailWithFlags(spec.ParameterIllegal, "upstream", upstream, "it must be an ip or ip:port")
+		}
+		return net.JoinHostPort(upstream, "53"), nil
+	}
+	for _, resolvConf := range resolvConfs {
+		response := cl.Run(ctx, "awk", fmt.Sprintf(`'$1 == "nameserver" {print $2}' %s`, resolvConf))
+		if !response.Success || util.IsNil(response.Result) {
+			continue
+		}
+		for _, nameserver := range strings.Fields(response.Result.(string)) {
+			// the local resolver forwards the queries to its upstream which would be redirected to the stub again
+			if ip := net.ParseIP(nameserver); ip != nil && !ip.IsLoopback() {
+				return net.JoinHostPort(nameserver, "53"), nil
+			}
+		}
+	}
+	log.Errorf(ctx, "network-dns-resolveDnsUpstream-no upstream nameserver is found in %v", resolvConfs)
+	return "", spec.ResponseFailWithFlags(spec.ParameterLess, "upstream")
+}
+
+// dnsRedirectComment returns the comment of the iptables redirect rules of the experiment
+func dnsRedirectComment(uid string) string {
+	return fmt.Sprintf("chaosblade-dns-%s", uid)
+}
+
+// buildIptablesDnsRedirect returns the rule redirecting the dns packets not sent by the stub, for example
+// OUTPUT -t nat -p udp --dport 53 -m mark ! --mark 0xcb53 -m comment --comment chaosblade-dns-1a2b -j REDIRECT --to-ports 40000
+func buildIptablesDnsRedirect(uid string, redirect dnsRedirect) string {
+	return fmt.Sprintf("OUTPUT -t nat -p %s --dport 53 -m mark ! --mark %#x -m comment --comment %s -j REDIRECT --to-ports %d",
+		redirect.protocol, dnsStubMark, dnsRedirectComment(uid), redirect.port)
+}
+
+func (ns *NetworkDnsExecutor) addIptablesDnsRedirects(ctx context.Context, uid string, redirects []dnsRedirect) *spec.Response {
+	for _, redirect := range redirects {
+		command := "iptables"
+		if redirect.family == "ip6" {
+			command = "ip6tables"
+		}
+		rule := buildIptablesDnsRedirect(uid, redirect)
+		response := ns.channel.Run(ctx, command, fmt.Sprintf("-A %s", rule))
+		if !response.Success {
+			log.Errorf(ctx, "network-dns-addIptablesDnsRedirects-add %s err, %s", rule, response.Err)
+			return response
+		}
+		store.Record(ctx, store.Resource{Kind: store.ResourceIptables, Value: rule, Attrs: map[string]string{"command": command}})
+	}
+	return spec.Success()
+}
+
+// deleteIptablesDnsRedirects deletes the recorded redirect rules, or the rules commented with the uid if nothing is recorded
+func (ns *NetworkDnsExecutor) deleteIptablesDnsRedirects(ctx context.Context, uid string) *spec.Response {
+	resources := store.Resources(ctx, store.ResourceIptables)
+	if len(resources) == 0 {
+		for _, command := range []string{"iptables", "ip6tables"} {
+			if !ns.channel.IsCommandAvailable(ctx, command) {
+				continue
+			}
+			response := ns.channel.Run(ctx, command, "-S OUTPUT -t nat")
+			if !response.Success || util.IsNil(response.Result) {
+				continue
+			}
+			for _, line := range strings.Split(response.Result.(string), "\n") {
+				if strings.HasPrefix(line, "-A OUTPUT ") && strings.Contains(line, dnsRedirectComment(uid)) {
+					resources = append(resources, store.Resource{
+						Value: fmt.Sprintf("OUTPUT -t nat %s", strings.TrimPrefix(line, "-A OUTPUT ")),
+						Attrs: map[string]string{"command": command},
+					})
+				}
+			}
+		}
+	}
+	for _, resource := range resources {
+		command := resource.Attrs["command"]
+		if !ns.channel.Run(ctx, command, fmt.Sprintf("-C %s", resource.Value)).Success {
+			log.Warnf(ctx, "network-dns-deleteIptablesDnsRedirects-%s not found, skip deleting it", resource.Value)
+			continue
+		}
+		if response := ns.channel.Run(ctx, command, fmt.Sprintf("-D %s", resource.Value)); !response.Success {
+			log.Errorf(ctx, "network-dns-deleteIptablesDnsRedirects-delete %s err, %s", resource.Value, response.Err)
+			return response
+		}
+	}
+	return spec.Success()
+}
+
+// buildNftDnsRedirectChains returns the nat chain redirecting the dns packets not sent by the stub, for example
+// meta mark != 0xcb53 meta nfproto ipv4 udp dport 53 redirect to :40000
+func buildNftDnsRedirectChains(uid string, redirects []dnsRedirect) []nftChain {
+	rules := make([]string, 0, len(redirects))
+	for _, redirect := range redirects {
+		nfproto := "ipv4"
+		if redirect.family == "ip6" {
+			nfproto = "ipv6"
+		}
+		rules = append(rules, fmt.Sprintf("meta mark != %#x meta nfproto %s %s dport 53 redirect to :%d",
+			dnsStubMark, nfproto, redirect.protocol, redirect.port))
+	}
+	return []nftChain{{name: nftChainName("dns", uid, "output"), hook: "output", chainType: "nat", priority: -100, rules: rules}}
+}
@@ -59,8 +59,21 @@ func NewDropActionSpec() spec.ExpActionCommandSpec {
 					Name: "network-traffic",
 					Desc: "The direction of network traffic",
 				},
+				&spec.ExpFlag{
+					Name: "protocol",
+					Desc: "The protocols of packet, comma separated tcp, udp and icmp, or all, default value is tcp,udp. The ports can only be specified for tcp and udp",
+				},
+			},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "mode",
+					Desc: "How the packets are blocked, drop, reject-rst, reject-icmp or reject-port-unreachable, default value is drop. The drop makes the clients hang until timeout, the reject-rst resets the tcp connections and rejects the other packets with icmp port unreachable, the reject-icmp replies icmp host unreachable and the reject-port-unreachable replies icmp port unreachable",
+				},
+				&spec.ExpFlag{
+					Name: "backend",
+					Desc: "The firewall backend, iptables or nftables. By default nftables is used if the nft command exists and iptables is missing or based on nf_tables, otherwise iptables is used",
+				},
 			},
-			ActionFlags:    []spec.ExpFlagSpec{},
 			ActionExecutor: &NetworkDropExecutor{},
 			ActionExample: `
 # Block incoming connection from the source ip 10.10.10.10
@@ -83,6 +96,15 @@ blade create network drop --string-pattern baidu.com --network-traffic out
 
 # Block outgoing connection to the specific domain on port 80
 blade create network drop --destination-port 80 --string-pattern baidu.com --network-traffic out
+
+# Reset the outgoing connections to the port 3306 immediately instead of dropping the packets silently
+blade create network drop --destination-port 3306 --network-traffic out --mode reject-rst
+
+# Block the ping to 10.10.10.10
+blade create network drop --destination-ip 10.10.10.10 --protocol icmp --network-traffic out
+
+# Block outgoing connection to the port 3306 by the nftables, the rules are added to a dedicated chain of the experiment
+blade create network drop --destination-port 3306 --network-traffic out --backend nftables
 `,
 			ActionPrograms:   []string{DropNetworkBin},
 			ActionCategories: []string{category.SystemNetwork},
@@ -118,140 +140,98 @@ func (*NetworkDropExecutor) Name() string {
 }
 
 func (ne *NetworkDropExecutor) Exec(suid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
-	commands := []string{"iptables"}
-	if response, ok := ne.channel.IsAllCommandsAvailable(ctx, commands); !ok {
+	rule := dropRule{
+		sourceIp:        model.ActionFlags["source-ip"],
+		destinationIp:   model.ActionFlags["destination-ip"],
+		sourcePort:      model.ActionFlags["source-port"],
+		destinationPort: model.ActionFlags["destination-port"],
+		stringPattern:   model.ActionFlags["string-pattern"],
+		networkTraffic:  model.ActionFlags["network-traffic"],
+		protocol:        model.ActionFlags["protocol"],
+		mode:            model.ActionFlags["mode"],
+	}
+	if response := validateDropRule(ctx, rule); response != nil {
+		return response
+	}
+	backend, response := selectDropBackend(ctx, ne.channel, model.ActionFlags["backend"], rule)
+	if response != nil {
 		return response
 	}
-
-	sourceIp := model.ActionFlags["source-ip"]
-	destinationIp := model.ActionFlags["destination-ip"]
-	sourcePort := model.ActionFlags["source-port"]
-	destinationPort := model.ActionFlags["destination-port"]
-	stringPattern := model.ActionFlags["string-pattern"]
-	networkTraffic := model.ActionFlags["network-traffic"]
 	if _, ok := spec.IsDestroy(ctx); ok {
-		return ne.stop(sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, networkTraffic, ctx)
+		return backend.stop(ctx, suid, rule)
 	}
 
-	return ne.start(sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, networkTraffic, ctx)
+	return ne.start(ctx, backend, suid, rule)
 }
 
-func (ne *NetworkDropExecutor) start(sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, networkTraffic string, ctx context.Context) *spec.Response {
-	if destinationIp == "" && sourceIp == "" && destinationPort == "" && sourcePort == "" && stringPattern == "" {
+func (ne *NetworkDropExecutor) start(ctx context.Context, backend dropBackend, uid string, rule dropRule) *spec.Response {
+	if rule.destinationIp == "" && rule.sourceIp == "" && rule.destinationPort == "" && rule.sourcePort == "" && rule.stringPattern == "" {
 		return spec.ReturnFail(spec.OsCmdExecFailed, "must specify ip or port or string flag")
 	}
+	return backend.start(ctx, uid, rule)
+}
 
-	var response *spec.Response
+// buildDropRules returns the iptables rule specifications without the command, for example INPUT -p tcp --dport 80 -j DROP
+func buildDropRules(rule dropRule) []string {
+	rules := make([]string, 0)
 	netFlows := []string{"INPUT", "OUTPUT"}
-	if networkTraffic == "in" {
+	if rule.networkTraffic == "in" {
 		netFlows = []string{"INPUT"}
 	}
-	if networkTraffic == "out" {
+	if rule.networkTraffic == "out" {
 		netFlows = []string{"OUTPUT"}
 	}
 	for _, netFlow := range netFlows {
-		tcpArgs := fmt.Sprintf("-A %s -p tcp", netFlow)
-		udpArgs := fmt.Sprintf("-A %s -p udp", netFlow)
-		if sourceIp != "" {
-			tcpArgs = fmt.Sprintf("%s -s %s", tcpArgs, sourceIp)
-			udpArgs = fmt.Sprintf("%s -s %s", udpArgs, sourceIp)
-		}
-		if destinationIp != "" {
-			tcpArgs = fmt.Sprintf("%s -d %s", tcpArgs, destinationIp)
-			udpArgs = fmt.Sprintf("%s -d %s", udpArgs, destinationIp)
-		}
-		if sourcePort != "" {
-			if strings.Contains(sourcePort, ",") {
-				tcpArgs = fmt.Sprintf("%s -m multiport --sports %s", tcpArgs, sourcePort)
-				udpArgs = fmt.Sprintf("%s -m multiport --sports %s", udpArgs, sourcePort)
-			} else {
-				tcpArgs = fmt.Sprintf("%s --sport %s", tcpArgs, sourcePort)
-				udpArgs = fmt.Sprintf("%s --sport %s", udpArgs, sourcePort)
+		for _, protocol := range rule.expandProtocols() {
+			args := netFlow
+			if protocol != ProtocolAll {
+				args = fmt.Sprintf("%s -p %s", args, protocol)
 			}
-		}
-		if destinationPort != "" {
-			if strings.Contains(destinationPort, ",") {
-				tcpArgs = fmt.Sprintf("%s -m multiport --dports %s", tcpArgs, destinationPort)
-				udpArgs = fmt.Sprintf("%s -m multiport --dports %s", udpArgs, destinationPort)
-			} else {
-				tcpArgs = fmt.Sprintf("%s --dport %s", tcpArgs, destinationPort)
-				udpArgs = fmt.Sprintf("%s --dport %s", udpArgs, destinationPort)
+			if rule.sourceIp != "" {
+				args = fmt.Sprintf("%s -s %s", args, rule.sourceIp)
 			}
-		}
-		if stringPattern != "" {
-			tcpArgs = fmt.Sprintf("%s -m string --string %s --algo bm", tcpArgs, stringPattern)
-			udpArgs = fmt.Sprintf("%s -m string --string %s --algo bm", udpArgs, stringPattern)
-		}
-		tcpArgs = fmt.Sprintf("%s -j DROP", tcpArgs)
-		udpArgs = fmt.Sprintf("%s -j DROP", udpArgs)
-		response = ne.channel.Run(ctx, "iptables", fmt.Sprintf(`%s`, tcpArgs))
-		if !response.Success {
-			ne.stop(sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, networkTraffic, ctx)
-			return response
-		}
-		response = ne.channel.Run(ctx, "iptables", fmt.Sprintf(`%s`, udpArgs))
-		if !response.Success {
-			ne.stop(sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, networkTraffic, ctx)
+			if rule.destinationIp != "" {
+				args = fmt.Sprintf("%s -d %s", args, rule.destinationIp)
+			}
+			if rule.sourcePort != "" {
+				if strings.Contains(rule.sourcePort, ",") {
+					args = fmt.Sprintf("%s -m multiport --sports %s", args, rule.sourcePort)
+				} else {
+					args = fmt.Sprintf("%s --sport %s", args, rule.sourcePort)
+				}
+			}
+			if rule.destinationPort != "" {
+				if strings.Contains(rule.destinationPort, ",") {
+					args = fmt.Sprintf("%s -m multiport --dports %s", args, rule.destinationPort)
+				} else {
+					args = fmt.Sprintf("%s --dport %s", args, rule.destinationPort)
+				}
+			}
+			if rule.stringPattern != "" {
+				args = fmt.Sprintf("%s -m string --string %s --algo bm", args, rule.stringPattern)
+			}
+			rules = append(rules, fmt.Sprintf("%s -j %s", args, iptablesTarget(rule.mode, protocol)))
 		}
 	}
-	return response
+	return rules
 }
 
-
-func (ne *NetworkDropExecutor) stop(sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, networkTraffic string, ctx context.Context) *spec.Response {
-	var response *spec.Response
-	netFlows := []string{"INPUT", "OUTPUT"}
-	if networkTraffic == "in" {
-		netFlows = []string{"INPUT"}
-	}
-	if networkTraffic == "out" {
-		netFlows = []string{"OUTPUT"}
-	}
-	for _, netFlow := range netFlows {
-		tcpArgs := fmt.Sprintf("-D %s -p tcp", netFlow)
-		udpArgs := fmt.Sprintf("-D %s -p udp", netFlow)
-		if sourceIp != "" {
-			tcpArgs = fmt.Sprintf("%s -s %s", tcpArgs, sourceIp)
-			udpArgs = fmt.Sprintf("%s -s %s", udpArgs, sourceIp)
-		}
-		if destinationIp != "" {
-			tcpArgs = fmt.Sprintf("%s -d %s", tcpArgs, destinationIp)
-			udpArgs = fmt.Sprintf("%s -d %s", udpArgs, destinationIp)
-		}
-		if sourcePort != "" {
-			if strings.Contains(sourcePort, ",") {
-				tcpArgs = fmt.Sprintf("%s -m multiport --sports %s", tcpArgs, sourcePort)
-				udpArgs = fmt.Sprintf("%s -m multiport --sports %s", udpArgs, sourcePort)
-			} else {
-				tcpArgs = fmt.Sprintf("%s --sport %s", tcpArgs, sourcePort)
-				udpArgs = fmt.Sprintf("%s --sport %s", udpArgs, sourcePort)
-			}
-		}
-		if destinationPort != "" {
-			if strings.Contains(destinationPort, ",") {
-				tcpArgs = fmt.Sprintf("%s -m multiport --dports %s", tcpArgs, destinationPort)
-				udpArgs = fmt.Sprintf("%s -m multiport --dports %s", udpArgs, destinationPort)
-			} else {
-				tcpArgs = fmt.Sprintf("%s --dport %s", tcpArgs, destinationPort)
-				udpArgs = fmt.Sprintf("%s --dport %s", udpArgs, destinationPort)
-			}
-		}
-		if stringPattern != "" {
-			tcpArgs = fmt.Sprintf("%s -m string --string %s --algo bm", tcpArgs, stringPattern)
-			udpArgs = fmt.Sprintf("%s -m string --string %s --algo bm", udpArgs, stringPattern)
-		}
-		tcpArgs = fmt.Sprintf("%s -j DROP", tcpArgs)
-		udpArgs = fmt.Sprintf("%s -j DROP", udpArgs)
-		response = ne.channel.Run(ctx, "iptables", fmt.Sprintf(`%s`, tcpArgs))
-		if !response.Success {
-			return response
-		}
-		response = ne.channel.Run(ctx, "iptables", fmt.Sprintf(`%s`, udpArgs))
-		if !response.Success {
-			return response
+// iptablesTarget returns the target of the mode, the tcp reset is only valid for the tcp packets,
+// so the other packets are rejected with the icmp port unreachable
+func iptablesTarget(mode, protocol string) string {
+	switch mode {
+	case ModeRejectRst:
+		if protocol == "tcp" {
+			return "REJECT --reject-with tcp-reset"
 		}
+		return "REJECT --reject-with icmp-port-unreachable"
+	case ModeRejectIcmp:
+		return "REJECT --reject-with icmp-host-unreachable"
+	case ModeRejectPortUnreachable:
+		return "REJECT --reject-with icmp-port-unreachable"
+	default:
+		return "DROP"
 	}
-	return response
 }
 
 func (ne *NetworkDropExecutor) SetChannel(channel spec.Channel) {
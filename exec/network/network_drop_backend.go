@@ -0,0 +1,215 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+)
+
+const (
+	BackendIptables = "iptables"
+	BackendNftables = "nftables"
+)
+
+// the modes of blocking the packets
+const (
+	ModeDrop                  = "drop"
+	ModeRejectRst             = "reject-rst"
+	ModeRejectIcmp            = "reject-icmp"
+	ModeRejectPortUnreachable = "reject-port-unreachable"
+)
+
+// ProtocolAll matches the packets of all protocols
+const ProtocolAll = "all"
+
+// dropRule is the packets matched by the drop action
+type dropRule struct {
+	sourceIp        string
+	destinationIp   string
+	sourcePort      string
+	destinationPort string
+	stringPattern   string
+	networkTraffic  string
+	protocol        string
+	mode            string
+}
+
+// protocols returns the protocols of the rule, tcp and udp if not specified
+func (r dropRule) protocols() []string {
+	protocols := splitList(r.protocol)
+	if len(protocols) == 0 {
+		return []string{"tcp", "udp"}
+	}
+	for _, protocol := range protocols {
+		if protocol == ProtocolAll {
+			return []string{ProtocolAll}
+		}
+	}
+	return protocols
+}
+
+// expandProtocols returns the protocols of the rules to add, the tcp packets are reset by a separate rule
+// before all packets are rejected in the reject-rst mode
+func (r dropRule) expandProtocols() []string {
+	protocols := r.protocols()
+	if r.mode == ModeRejectRst && protocols[0] == ProtocolAll {
+		return []string{"tcp", ProtocolAll}
+	}
+	return protocols
+}
+
+func validateDropRule(ctx context.Context, rule dropRule) *spec.Response {
+	switch rule.mode {
+	case "", ModeDrop, ModeRejectRst, ModeRejectIcmp, ModeRejectPortUnreachable:
+	default:
+		log.Errorf(ctx, "`%s`: network-drop-mode is illegal", rule.mode)
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "mode", rule.mode,
+			"it must be drop, reject-rst, reject-icmp or reject-port-unreachable")
+	}
+	for _, protocol := range rule.protocols() {
+		switch protocol {
+		case "tcp", "udp":
+		case "icmp", ProtocolAll:
+			if rule.sourcePort != "" || rule.destinationPort != "" {
+				log.Errorf(ctx, "network-drop-ports can not be specified for the %s protocol", protocol)
+				return spec.ResponseFailWithFlags(spec.ParameterIllegal, "protocol", rule.protocol,
+					"the ports can only be specified for tcp and udp")
+			}
+		default:
+			log.Errorf(ctx, "`%s`: network-drop-protocol is illegal", rule.protocol)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "protocol", rule.protocol,
+				"it must be comma separated tcp, udp and icmp, or all")
+		}
+	}
+	return nil
+}
+
+// dropBackend installs the drop rules of the experiment into the firewall and removes them
+type dropBackend interface {
+	start(ctx context.Context, uid string, rule dropRule) *spec.Response
+	stop(ctx context.Context, uid string, rule dropRule) *spec.Response
+}
+
+// selectDropBackend returns the firewall backend of the drop rule
+func selectDropBackend(ctx context.Context, cl spec.Channel, backend string, rule dropRule) (dropBackend, *spec.Response) {
+	backend, response := selectFirewallBackend(ctx, cl, backend, rule.stringPattern != "")
+	if response != nil {
+		return nil, response
+	}
+	if backend == BackendIptables {
+		return &iptablesBackend{channel: cl}, nil
+	}
+	if rule.stringPattern != "" {
+		log.Errorf(ctx, "network-drop-string-pattern is not supported by nftables")
+		return nil, spec.ResponseFailWithFlags(spec.ParameterIllegal, "backend", backend, "the string-pattern flag is only supported by iptables")
+	}
+	return &nftablesBackend{channel: cl}, nil
+}
+
+// selectFirewallBackend returns the backend specified by the flag, the backend recorded by the experiment when destroying,
+// or the backend matching the firewall of the host
+func selectFirewallBackend(ctx context.Context, cl spec.Channel, backend string, iptablesOnly bool) (string, *spec.Response) {
+	if _, ok := spec.IsDestroy(ctx); ok {
+		if len(store.Resources(ctx, store.ResourceNftChain)) > 0 {
+			backend = BackendNftables
+		} else if len(store.Resources(ctx, store.ResourceIptables)) > 0 {
+			backend = BackendIptables
+		}
+	}
+	if backend == "" {
+		backend = BackendIptables
+		if !iptablesOnly {
+			backend = detectFirewallBackend(ctx, cl)
+		}
+		log.Infof(ctx, "network-selectFirewallBackend-use the %s backend", backend)
+	}
+	switch backend {
+	case BackendIptables:
+		if response, ok := cl.IsAllCommandsAvailable(ctx, []string{"iptables"}); !ok {
+			return "", response
+		}
+	case BackendNftables:
+		if response, ok := cl.IsAllCommandsAvailable(ctx, []string{"nft"}); !ok {
+			return "", response
+		}
+	default:
+		log.Errorf(ctx, "`%s`: network-backend is illegal", backend)
+		return "", spec.ResponseFailWithFlags(spec.ParameterIllegal, "backend", backend, "it must be iptables or nftables")
+	}
+	return backend, nil
+}
+
+// detectFirewallBackend returns nftables if the nft command exists and the iptables is missing or based on nf_tables,
+// so the legacy and nft rulesets are not mixed
+func detectFirewallBackend(ctx context.Context, cl spec.Channel) string {
+	if !cl.IsCommandAvailable(ctx, "nft") {
+		return BackendIptables
+	}
+	if !cl.IsCommandAvailable(ctx, "iptables") {
+		return BackendNftables
+	}
+	// iptables v1.8.7 (nf_tables) or iptables v1.8.7 (legacy)
+	response := cl.Run(ctx, "iptables", "-V")
+	if response.Success && !util.IsNil(response.Result) && strings.Contains(response.Result.(string), "nf_tables") {
+		return BackendNftables
+	}
+	return BackendIptables
+}
+
+// iptablesBackend appends the drop rules to the INPUT and OUTPUT chains
+type iptablesBackend struct {
+	channel spec.Channel
+}
+
+func (ib *iptablesBackend) start(ctx context.Context, uid string, rule dropRule) *spec.Response {
+	var response *spec.Response
+	for _, ruleSpec := range buildDropRules(rule) {
+		response = ib.channel.Run(ctx, "iptables", fmt.Sprintf(`-A %s`, ruleSpec))
+		if !response.Success {
+			ib.stop(ctx, uid, rule)
+			return response
+		}
+		store.Record(ctx, store.Resource{Kind: store.ResourceIptables, Value: ruleSpec})
+	}
+	return response
+}
+
+func (ib *iptablesBackend) stop(ctx context.Context, uid string, rule dropRule) *spec.Response {
+	rules := make([]string, 0)
+	// only the rules appended by the experiment are deleted if they are recorded
+	for _, resource := range store.Resources(ctx, store.ResourceIptables) {
+		rules = append(rules, resource.Value)
+	}
+	if len(rules) == 0 {
+		rules = buildDropRules(rule)
+	}
+	var response *spec.Response
+	for _, ruleSpec := range rules {
+		response = ib.channel.Run(ctx, "iptables", fmt.Sprintf(`-D %s`, ruleSpec))
+		if !response.Success {
+			return response
+		}
+	}
+	return response
+}
@@ -0,0 +1,271 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+)
+
+// NftTable is the table holding the chains of all experiments, each experiment has its own chains
+const NftTable = "inet chaosblade"
+
+// nftChain is a base chain of the experiment with its rules, the chain type is filter if not specified
+type nftChain struct {
+	name      string
+	hook      string
+	chainType string
+	priority  int
+	rules     []string
+}
+
+// nftablesBackend adds the drop rules to the input and output chains of the experiment in the chaosblade table
+type nftablesBackend struct {
+	channel spec.Channel
+}
+
+func (nb *nftablesBackend) start(ctx context.Context, uid string, rule dropRule) *spec.Response {
+	// the chains without rules would be created successfully but drop nothing
+	if len(nftAddressMatches(rule.sourceIp, rule.destinationIp)) == 0 {
+		log.Errorf(ctx, "`%s`, `%s`: network-drop-no source ip and destination ip of the same family", rule.sourceIp, rule.destinationIp)
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "destination-ip", rule.destinationIp,
+			"the source-ip and the destination-ip must have addresses of the same family")
+	}
+	return addNftChains(ctx, nb.channel, buildNftDropChains(uid, rule))
+}
+
+func (nb *nftablesBackend) stop(ctx context.Context, uid string, rule dropRule) *spec.Response {
+	return deleteNftChains(ctx, nb.channel, buildNftDropChains(uid, rule))
+}
+
+// buildNftDropChains returns the chains of the drop rule, for example
+// meta l4proto { tcp, udp } ip daddr { 10.0.0.1 } th dport { 80 } drop
+func buildNftDropChains(uid string, rule dropRule) []nftChain {
+	hooks := []string{"input", "output"}
+	if rule.networkTraffic == "in" {
+		hooks = []string{"input"}
+	}
+	if rule.networkTraffic == "out" {
+		hooks = []string{"output"}
+	}
+	ports := ""
+	if rule.sourcePort != "" {
+		ports = fmt.Sprintf(" th sport { %s }", nftPorts(rule.sourcePort))
+	}
+	if rule.destinationPort != "" {
+		ports = fmt.Sprintf("%s th dport { %s }", ports, nftPorts(rule.destinationPort))
+	}
+	rules := make([]string, 0)
+	for _, group := range nftProtocolGroups(rule) {
+		for _, addresses := range nftAddressMatches(rule.sourceIp, rule.destinationIp) {
+			rules = append(rules, strings.TrimSpace(fmt.Sprintf("%s%s%s %s", group.match, addresses, ports, group.verdict)))
+		}
+	}
+	chains := make([]nftChain, 0)
+	for _, hook := range hooks {
+		chains = append(chains, nftChain{name: nftChainName("drop", uid, hook), hook: hook, rules: rules})
+	}
+	return chains
+}
+
+// nftProtocolGroup is the protocols sharing the verdict
+type nftProtocolGroup struct {
+	match   string
+	verdict string
+}
+
+// nftProtocolGroups returns the protocol matches and the verdicts of the rule, for example
+// meta l4proto { tcp, udp } with drop, or meta l4proto { tcp } with reject with tcp reset
+func nftProtocolGroups(rule dropRule) []nftProtocolGroup {
+	groups := make([]nftProtocolGroup, 0)
+	protocols := make([]string, 0)
+	verdict := ""
+	flush := func() {
+		if len(protocols) > 0 {
+			groups = append(groups, nftProtocolGroup{match: fmt.Sprintf("meta l4proto { %s }", strings.Join(protocols, ", ")), verdict: verdict})
+			protocols = make([]string, 0)
+		}
+	}
+	for _, protocol := range rule.expandProtocols() {
+		if protocol == ProtocolAll {
+			flush()
+			groups = append(groups, nftProtocolGroup{verdict: nftVerdict(rule.mode, protocol)})
+			continue
+		}
+		if v := nftVerdict(rule.mode, protocol); v != verdict {
+			flush()
+			verdict = v
+		}
+		if protocol == "icmp" {
+			protocols = append(protocols, "icmp", "ipv6-icmp")
+		} else {
+			protocols = append(protocols, protocol)
+		}
+	}
+	flush()
+	return groups
+}
+
+// nftVerdict returns the verdict of the mode, the tcp reset is only valid for the tcp packets,
+// so the other packets are rejected with the icmp port unreachable
+func nftVerdict(mode, protocol string) string {
+	switch mode {
+	case ModeRejectRst:
+		if protocol == "tcp" {
+			return "reject with tcp reset"
+		}
+		return "reject with icmpx type port-unreachable"
+	case ModeRejectIcmp:
+		return "reject with icmpx type host-unreachable"
+	case ModeRejectPortUnreachable:
+		return "reject with icmpx type port-unreachable"
+	default:
+		return "drop"
+	}
+}
+
+// nftAddressMatches returns the address matches of each family, the rule of a family is skipped
+// if the source or destination ips do not contain the family
+func nftAddressMatches(sourceIp, destinationIp string) []string {
+	if sourceIp == "" && destinationIp == "" {
+		return []string{""}
+	}
+	sources, destinations := splitIpsByFamily(sourceIp), splitIpsByFamily(destinationIp)
+	matches := make([]string, 0)
+	for _, family := range []string{"ip", "ip6"} {
+		match := ""
+		if sourceIp != "" {
+			if len(sources[family]) == 0 {
+				continue
+			}
+			match = fmt.Sprintf(" %s saddr { %s }", family, strings.Join(sources[family], ", "))
+		}
+		if destinationIp != "" {
+			if len(destinations[family]) == 0 {
+				continue
+			}
+			match = fmt.Sprintf("%s %s daddr { %s }", match, family, strings.Join(destinations[family], ", "))
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// splitIpsByFamily returns the comma separated ips grouped by the nft family, ip or ip6
+func splitIpsByFamily(ips string) map[string][]string {
+	families := make(map[string][]string)
+	for _, ip := range strings.Split(ips, ",") {
+		ip = strings.TrimSpace(ip)
+		if ip == "" {
+			continue
+		}
+		family := "ip"
+		if strings.Contains(ip, ":") {
+			family = "ip6"
+		}
+		families[family] = append(families[family], ip)
+	}
+	return families
+}
+
+// nftPorts converts the ports of the iptables multiport format, for example 80,8000:8080, to the nft set elements
+func nftPorts(ports string) string {
+	elements := make([]string, 0)
+	for _, port := range strings.Split(ports, ",") {
+		port = strings.TrimSpace(port)
+		if port != "" {
+			elements = append(elements, strings.Replace(port, ":", "-", 1))
+		}
+	}
+	return strings.Join(elements, ", ")
+}
+
+var nftIdentifierRegexp = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// nftChainName returns the chain name of the experiment, for example drop_1a2b3c_input
+func nftChainName(prefix, uid, hook string) string {
+	return fmt.Sprintf("%s_%s_%s", prefix, nftIdentifierRegexp.ReplaceAllString(uid, "_"), hook)
+}
+
+// addNftChains adds the chains with the rules in one nft transaction, so all or none of the rules take effect
+func addNftChains(ctx context.Context, cl spec.Channel, chains []nftChain) *spec.Response {
+	commands := []string{fmt.Sprintf("add table %s", NftTable)}
+	for _, chain := range chains {
+		chainType := chain.chainType
+		if chainType == "" {
+			chainType = "filter"
+		}
+		commands = append(commands, fmt.Sprintf("add chain %s %s { type %s hook %s priority %d; policy accept; }",
+			NftTable, chain.name, chainType, chain.hook, chain.priority))
+		for _, rule := range chain.rules {
+			commands = append(commands, fmt.Sprintf("add rule %s %s %s", NftTable, chain.name, rule))
+		}
+	}
+	response := cl.Run(ctx, "nft", fmt.Sprintf("'%s'", strings.Join(commands, "; ")))
+	if !response.Success {
+		log.Errorf(ctx, "network-nft-addNftChains-add chains err, %s", response.Err)
+		return response
+	}
+	for _, chain := range chains {
+		store.Record(ctx, store.Resource{Kind: store.ResourceNftChain, Value: fmt.Sprintf("%s %s", NftTable, chain.name)})
+	}
+	return response
+}
+
+// deleteNftChains deletes the recorded chains of the experiment, or the chains if nothing is recorded,
+// and deletes the table if no chain is left
+func deleteNftChains(ctx context.Context, cl spec.Channel, chains []nftChain) *spec.Response {
+	names := make([]string, 0)
+	for _, resource := range store.Resources(ctx, store.ResourceNftChain) {
+		names = append(names, resource.Value)
+	}
+	if len(names) == 0 {
+		for _, chain := range chains {
+			names = append(names, fmt.Sprintf("%s %s", NftTable, chain.name))
+		}
+	}
+	commands := make([]string, 0)
+	for _, name := range names {
+		if !cl.Run(ctx, "nft", fmt.Sprintf("list chain %s", name)).Success {
+			log.Warnf(ctx, "network-nft-deleteNftChains-chain %s not found, skip deleting it", name)
+			continue
+		}
+		commands = append(commands, fmt.Sprintf("flush chain %s", name), fmt.Sprintf("delete chain %s", name))
+	}
+	response := spec.Success()
+	if len(commands) > 0 {
+		response = cl.Run(ctx, "nft", fmt.Sprintf("'%s'", strings.Join(commands, "; ")))
+		if !response.Success {
+			log.Errorf(ctx, "network-nft-deleteNftChains-delete chains err, %s", response.Err)
+			return response
+		}
+	}
+	table := cl.Run(ctx, "nft", fmt.Sprintf("list table %s", NftTable))
+	if table.Success && !util.IsNil(table.Result) && !strings.Contains(table.Result.(string), "chain ") {
+		if deleted := cl.Run(ctx, "nft", fmt.Sprintf("delete table %s", NftTable)); !deleted.Success {
+			log.Warnf(ctx, "network-nft-deleteNftChains-delete table %s err, %s", NftTable, deleted.Err)
+		}
+	}
+	return response
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+)
+
+func TestBuildNftDropChains(t *testing.T) {
+	chains := buildNftDropChains("a1-b2", dropRule{
+		destinationIp:   "10.0.0.1,fd00::1",
+		destinationPort: "80,8000:8080",
+		networkTraffic:  "out",
+	})
+	expect := []nftChain{{
+		name: "drop_a1_b2_output",
+		hook: "output",
+		rules: []string{
+			"meta l4proto { tcp, udp } ip daddr { 10.0.0.1 } th dport { 80, 8000-8080 } drop",
+			"meta l4proto { tcp, udp } ip6 daddr { fd00::1 } th dport { 80, 8000-8080 } drop",
+		},
+	}}
+	if !reflect.DeepEqual(chains, expect) {
+		t.Errorf("buildNftDropChains() = %v, want %v", chains, expect)
+	}
+
+	chains = buildNftDropChains("uid", dropRule{sourcePort: "22"})
+	if len(chains) != 2 || chains[0].hook != "input" || chains[1].hook != "output" ||
+		!reflect.DeepEqual(chains[0].rules, []string{"meta l4proto { tcp, udp } th sport { 22 } drop"}) {
+		t.Errorf("buildNftDropChains() = %v, unexpected chains without addresses", chains)
+	}
+
+	chains = buildNftDropChains("uid", dropRule{destinationIp: "10.0.0.1", networkTraffic: "out", protocol: ProtocolAll, mode: ModeRejectRst})
+	expectRules := []string{
+		"meta l4proto { tcp } ip daddr { 10.0.0.1 } reject with tcp reset",
+		"ip daddr { 10.0.0.1 } reject with icmpx type port-unreachable",
+	}
+	if !reflect.DeepEqual(chains[0].rules, expectRules) {
+		t.Errorf("buildNftDropChains() = %v, want %v", chains[0].rules, expectRules)
+	}
+}
+
+func TestNftDropRejectMismatchedFamilies(t *testing.T) {
+	if matches := nftAddressMatches("10.0.0.1", "fd00::1"); len(matches) != 0 {
+		t.Errorf("nftAddressMatches() = %v, want no match", matches)
+	}
+	backend := &nftablesBackend{channel: channel.NewLocalChannel()}
+	response := backend.start(context.Background(), "uid", dropRule{sourceIp: "10.0.0.1,10.0.0.2", destinationIp: "fd00::1"})
+	if response.Success || response.Code != spec.ParameterIllegal.Code {
+		t.Errorf("start() = %+v, want parameter illegal", response)
+	}
+}
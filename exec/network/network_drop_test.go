@@ -0,0 +1,67 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestBuildDropRules(t *testing.T) {
+	tests := []struct {
+		rule   dropRule
+		expect []string
+	}{
+		{
+			rule:   dropRule{destinationPort: "80,81", networkTraffic: "out"},
+			expect: []string{"OUTPUT -p tcp -m multiport --dports 80,81 -j DROP", "OUTPUT -p udp -m multiport --dports 80,81 -j DROP"},
+		},
+		{
+			rule: dropRule{destinationPort: "80", networkTraffic: "out", mode: ModeRejectRst},
+			expect: []string{"OUTPUT -p tcp --dport 80 -j REJECT --reject-with tcp-reset",
+				"OUTPUT -p udp --dport 80 -j REJECT --reject-with icmp-port-unreachable"},
+		},
+		{
+			rule: dropRule{destinationIp: "10.0.0.1", networkTraffic: "out", protocol: ProtocolAll, mode: ModeRejectRst},
+			expect: []string{"OUTPUT -p tcp -d 10.0.0.1 -j REJECT --reject-with tcp-reset",
+				"OUTPUT -d 10.0.0.1 -j REJECT --reject-with icmp-port-unreachable"},
+		},
+		{
+			rule:   dropRule{sourceIp: "10.0.0.1", networkTraffic: "in", protocol: "icmp", mode: ModeRejectIcmp},
+			expect: []string{"INPUT -p icmp -s 10.0.0.1 -j REJECT --reject-with icmp-host-unreachable"},
+		},
+	}
+	for _, tt := range tests {
+		if rules := buildDropRules(tt.rule); !reflect.DeepEqual(rules, tt.expect) {
+			t.Errorf("buildDropRules(%+v) = %v, want %v", tt.rule, rules, tt.expect)
+		}
+	}
+}
+
+func TestValidateDropRule(t *testing.T) {
+	ctx := context.Background()
+	if response := validateDropRule(ctx, dropRule{destinationPort: "80", protocol: "icmp"}); response == nil {
+		t.Errorf("validateDropRule() expected failure for the ports of icmp")
+	}
+	if response := validateDropRule(ctx, dropRule{mode: "reject"}); response == nil {
+		t.Errorf("validateDropRule() expected failure for the illegal mode")
+	}
+	if response := validateDropRule(ctx, dropRule{destinationPort: "80", protocol: "tcp", mode: ModeRejectPortUnreachable}); response != nil {
+		t.Errorf("validateDropRule() unexpected failure, %s", response.Err)
+	}
+}
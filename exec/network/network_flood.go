@@ -23,8 +23,10 @@ import (
 	"github.com/pkg/errors"
 	"github.com/shirou/gopsutil/process"
 	"net"
+	"strconv"
 	"strings"
 
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 )
@@ -140,31 +142,22 @@ func (ns *NetworkFloodExecutor) start(ctx context.Context, iPAddress, duration,
 }
 
 func (ns *NetworkFloodExecutor) flood(ctx context.Context) *spec.Response {
-	response := new(spec.Response)
-	response.Success = true
-	pids, _ := process.Pids()
-	for _, pid := range pids {
-		pn, _ := process.NewProcess(pid)
-		pName, _ := pn.Name()
-		if strings.Contains(pName, "iperf") {
-			proc, err := process.NewProcess(pid)
-			if err != nil {
-				if errors.Is(err, process.ErrorProcessNotRunning) {
-					log.Errorf(ctx, "network-flood-Failed to get iperf process", err.Error())
-					response.Success = false
-					response.Result = fmt.Sprintf("Failed to get iperf process %s", err.Error())
-					return response
-				}
-			}
-			if err := proc.Kill(); err != nil {
-				log.Errorf(ctx, "the iperf process kill failed", err.Error())
-				response.Success = false
-				response.Result = fmt.Sprintf("Failed to get iperf process %s", err.Error())
-				return response
-			}
+	pids := make([]string, 0)
+	processes, err := process.Processes()
+	if err != nil {
+		log.Errorf(ctx, "network-flood-Failed to get iperf process, %s", err.Error())
+		return spec.ReturnFail(spec.OsCmdExecFailed, fmt.Sprintf("Failed to get iperf process %s", err.Error()))
+	}
+	for _, p := range processes {
+		if pName, err := p.Name(); err == nil && strings.Contains(pName, "iperf") {
+			pids = append(pids, strconv.Itoa(int(p.Pid)))
 		}
 	}
-	return response
+	if len(pids) == 0 {
+		return spec.Success()
+	}
+	// the iperf processes run on the host, so they are killed by the host channel
+	return exec.HostChannel(ns.channel).Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
 }
 
 func (ns *NetworkFloodExecutor) SetChannel(channel spec.Channel) {
@@ -201,6 +194,7 @@ func CheckIPs(i string) bool {
 
 	ips := strings.Split(i, ",")
 	for _, ip := range ips {
+		ip = strings.TrimSpace(ip)
 		if !strings.Contains(ip, "/") {
 			if net.ParseIP(ip) == nil {
 				return false
@@ -0,0 +1,436 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/network/tc"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+)
+
+type PartitionActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewPartitionActionSpec() spec.ExpActionCommandSpec {
+	return &PartitionActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "ip",
+					Desc: "The ips or cidrs to isolate, comma separated, for example 10.0.1.0/24,10.0.2.0/24,fd00::/64",
+				},
+				&spec.ExpFlag{
+					Name: "port",
+					Desc: "The remote ports to isolate, comma separated or connector representing ranges, for example 3306,6379-6380. All ports are isolated if not specified",
+				},
+				&spec.ExpFlag{
+					Name: "allow-ip",
+					Desc: "The ips or cidrs still reachable, they take precedence over the isolated ips, for example 10.0.1.5",
+				},
+				&spec.ExpFlag{
+					Name: "allow-port",
+					Desc: "The local or remote ports still reachable, they take precedence over the isolated ports, for example 22",
+				},
+			},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "backend",
+					Desc: "The firewall backend, iptables or nftables. By default nftables is used if the nft command exists and iptables is missing or based on nf_tables, otherwise iptables is used",
+				},
+			},
+			ActionExecutor: &NetworkPartitionExecutor{},
+			ActionExample: `
+# Isolate the node from the database and the cache subnets in both directions, other networks are still reachable
+blade create network partition --ip 10.0.1.0/24,10.0.2.0/24
+
+# Isolate the node from the mysql and redis ports of the subnet, but keep the ssh
+blade create network partition --ip 10.0.0.0/16 --port 3306,6379 --allow-port 22
+
+# Isolate the node from the subnet except the gateway
+blade create network partition --ip 192.168.0.0/24 --allow-ip 192.168.0.1`,
+			ActionPrograms:   []string{DropNetworkBin},
+			ActionCategories: []string{category.SystemNetwork},
+		},
+	}
+}
+
+func (*PartitionActionSpec) Name() string {
+	return "partition"
+}
+
+func (*PartitionActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*PartitionActionSpec) ShortDesc() string {
+	return "Network partition experiment"
+}
+
+func (p *PartitionActionSpec) LongDesc() string {
+	if p.ActionLongDesc != "" {
+		return p.ActionLongDesc
+	}
+	return "Drop the incoming and outgoing packets of the isolated ips and ports, all rules are installed in one transaction and reported in the result"
+}
+
+type NetworkPartitionExecutor struct {
+	channel spec.Channel
+}
+
+func (*NetworkPartitionExecutor) Name() string {
+	return "partition"
+}
+
+// partitionRule is the isolated and allowed ips and ports of the partition
+type partitionRule struct {
+	ips        []string
+	ports      [][]int
+	allowIps   []string
+	allowPorts [][]int
+}
+
+// PartitionResult is the rules installed by the partition experiment
+type PartitionResult struct {
+	Backend string   `json:"backend"`
+	Rules   []string `json:"rules"`
+}
+
+func (pe *NetworkPartitionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	rule, response := parsePartitionRule(ctx, model.ActionFlags)
+	if response != nil {
+		return response
+	}
+	backend, response := selectFirewallBackend(ctx, pe.channel, model.ActionFlags["backend"], false)
+	if response != nil {
+		return response
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return pe.stop(ctx, uid, backend)
+	}
+	if len(rule.ips) == 0 && len(rule.ports) == 0 {
+		log.Errorf(ctx, "network-partition-less params, ip|port")
+		return spec.ResponseFailWithFlags(spec.ParameterLess, "ip|port")
+	}
+	return pe.start(ctx, uid, backend, rule)
+}
+
+func parsePartitionRule(ctx context.Context, flags map[string]string) (partitionRule, *spec.Response) {
+	var rule partitionRule
+	for _, name := range []string{"ip", "allow-ip"} {
+		if !CheckIPs(flags[name]) {
+			log.Errorf(ctx, "`%s`: network-partition-%s is illegal", flags[name], name)
+			return rule, spec.ResponseFailWithFlags(spec.ParameterIllegal, name, flags[name], "it must be comma separated ips or cidrs")
+		}
+	}
+	rule.ips = splitList(flags["ip"])
+	rule.allowIps = splitList(flags["allow-ip"])
+	var err error
+	if flags["port"] != "" {
+		if rule.ports, err = tc.ParseIntegerListToPortRanges("port", flags["port"]); err != nil {
+			log.Errorf(ctx, "`%s`: network-partition-port is illegal, %v", flags["port"], err)
+			return rule, spec.ResponseFailWithFlags(spec.ParameterIllegal, "port", flags["port"], err)
+		}
+	}
+	if flags["allow-port"] != "" {
+		if rule.allowPorts, err = tc.ParseIntegerListToPortRanges("allow-port", flags["allow-port"]); err != nil {
+			log.Errorf(ctx, "`%s`: network-partition-allow-port is illegal, %v", flags["allow-port"], err)
+			return rule, spec.ResponseFailWithFlags(spec.ParameterIllegal, "allow-port", flags["allow-port"], err)
+		}
+	}
+	return rule, nil
+}
+
+func (pe *NetworkPartitionExecutor) start(ctx context.Context, uid, backend string, rule partitionRule) *spec.Response {
+	result := PartitionResult{Backend: backend, Rules: make([]string, 0)}
+	if backend == BackendNftables {
+		chains := buildNftPartitionChains(uid, rule)
+		if response := addNftChains(ctx, pe.channel, chains); !response.Success {
+			return response
+		}
+		for _, chain := range chains {
+			for _, r := range chain.rules {
+				result.Rules = append(result.Rules, fmt.Sprintf("%s %s %s", NftTable, chain.name, r))
+			}
+		}
+		return spec.ReturnSuccess(result)
+	}
+	families := iptablesFamilies(ctx, pe.channel, rule.ips)
+	for _, family := range families {
+		if response, ok := pe.channel.IsAllCommandsAvailable(ctx, []string{iptablesCommands[family] + "-restore"}); !ok {
+			return response
+		}
+	}
+	for _, family := range families {
+		lines := buildIptablesPartitionRules(uid, rule, family)
+		if len(lines) == 0 {
+			continue
+		}
+		command := iptablesCommands[family]
+		if response := restoreIptables(ctx, pe.channel, command, lines); !response.Success {
+			pe.stop(ctx, uid, backend)
+			return response
+		}
+		for _, hook := range partitionHooks {
+			store.Record(ctx, store.Resource{
+				Kind:  store.ResourceIptables,
+				Value: fmt.Sprintf("%s -j %s", hook.iptablesChain, iptablesPartitionChain(uid, hook.suffix)),
+				Attrs: map[string]string{"command": command},
+			})
+		}
+		for _, line := range lines {
+			if strings.HasPrefix(line, "-") {
+				result.Rules = append(result.Rules, fmt.Sprintf("%s %s", command, line))
+			}
+		}
+	}
+	return spec.ReturnSuccess(result)
+}
+
+func (pe *NetworkPartitionExecutor) stop(ctx context.Context, uid, backend string) *spec.Response {
+	if backend == BackendNftables {
+		return deleteNftChains(ctx, pe.channel, buildNftPartitionChains(uid, partitionRule{}))
+	}
+	response := spec.Success()
+	for _, command := range []string{iptablesCommands["ip"], iptablesCommands["ip6"]} {
+		lines := make([]string, 0)
+		for _, hook := range partitionHooks {
+			chain := iptablesPartitionChain(uid, hook.suffix)
+			// the chain does not exist if the family is not isolated
+			if !pe.channel.Run(ctx, command, fmt.Sprintf("-S %s", chain)).Success {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("-D %s -j %s", hook.iptablesChain, chain),
+				fmt.Sprintf("-F %s", chain), fmt.Sprintf("-X %s", chain))
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		if restored := restoreIptables(ctx, pe.channel, command, lines); !restored.Success {
+			response = restored
+		}
+	}
+	return response
+}
+
+func (pe *NetworkPartitionExecutor) SetChannel(channel spec.Channel) {
+	pe.channel = channel
+}
+
+// partitionHook is the packets of a direction, the remote address is the source of the incoming packets
+// and the destination of the outgoing packets
+type partitionHook struct {
+	nftHook       string
+	iptablesChain string
+	suffix        string
+	addressField  string
+	portField     string
+}
+
+var partitionHooks = []partitionHook{
+	{nftHook: "input", iptablesChain: "INPUT", suffix: "IN", addressField: "saddr", portField: "sport"},
+	{nftHook: "output", iptablesChain: "OUTPUT", suffix: "OUT", addressField: "daddr", portField: "dport"},
+}
+
+var iptablesCommands = map[string]string{"ip": "iptables", "ip6": "ip6tables"}
+
+// buildNftPartitionChains returns the input and output chains of the partition, the allowed packets are accepted
+// before the isolated packets are dropped
+func buildNftPartitionChains(uid string, rule partitionRule) []nftChain {
+	ips, allowIps := splitIpsByFamily(strings.Join(rule.ips, ",")), splitIpsByFamily(strings.Join(rule.allowIps, ","))
+	chains := make([]nftChain, 0)
+	for _, hook := range partitionHooks {
+		rules := make([]string, 0)
+		if len(rule.allowPorts) > 0 {
+			ports := formatPortRanges(rule.allowPorts, "-", ", ")
+			rules = append(rules, fmt.Sprintf("meta l4proto { tcp, udp } th sport { %s } accept", ports),
+				fmt.Sprintf("meta l4proto { tcp, udp } th dport { %s } accept", ports))
+		}
+		for _, family := range []string{"ip", "ip6"} {
+			if len(allowIps[family]) > 0 {
+				rules = append(rules, fmt.Sprintf("%s %s { %s } accept", family, hook.addressField, strings.Join(allowIps[family], ", ")))
+			}
+		}
+		ports := ""
+		if len(rule.ports) > 0 {
+			ports = fmt.Sprintf("meta l4proto { tcp, udp } th %s { %s } ", hook.portField, formatPortRanges(rule.ports, "-", ", "))
+		}
+		if len(rule.ips) == 0 && ports != "" {
+			rules = append(rules, fmt.Sprintf("%sdrop", ports))
+		}
+		for _, family := range []string{"ip", "ip6"} {
+			if len(ips[family]) > 0 {
+				rules = append(rules, fmt.Sprintf("%s %s { %s } %sdrop", family, hook.addressField, strings.Join(ips[family], ", "), ports))
+			}
+		}
+		chains = append(chains, nftChain{name: nftChainName("partition", uid, hook.nftHook), hook: hook.nftHook, rules: rules})
+	}
+	return chains
+}
+
+// iptablesFamilies returns the families of the isolated ips, or the families supported by the host
+// if only the ports are isolated
+func iptablesFamilies(ctx context.Context, cl spec.Channel, ips []string) []string {
+	if len(ips) == 0 {
+		if cl.IsCommandAvailable(ctx, "ip6tables-restore") {
+			return []string{"ip", "ip6"}
+		}
+		return []string{"ip"}
+	}
+	families := make([]string, 0)
+	byFamily := splitIpsByFamily(strings.Join(ips, ","))
+	for _, family := range []string{"ip", "ip6"} {
+		if len(byFamily[family]) > 0 {
+			families = append(families, family)
+		}
+	}
+	return families
+}
+
+// iptablesPartitionChain returns the chain of the partition, the length of iptables chain names is limited to 28
+func iptablesPartitionChain(uid, suffix string) string {
+	id := nftIdentifierRegexp.ReplaceAllString(uid, "_")
+	if len(id) > 18 {
+		id = id[:18]
+	}
+	return fmt.Sprintf("PART_%s_%s", id, suffix)
+}
+
+// multiportLimit is the max ports of the iptables multiport match, a range counts as two ports
+const multiportLimit = 15
+
+// buildIptablesPartitionRules returns the iptables-restore lines of the family, the chains of the partition
+// are created and jumped to from the INPUT and OUTPUT chains. The allowed packets return to the INPUT and OUTPUT
+// chains instead of being accepted, so the other rules of the host still apply to them
+func buildIptablesPartitionRules(uid string, rule partitionRule, family string) []string {
+	ips, allowIps := splitIpsByFamily(strings.Join(rule.ips, ",")), splitIpsByFamily(strings.Join(rule.allowIps, ","))
+	if len(rule.ips) > 0 && len(ips[family]) == 0 {
+		return []string{}
+	}
+	lines := []string{"*filter"}
+	for _, hook := range partitionHooks {
+		lines = append(lines, fmt.Sprintf(":%s - [0:0]", iptablesPartitionChain(uid, hook.suffix)))
+	}
+	for _, hook := range partitionHooks {
+		chain := iptablesPartitionChain(uid, hook.suffix)
+		addressFlag := "-d"
+		if hook.addressField == "saddr" {
+			addressFlag = "-s"
+		}
+		for _, protocol := range []string{"tcp", "udp"} {
+			for _, ports := range chunkPortRanges(rule.allowPorts) {
+				lines = append(lines, fmt.Sprintf("-A %s -p %s -m multiport --sports %s -j RETURN", chain, protocol, ports),
+					fmt.Sprintf("-A %s -p %s -m multiport --dports %s -j RETURN", chain, protocol, ports))
+			}
+		}
+		for _, ip := range allowIps[family] {
+			lines = append(lines, fmt.Sprintf("-A %s %s %s -j RETURN", chain, addressFlag, ip))
+		}
+		addresses := []string{""}
+		if len(rule.ips) > 0 {
+			addresses = make([]string, 0)
+			for _, ip := range ips[family] {
+				addresses = append(addresses, fmt.Sprintf(" %s %s", addressFlag, ip))
+			}
+		}
+		for _, address := range addresses {
+			if len(rule.ports) == 0 {
+				lines = append(lines, fmt.Sprintf("-A %s%s -j DROP", chain, address))
+				continue
+			}
+			for _, protocol := range []string{"tcp", "udp"} {
+				for _, ports := range chunkPortRanges(rule.ports) {
+					lines = append(lines, fmt.Sprintf("-A %s%s -p %s -m multiport --%ss %s -j DROP", chain, address, protocol, hook.portField, ports))
+				}
+			}
+		}
+	}
+	for _, hook := range partitionHooks {
+		lines = append(lines, fmt.Sprintf("-I %s 1 -j %s", hook.iptablesChain, iptablesPartitionChain(uid, hook.suffix)))
+	}
+	return append(lines, "COMMIT")
+}
+
+// chunkPortRanges splits the port ranges to the multiport lists, for example 80,8000:8080
+func chunkPortRanges(portRanges [][]int) []string {
+	chunks := make([]string, 0)
+	chunk := make([][]int, 0)
+	count := 0
+	for _, portRange := range portRanges {
+		size := 1
+		if portRange[0] != portRange[1] {
+			size = 2
+		}
+		if count+size > multiportLimit {
+			chunks = append(chunks, formatPortRanges(chunk, ":", ","))
+			chunk, count = make([][]int, 0), 0
+		}
+		chunk = append(chunk, portRange)
+		count += size
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, formatPortRanges(chunk, ":", ","))
+	}
+	return chunks
+}
+
+// formatPortRanges joins the port ranges, the start and end of a range are joined by the connector
+func formatPortRanges(portRanges [][]int, connector, separator string) string {
+	ports := make([]string, 0)
+	for _, portRange := range portRanges {
+		if portRange[0] == portRange[1] {
+			ports = append(ports, fmt.Sprintf("%d", portRange[0]))
+		} else {
+			ports = append(ports, fmt.Sprintf("%d%s%d", portRange[0], connector, portRange[1]))
+		}
+	}
+	return strings.Join(ports, separator)
+}
+
+// restoreIptables commits the lines by iptables-restore without flushing the existing rules,
+// so all or none of the lines take effect
+func restoreIptables(ctx context.Context, cl spec.Channel, command string, lines []string) *spec.Response {
+	if lines[0] != "*filter" {
+		lines = append(append([]string{"*filter"}, lines...), "COMMIT")
+	}
+	args := `'%s\n'`
+	for _, line := range lines {
+		args = fmt.Sprintf("%s '%s'", args, line)
+	}
+	response := cl.Run(ctx, "printf", fmt.Sprintf("%s | %s-restore --noflush", args, command))
+	if !response.Success {
+		log.Errorf(ctx, "network-partition-restoreIptables-%s-restore err, %s", command, response.Err)
+	}
+	return response
+}
+
+// splitList returns the trimmed non-empty elements of the comma separated list
+func splitList(list string) []string {
+	elements := make([]string, 0)
+	for _, element := range strings.Split(list, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
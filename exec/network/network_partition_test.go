@@ -0,0 +1,75 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildNftPartitionChains(t *testing.T) {
+	rule := partitionRule{
+		ips:        []string{"10.0.1.0/24", "fd00::/64"},
+		ports:      [][]int{{3306, 3306}, {6379, 6380}},
+		allowIps:   []string{"10.0.1.5"},
+		allowPorts: [][]int{{22, 22}},
+	}
+	chains := buildNftPartitionChains("u1", rule)
+	if len(chains) != 2 || chains[0].name != "partition_u1_input" || chains[1].name != "partition_u1_output" {
+		t.Fatalf("buildNftPartitionChains() = %v, unexpected chains", chains)
+	}
+	expect := []string{
+		"meta l4proto { tcp, udp } th sport { 22 } accept",
+		"meta l4proto { tcp, udp } th dport { 22 } accept",
+		"ip daddr { 10.0.1.5 } accept",
+		"ip daddr { 10.0.1.0/24 } meta l4proto { tcp, udp } th dport { 3306, 6379-6380 } drop",
+		"ip6 daddr { fd00::/64 } meta l4proto { tcp, udp } th dport { 3306, 6379-6380 } drop",
+	}
+	if !reflect.DeepEqual(chains[1].rules, expect) {
+		t.Errorf("buildNftPartitionChains() output rules = %v, want %v", chains[1].rules, expect)
+	}
+}
+
+func TestBuildIptablesPartitionRules(t *testing.T) {
+	rule := partitionRule{ips: []string{"10.0.1.0/24"}, allowIps: []string{"10.0.1.5"}}
+	expect := []string{
+		"*filter",
+		":PART_u1_IN - [0:0]",
+		":PART_u1_OUT - [0:0]",
+		"-A PART_u1_IN -s 10.0.1.5 -j RETURN",
+		"-A PART_u1_IN -s 10.0.1.0/24 -j DROP",
+		"-A PART_u1_OUT -d 10.0.1.5 -j RETURN",
+		"-A PART_u1_OUT -d 10.0.1.0/24 -j DROP",
+		"-I INPUT 1 -j PART_u1_IN",
+		"-I OUTPUT 1 -j PART_u1_OUT",
+		"COMMIT",
+	}
+	if lines := buildIptablesPartitionRules("u1", rule, "ip"); !reflect.DeepEqual(lines, expect) {
+		t.Errorf("buildIptablesPartitionRules() = %v, want %v", lines, expect)
+	}
+	if lines := buildIptablesPartitionRules("u1", rule, "ip6"); len(lines) != 0 {
+		t.Errorf("buildIptablesPartitionRules() = %v, want no ip6 rules", lines)
+	}
+}
+
+func TestChunkPortRanges(t *testing.T) {
+	ranges := [][]int{{1, 1}, {2, 3}, {4, 5}, {6, 7}, {8, 9}, {10, 11}, {12, 13}, {14, 15}, {16, 16}}
+	expect := []string{"1,2:3,4:5,6:7,8:9,10:11,12:13,14:15", "16"}
+	if chunks := chunkPortRanges(ranges); !reflect.DeepEqual(chunks, expect) {
+		t.Errorf("chunkPortRanges() = %v, want %v", chunks, expect)
+	}
+}
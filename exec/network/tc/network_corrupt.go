@@ -88,7 +88,7 @@ func (ce *NetworkCorruptExecutor) Exec(uid string, ctx context.Context, model *s
 		return spec.ResponseFailWithFlags(spec.ParameterLess, "interface")
 	}
 	if _, ok := spec.IsDestroy(ctx); ok {
-		return ce.stop(netInterface, ctx)
+		return ce.stop(netInterface, model.ActionFlags["direction"], ctx)
 	} else {
 		percent := model.ActionFlags["percent"]
 		if percent == "" {
@@ -102,21 +102,22 @@ func (ce *NetworkCorruptExecutor) Exec(uid string, ctx context.Context, model *s
 		excludeIp := model.ActionFlags["exclude-ip"]
 		ignorePeerPort := model.ActionFlags["ignore-peer-port"] == "true"
 		protocol := model.ActionFlags["protocol"]
+		direction := model.ActionFlags["direction"]
 		force := model.ActionFlags["force"] == "true"
-		return ce.start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, percent, ignorePeerPort, force, protocol, ctx)
+		return ce.start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, direction, percent, ignorePeerPort, force, protocol, ctx)
 	}
 }
 
-func (ce *NetworkCorruptExecutor) start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, percent string,
+func (ce *NetworkCorruptExecutor) start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, direction, percent string,
 	ignorePeerPort, force bool, protocol string, ctx context.Context) *spec.Response {
 
 	classRule := fmt.Sprintf("netem corrupt %s%%", percent)
 
-	return startNet(ctx, netInterface, classRule, localPort, remotePort, excludePort, destIp, excludeIp, force, ignorePeerPort, protocol, ce.channel)
+	return startNet(ctx, netInterface, classRule, localPort, remotePort, excludePort, destIp, excludeIp, direction, force, ignorePeerPort, protocol, ce.channel)
 }
 
-func (ce *NetworkCorruptExecutor) stop(netInterface string, ctx context.Context) *spec.Response {
-	return stopNet(ctx, netInterface, ce.channel)
+func (ce *NetworkCorruptExecutor) stop(netInterface, direction string, ctx context.Context) *spec.Response {
+	return stopNet(ctx, netInterface, direction, ce.channel)
 }
 
 func (ce *NetworkCorruptExecutor) SetChannel(channel spec.Channel) {
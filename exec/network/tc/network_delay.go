@@ -52,7 +52,10 @@ blade create network delay --time 3000 --offset 1000 --interface eth0 --local-po
 blade create network delay --time 3000 --interface eth0 --remote-port 80 --destination-ip 14.215.177.39
 
 # Do a 5 second delay for the entire network card eth0, excluding ports 22 and 8000 to 8080
-blade create network delay --time 5000 --interface eth0 --exclude-port 22,8000-8080`,
+blade create network delay --time 5000 --interface eth0 --exclude-port 22,8000-8080
+
+# The responses from the remote service 10.0.0.2:3306 are delayed by 1 second
+blade create network delay --time 1000 --interface eth0 --remote-port 3306 --destination-ip 10.0.0.2 --direction ingress`,
 			ActionPrograms:   []string{TcNetworkBin},
 			ActionCategories: []string{category.SystemNetwork},
 		},
@@ -98,7 +101,7 @@ func (de *NetworkDelayExecutor) Exec(uid string, ctx context.Context, model *spe
 		return spec.ResponseFailWithFlags(spec.ParameterLess, "interface")
 	}
 	if _, ok := spec.IsDestroy(ctx); ok {
-		return de.stop(netInterface, ctx)
+		return de.stop(netInterface, model.ActionFlags["direction"], ctx)
 	} else {
 		time := model.ActionFlags["time"]
 		if time == "" {
@@ -116,21 +119,22 @@ func (de *NetworkDelayExecutor) Exec(uid string, ctx context.Context, model *spe
 		excludeIp := model.ActionFlags["exclude-ip"]
 		ignorePeerPort := model.ActionFlags["ignore-peer-port"] == "true"
 		protocol := model.ActionFlags["protocol"]
+		direction := model.ActionFlags["direction"]
 		force := model.ActionFlags["force"] == "true"
-		return de.start(localPort, remotePort, excludePort, destIp, excludeIp, time, offset, netInterface, ignorePeerPort, force, protocol, ctx)
+		return de.start(localPort, remotePort, excludePort, destIp, excludeIp, direction, time, offset, netInterface, ignorePeerPort, force, protocol, ctx)
 	}
 }
 
-func (de *NetworkDelayExecutor) start(localPort, remotePort, excludePort, destIp, excludeIp, time, offset, netInterface string,
+func (de *NetworkDelayExecutor) start(localPort, remotePort, excludePort, destIp, excludeIp, direction, time, offset, netInterface string,
 	ignorePeerPort, force bool, protocol string, ctx context.Context) *spec.Response {
 
 	classRule := fmt.Sprintf("netem delay %sms %sms", time, offset)
-	return startNet(ctx, netInterface, classRule, localPort, remotePort, excludePort, destIp, excludeIp, force, ignorePeerPort, protocol, de.channel)
+	return startNet(ctx, netInterface, classRule, localPort, remotePort, excludePort, destIp, excludeIp, direction, force, ignorePeerPort, protocol, de.channel)
 
 }
 
-func (de *NetworkDelayExecutor) stop(netInterface string, ctx context.Context) *spec.Response {
-	return stopNet(ctx, netInterface, de.channel)
+func (de *NetworkDelayExecutor) stop(netInterface, direction string, ctx context.Context) *spec.Response {
+	return stopNet(ctx, netInterface, direction, de.channel)
 }
 
 func (de *NetworkDelayExecutor) SetChannel(channel spec.Channel) {
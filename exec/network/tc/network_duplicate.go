@@ -88,7 +88,7 @@ func (de *NetworkDuplicateExecutor) Exec(uid string, ctx context.Context, model
 		return spec.ResponseFailWithFlags(spec.ParameterLess, "interface")
 	}
 	if _, ok := spec.IsDestroy(ctx); ok {
-		return de.stop(netInterface, ctx)
+		return de.stop(netInterface, model.ActionFlags["direction"], ctx)
 	} else {
 		percent := model.ActionFlags["percent"]
 		if percent == "" {
@@ -102,22 +102,23 @@ func (de *NetworkDuplicateExecutor) Exec(uid string, ctx context.Context, model
 		excludeIp := model.ActionFlags["exclude-ip"]
 		ignorePeerPort := model.ActionFlags["ignore-peer-port"] == "true"
 		protocol := model.ActionFlags["protocol"]
+		direction := model.ActionFlags["direction"]
 		force := model.ActionFlags["force"] == "true"
-		return de.start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, percent, ignorePeerPort, force, protocol, ctx)
+		return de.start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, direction, percent, ignorePeerPort, force, protocol, ctx)
 	}
 }
 
-func (de *NetworkDuplicateExecutor) start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, percent string,
+func (de *NetworkDuplicateExecutor) start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, direction, percent string,
 	ignorePeerPort, force bool, protocol string, ctx context.Context) *spec.Response {
 
 	classRule := fmt.Sprintf("netem duplicate %s%%", percent)
 
-	return startNet(ctx, netInterface, classRule, localPort, remotePort, excludePort, destIp, excludeIp, force, ignorePeerPort, protocol, de.channel)
+	return startNet(ctx, netInterface, classRule, localPort, remotePort, excludePort, destIp, excludeIp, direction, force, ignorePeerPort, protocol, de.channel)
 
 }
 
-func (de *NetworkDuplicateExecutor) stop(netInterface string, ctx context.Context) *spec.Response {
-	return stopNet(ctx, netInterface, de.channel)
+func (de *NetworkDuplicateExecutor) stop(netInterface, direction string, ctx context.Context) *spec.Response {
+	return stopNet(ctx, netInterface, direction, de.channel)
 }
 
 func (de *NetworkDuplicateExecutor) SetChannel(channel spec.Channel) {
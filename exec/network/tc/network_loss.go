@@ -100,7 +100,7 @@ func (nle *NetworkLossExecutor) Exec(uid string, ctx context.Context, model *spe
 		dev = netInterface
 	}
 	if _, ok := spec.IsDestroy(ctx); ok {
-		return nle.stop(dev, ctx)
+		return nle.stop(dev, model.ActionFlags["direction"], ctx)
 	}
 	percent := model.ActionFlags["percent"]
 	if percent == "" {
@@ -114,19 +114,20 @@ func (nle *NetworkLossExecutor) Exec(uid string, ctx context.Context, model *spe
 	excludeIp := model.ActionFlags["exclude-ip"]
 	ignorePeerPort := model.ActionFlags["ignore-peer-port"] == "true"
 	protocol := model.ActionFlags["protocol"]
+	direction := model.ActionFlags["direction"]
 	force := model.ActionFlags["force"] == "true"
-	return nle.start(dev, localPort, remotePort, excludePort, destIp, excludeIp, percent, ignorePeerPort, force, protocol, ctx)
+	return nle.start(dev, localPort, remotePort, excludePort, destIp, excludeIp, direction, percent, ignorePeerPort, force, protocol, ctx)
 }
 
-func (nle *NetworkLossExecutor) start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, percent string,
+func (nle *NetworkLossExecutor) start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, direction, percent string,
 	ignorePeerPort, force bool, protocol string, ctx context.Context) *spec.Response {
 	classRule := fmt.Sprintf("netem loss %s%%", percent)
-	return startNet(ctx, netInterface, classRule, localPort, remotePort, excludePort, destIp, excludeIp, force, ignorePeerPort, protocol, nle.channel)
+	return startNet(ctx, netInterface, classRule, localPort, remotePort, excludePort, destIp, excludeIp, direction, force, ignorePeerPort, protocol, nle.channel)
 
 }
 
-func (nle *NetworkLossExecutor) stop(netInterface string, ctx context.Context) *spec.Response {
-	return stopNet(ctx, netInterface, nle.channel)
+func (nle *NetworkLossExecutor) stop(netInterface, direction string, ctx context.Context) *spec.Response {
+	return stopNet(ctx, netInterface, direction, nle.channel)
 }
 
 func (nle *NetworkLossExecutor) SetChannel(channel spec.Channel) {
@@ -0,0 +1,223 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tc
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+)
+
+type RateActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewRateActionSpec() spec.ExpActionCommandSpec {
+	return &RateActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: commFlags,
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name:     "rate",
+					Desc:     "Bandwidth rate, the unit is bit, kbit, mbit, gbit, bps, kbps, mbps or gbps, for example 1mbit",
+					Required: true,
+				},
+				&spec.ExpFlag{
+					Name: "burst",
+					Desc: "Bucket size in bytes, the unit is b, kb, mb or gb, default value is the bytes sent in 10ms at the rate and not less than 1600",
+				},
+				&spec.ExpFlag{
+					Name: "latency",
+					Desc: "Max time a packet can sit in the bucket, ms, default value is 50. It can not be specified with the limit flag",
+				},
+				&spec.ExpFlag{
+					Name: "limit",
+					Desc: "Max bytes queued waiting for tokens, the unit is b, kb, mb or gb. It can not be specified with the latency flag",
+				},
+			},
+			ActionExecutor: &NetworkRateExecutor{},
+			ActionExample: `
+# Limit the bandwidth of the entire network card eth0 to 10mbit
+blade create network rate --rate 10mbit --interface eth0
+
+# Limit the bandwidth of accessing the 10.0.0.0/24 port 3306 to 1mbit, other traffic is untouched
+blade create network rate --rate 1mbit --interface eth0 --remote-port 3306 --destination-ip 10.0.0.0/24
+
+# Limit the download bandwidth from 10.0.0.2 to 512kbit with a 64kb bucket
+blade create network rate --rate 512kbit --burst 64kb --interface eth0 --destination-ip 10.0.0.2 --direction ingress`,
+			ActionPrograms:   []string{TcNetworkBin},
+			ActionCategories: []string{category.SystemNetwork},
+		},
+	}
+}
+
+func (*RateActionSpec) Name() string {
+	return "rate"
+}
+
+func (*RateActionSpec) Aliases() []string {
+	return []string{"bandwidth"}
+}
+
+func (*RateActionSpec) ShortDesc() string {
+	return "Limit network bandwidth"
+}
+
+func (r *RateActionSpec) LongDesc() string {
+	if r.ActionLongDesc != "" {
+		return r.ActionLongDesc
+	}
+	return "Limit network bandwidth by the tbf qdisc. If only the exclude-port or exclude-ip flag is specified, each of the three default bands is limited to the rate"
+}
+
+type NetworkRateExecutor struct {
+	channel spec.Channel
+}
+
+func (*NetworkRateExecutor) Name() string {
+	return "rate"
+}
+
+func (nre *NetworkRateExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	commands := []string{"tc", "head"}
+	if response, ok := nre.channel.IsAllCommandsAvailable(ctx, commands); !ok {
+		return response
+	}
+
+	netInterface := model.ActionFlags["interface"]
+	if netInterface == "" {
+		log.Errorf(ctx, "network-rate-exec-interface is nil")
+		return spec.ResponseFailWithFlags(spec.ParameterLess, "interface")
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return nre.stop(netInterface, model.ActionFlags["direction"], ctx)
+	}
+	rate := model.ActionFlags["rate"]
+	if rate == "" {
+		log.Errorf(ctx, "network-rate-exec-rate is nil")
+		return spec.ResponseFailWithFlags(spec.ParameterLess, "rate")
+	}
+	classRule, response := buildTbfClassRule(ctx, rate, model.ActionFlags["burst"], model.ActionFlags["latency"], model.ActionFlags["limit"])
+	if response != nil {
+		return response
+	}
+	localPort := model.ActionFlags["local-port"]
+	remotePort := model.ActionFlags["remote-port"]
+	excludePort := model.ActionFlags["exclude-port"]
+	destIp := model.ActionFlags["destination-ip"]
+	excludeIp := model.ActionFlags["exclude-ip"]
+	ignorePeerPort := model.ActionFlags["ignore-peer-port"] == "true"
+	protocol := model.ActionFlags["protocol"]
+	direction := model.ActionFlags["direction"]
+	force := model.ActionFlags["force"] == "true"
+	return nre.start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, direction, classRule, ignorePeerPort, force, protocol, ctx)
+}
+
+func (nre *NetworkRateExecutor) start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, direction, classRule string,
+	ignorePeerPort, force bool, protocol string, ctx context.Context) *spec.Response {
+	return startNet(ctx, netInterface, classRule, localPort, remotePort, excludePort, destIp, excludeIp, direction, force, ignorePeerPort, protocol, nre.channel)
+}
+
+func (nre *NetworkRateExecutor) stop(netInterface, direction string, ctx context.Context) *spec.Response {
+	return stopNet(ctx, netInterface, direction, nre.channel)
+}
+
+func (nre *NetworkRateExecutor) SetChannel(channel spec.Channel) {
+	nre.channel = channel
+}
+
+var rateRegexp = regexp.MustCompile(`^(\d+(?:\.\d+)?)(bit|kbit|mbit|gbit|bps|kbps|mbps|gbps)?$`)
+var sizeRegexp = regexp.MustCompile(`^(\d+)(b|kb|mb|gb)?$`)
+
+var rateUnits = map[string]float64{
+	"": 1, "bit": 1, "kbit": 1e3, "mbit": 1e6, "gbit": 1e9,
+	"bps": 8, "kbps": 8e3, "mbps": 8e6, "gbps": 8e9,
+}
+
+var sizeUnits = map[string]int64{"": 1, "b": 1, "kb": 1 << 10, "mb": 1 << 20, "gb": 1 << 30}
+
+// minBurst is the min bucket size, a bucket smaller than the mtu can not send any packet
+const minBurst = 1600
+
+// buildTbfClassRule returns the tbf qdisc of the flags, for example tbf rate 1mbit burst 12500 latency 50ms
+func buildTbfClassRule(ctx context.Context, rate, burst, latency, limit string) (string, *spec.Response) {
+	rate = strings.ToLower(rate)
+	match := rateRegexp.FindStringSubmatch(rate)
+	if match == nil {
+		log.Errorf(ctx, "`%s`: network-rate-rate is illegal", rate)
+		return "", spec.ResponseFailWithFlags(spec.ParameterIllegal, "rate", rate, "it must be a number with the bit, kbit, mbit, gbit, bps, kbps, mbps or gbps unit")
+	}
+	value, _ := strconv.ParseFloat(match[1], 64)
+	bitsPerSecond := value * rateUnits[match[2]]
+	if bitsPerSecond <= 0 {
+		log.Errorf(ctx, "`%s`: network-rate-rate is illegal", rate)
+		return "", spec.ResponseFailWithFlags(spec.ParameterIllegal, "rate", rate, "it must be greater than 0")
+	}
+
+	burstBytes := int64(bitsPerSecond / 8 / 100)
+	if burstBytes < minBurst {
+		burstBytes = minBurst
+	}
+	if burst != "" {
+		var err error
+		if burstBytes, err = parseSize(burst); err != nil || burstBytes <= 0 {
+			log.Errorf(ctx, "`%s`: network-rate-burst is illegal", burst)
+			return "", spec.ResponseFailWithFlags(spec.ParameterIllegal, "burst", burst, "it must be a positive integer with the b, kb, mb or gb unit")
+		}
+	}
+	classRule := fmt.Sprintf("tbf rate %s burst %d", rate, burstBytes)
+
+	if latency != "" && limit != "" {
+		log.Errorf(ctx, "network-rate-latency and limit are both specified")
+		return "", spec.ResponseFailWithFlags(spec.ParameterIllegal, "latency", latency, "it can not be specified with the limit flag")
+	}
+	if limit != "" {
+		limitBytes, err := parseSize(limit)
+		if err != nil || limitBytes <= 0 {
+			log.Errorf(ctx, "`%s`: network-rate-limit is illegal", limit)
+			return "", spec.ResponseFailWithFlags(spec.ParameterIllegal, "limit", limit, "it must be a positive integer with the b, kb, mb or gb unit")
+		}
+		return fmt.Sprintf("%s limit %d", classRule, limitBytes), nil
+	}
+	if latency == "" {
+		latency = "50"
+	}
+	if ms, err := strconv.Atoi(latency); err != nil || ms <= 0 {
+		log.Errorf(ctx, "`%s`: network-rate-latency is illegal", latency)
+		return "", spec.ResponseFailWithFlags(spec.ParameterIllegal, "latency", latency, "it must be a positive integer")
+	}
+	return fmt.Sprintf("%s latency %sms", classRule, latency), nil
+}
+
+// parseSize returns the bytes of the size with the b, kb, mb or gb unit
+func parseSize(size string) (int64, error) {
+	match := sizeRegexp.FindStringSubmatch(strings.ToLower(size))
+	if match == nil {
+		return 0, fmt.Errorf("illegal size %s", size)
+	}
+	value, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return value * sizeUnits[match[2]], nil
+}
@@ -102,7 +102,7 @@ func (ce *NetworkReorderExecutor) Exec(uid string, ctx context.Context, model *s
 		return spec.ResponseFailWithFlags(spec.ParameterLess, "interface")
 	}
 	if _, ok := spec.IsDestroy(ctx); ok {
-		return ce.stop(netInterface, ctx)
+		return ce.stop(netInterface, model.ActionFlags["direction"], ctx)
 	} else {
 		percent := model.ActionFlags["percent"]
 		if percent == "" {
@@ -125,13 +125,14 @@ func (ce *NetworkReorderExecutor) Exec(uid string, ctx context.Context, model *s
 		excludeIp := model.ActionFlags["exclude-ip"]
 		ignorePeerPort := model.ActionFlags["ignore-peer-port"] == "true"
 		protocol := model.ActionFlags["protocol"]
+		direction := model.ActionFlags["direction"]
 		force := model.ActionFlags["force"] == "true"
-		return ce.start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, percent,
+		return ce.start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, direction, percent,
 			ignorePeerPort, gap, time, correlation, force, protocol, ctx)
 	}
 }
 
-func (ce *NetworkReorderExecutor) start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, percent string,
+func (ce *NetworkReorderExecutor) start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, direction, percent string,
 	ignorePeerPort bool, gap, time, correlation string, force bool, protocol string, ctx context.Context) *spec.Response {
 
 	classRule := fmt.Sprintf("netem reorder %s%% %s%%", percent, correlation)
@@ -140,12 +141,12 @@ func (ce *NetworkReorderExecutor) start(netInterface, localPort, remotePort, exc
 	}
 	classRule = fmt.Sprintf("%s delay %sms", classRule, time)
 
-	return startNet(ctx, netInterface, classRule, localPort, remotePort, excludePort, destIp, excludeIp, force, ignorePeerPort, protocol, ce.channel)
+	return startNet(ctx, netInterface, classRule, localPort, remotePort, excludePort, destIp, excludeIp, direction, force, ignorePeerPort, protocol, ce.channel)
 
 }
 
-func (ce *NetworkReorderExecutor) stop(netInterface string, ctx context.Context) *spec.Response {
-	return stopNet(ctx, netInterface, ce.channel)
+func (ce *NetworkReorderExecutor) stop(netInterface, direction string, ctx context.Context) *spec.Response {
+	return stopNet(ctx, netInterface, direction, ce.channel)
 }
 
 func (ce *NetworkReorderExecutor) SetChannel(channel spec.Channel) {
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"math"
 	"math/bits"
+	"net"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
 	"github.com/chaosblade-io/chaosblade-spec-go/log"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 	"github.com/chaosblade-io/chaosblade-spec-go/util"
@@ -34,7 +36,7 @@ var commFlags = []spec.ExpFlagSpec{
 	},
 	&spec.ExpFlag{
 		Name: "destination-ip",
-		Desc: "destination ip. Support for using mask to specify the ip range such as 92.168.1.0/24 or comma separated multiple ips, for example 10.0.0.1,11.0.0.1.",
+		Desc: "destination ip. Support for using mask to specify the ip range such as 92.168.1.0/24 or comma separated multiple ips, for example 10.0.0.1,11.0.0.1. IPv6 addresses and ranges such as fd00::/64 are supported",
 	},
 	&spec.ExpFlag{
 		Name:   "ignore-peer-port",
@@ -49,12 +51,16 @@ var commFlags = []spec.ExpFlagSpec{
 	},
 	&spec.ExpFlag{
 		Name: "exclude-ip",
-		Desc: "Exclude ips. Support for using mask to specify the ip range such as 92.168.1.0/24 or comma separated multiple ips, for example 10.0.0.1,11.0.0.1",
+		Desc: "Exclude ips. Support for using mask to specify the ip range such as 92.168.1.0/24 or comma separated multiple ips, for example 10.0.0.1,11.0.0.1. IPv6 addresses and ranges are supported",
 	},
 	&spec.ExpFlag{
 		Name: "protocol",
 		Desc: "specify protocol for example tcp udp icmp ",
 	},
+	&spec.ExpFlag{
+		Name: "direction",
+		Desc: "The direction of the packets, egress, ingress or both, default value is egress. The ingress packets are redirected to an ifb device",
+	},
 	&spec.ExpFlag{
 		Name:   "force",
 		Desc:   "Forcibly overwrites the original rules",
@@ -64,7 +70,19 @@ var commFlags = []spec.ExpFlagSpec{
 
 const delimiter = ","
 
-func startNet(ctx context.Context, netInterface, classRule, localPort, remotePort, excludePort, destIp, excludeIp string, force, ignorePeerPorts bool, protocol string, cl spec.Channel) *spec.Response {
+func startNet(ctx context.Context, netInterface, classRule, localPort, remotePort, excludePort, destIp, excludeIp, direction string, force, ignorePeerPorts bool, protocol string, cl spec.Channel) *spec.Response {
+	switch direction {
+	case "":
+		direction = DirectionEgress
+	case DirectionEgress, DirectionIngress, DirectionBoth:
+	default:
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "direction", direction, "it must be egress, ingress or both")
+	}
+	if direction != DirectionEgress {
+		if response, ok := cl.IsAllCommandsAvailable(ctx, []string{"ip"}); !ok {
+			return response
+		}
+	}
 	if protocol != "" {
 		switch protocol {
 		case "tcp":
@@ -80,13 +98,13 @@ func startNet(ctx context.Context, netInterface, classRule, localPort, remotePor
 	var localPortRanges, remotePortRanges, excludePortRanges [][]int
 	var err error
 	if localPort != "" {
-		localPortRanges, err = parseIntegerListToPortRanges("local-port", localPort)
+		localPortRanges, err = ParseIntegerListToPortRanges("local-port", localPort)
 		if err != nil {
 			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "local-port", localPort, err)
 		}
 	}
 	if remotePort != "" {
-		remotePortRanges, err = parseIntegerListToPortRanges("remote-port", remotePort)
+		remotePortRanges, err = ParseIntegerListToPortRanges("remote-port", remotePort)
 		if err != nil {
 			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "remote-port", remotePort, err)
 		}
@@ -112,36 +130,77 @@ func startNet(ctx context.Context, netInterface, classRule, localPort, remotePor
 			excludeIp = channelIps
 		}
 	}
+	destIpRules, err := getIpRules(destIp)
+	if err != nil {
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "destination-ip", destIp, err)
+	}
+	excludeIpRules, err := getIpRules(excludeIp)
+	if err != nil {
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "exclude-ip", excludeIp, err)
+	}
 	if force {
-		stopNet(ctx, netInterface, cl)
+		stopNet(ctx, netInterface, direction, cl)
+	}
+	if direction != DirectionIngress {
+		response = addNetem(ctx, netInterface, classRule, localPortRanges, remotePortRanges, excludePortRanges,
+			destIpRules, excludeIpRules, protocol, cl)
+		if !response.Success || direction == DirectionEgress {
+			return response
+		}
+	}
+	// the incoming packets are redirected to the ifb device, on which the local port is the destination port
+	// and the destination ip is the source address
+	ifb, response := addIngressRedirect(ctx, netInterface, cl)
+	if !response.Success {
+		stopNet(ctx, netInterface, direction, cl)
+		return response
+	}
+	response = addNetem(ctx, ifb, classRule, remotePortRanges, localPortRanges, excludePortRanges,
+		toIngressIpRules(destIpRules), toIngressIpRules(excludeIpRules), protocol, cl)
+	if !response.Success {
+		stopNet(ctx, netInterface, direction, cl)
 	}
+	return response
+}
+
+// addNetem adds the class rule to the root qdisc of the device with the filters
+func addNetem(ctx context.Context, netInterface, classRule string, localPortRanges, remotePortRanges, excludePortRanges [][]int,
+	destIpRules, excludeIpRules []string, protocol string, cl spec.Channel) *spec.Response {
 	// Only interface flag
-	if localPort == "" && remotePort == "" && excludePort == "" && destIp == "" && excludeIp == "" && protocol == "" {
-		return cl.Run(ctx, "tc", fmt.Sprintf(`qdisc add dev %s root %s`, netInterface, classRule))
+	if len(localPortRanges) == 0 && len(remotePortRanges) == 0 && len(excludePortRanges) == 0 &&
+		len(destIpRules) == 0 && len(excludeIpRules) == 0 && protocol == "" {
+		response := cl.Run(ctx, "tc", fmt.Sprintf(`qdisc add dev %s root %s`, netInterface, classRule))
+		if response.Success {
+			recordRootQdisc(ctx, netInterface, cl)
+		}
+		return response
 	}
 
-	response = addQdiscForDL(cl, ctx, netInterface)
+	response := addQdiscForDL(cl, ctx, netInterface)
+	if !response.Success {
+		return response
+	}
 
 	// only contains excludePort or excludeIP
-	if localPort == "" && remotePort == "" && destIp == "" && protocol == "" {
+	if len(localPortRanges) == 0 && len(remotePortRanges) == 0 && len(destIpRules) == 0 && protocol == "" {
 		// Add class rule to 1,2,3 band, exclude port and exclude ip are added to 4 band
 		args := buildNetemToDefaultBandsArgs(netInterface, classRule)
-		excludeFilters := buildExcludeFilterToNewBand(netInterface, excludePortRanges, excludeIp)
+		excludeFilters := buildExcludeFilterToNewBand(netInterface, excludePortRanges, excludeIpRules)
 		response := cl.Run(ctx, "tc", args+excludeFilters)
 		if !response.Success {
-			stopNet(ctx, netInterface, cl)
+			stopRootQdisc(ctx, netInterface, cl)
+			return response
 		}
+		recordRootQdisc(ctx, netInterface, cl)
 		return response
 	}
-	destIpRules := getIpRules(destIp)
-	excludeIpRules := getIpRules(excludeIp)
 	// local port or remote port
 	return executeTargetPortAndIpWithExclude(ctx, cl, netInterface, classRule, localPortRanges, remotePortRanges, destIpRules,
 		excludePortRanges, excludeIpRules, protocol)
 }
 
 func getExcludePortRanges(ctx context.Context, excludePort string, ignorePeerPorts bool, cl spec.Channel) ([][]int, error) {
-	excludePortRanges, err := parseIntegerListToPortRanges("exclude-port", excludePort)
+	excludePortRanges, err := ParseIntegerListToPortRanges("exclude-port", excludePort)
 	if err != nil {
 		return [][]int{}, err
 	}
@@ -174,24 +233,27 @@ func getExcludePortRanges(ctx context.Context, excludePort string, ignorePeerPor
 	return portSetToPortRanges(portSet), nil
 }
 
-func buildExcludeFilterToNewBand(netInterface string, excludePortRanges [][]int, excludeIp string) string {
+func buildExcludeFilterToNewBand(netInterface string, excludePortRanges [][]int, excludeIpRules []string) string {
 	var args string
-	excludeIpRules := getIpRules(excludeIp)
 	for _, rule := range excludeIpRules {
+		family := ruleFamily(rule)
 		args = fmt.Sprintf(
 			`%s && \
-			tc filter add dev %s parent 1: prio 4 protocol ip u32 %s flowid 1:4`,
-			args, netInterface, rule)
+			tc filter add dev %s parent 1: prio %d protocol %s u32 %s flowid 1:4`,
+			args, netInterface, filterPrio(4, family), filterProtocol(family), rule)
 	}
 
 	for _, portRange := range excludePortRanges {
 		masks := buildMaskForRange(portRange[0], portRange[1])
 		for _, mask := range masks {
-			args = fmt.Sprintf(
-				`%s && \
-                tc filter add dev %s parent 1: prio 4 protocol ip u32 match ip dport %d %#x flowid 1:4 && \
-                tc filter add dev %s parent 1: prio 4 protocol ip u32 match ip sport %d %#x flowid 1:4`,
-				args, netInterface, mask[0], mask[1], netInterface, mask[0], mask[1])
+			for _, family := range ipFamilies {
+				args = fmt.Sprintf(
+					`%s && \
+                tc filter add dev %s parent 1: prio %d protocol %s u32 match %s dport %d %#x flowid 1:4 && \
+                tc filter add dev %s parent 1: prio %d protocol %s u32 match %s sport %d %#x flowid 1:4`,
+					args, netInterface, filterPrio(4, family), filterProtocol(family), family, mask[0], mask[1],
+					netInterface, filterPrio(4, family), filterProtocol(family), family, mask[0], mask[1])
+			}
 		}
 	}
 	return args
@@ -243,20 +305,96 @@ func preHandleTxqueue(ctx context.Context, netInterface string, cl spec.Channel)
 	return spec.ReturnSuccess("success")
 }
 
-func getIpRules(targetIp string) []string {
+const (
+	ipv4Family = "ip"
+	ipv6Family = "ip6"
+	// ipv6PrioOffset separates the ipv6 filters from the ipv4 filters, tc rejects filters with
+	// the same prio but different protocols
+	ipv6PrioOffset = 2
+)
+
+var ipFamilies = []string{ipv4Family, ipv6Family}
+
+// getIpRules returns the u32 dst matches of the comma separated ips or cidrs, for example
+// match ip dst 10.0.0.1 or match ip6 dst fd00::/64
+func getIpRules(targetIp string) ([]string, error) {
 	if targetIp == "" {
-		return []string{}
+		return []string{}, nil
 	}
 	ipString := strings.TrimSpace(targetIp)
 	ips := strings.Split(ipString, delimiter)
 	ipRules := make([]string, 0)
 	for _, ip := range ips {
-		if strings.TrimSpace(ip) == "" {
+		ip = strings.TrimSpace(ip)
+		if ip == "" {
 			continue
 		}
-		ipRules = append(ipRules, fmt.Sprintf("match ip dst %s", ip))
+		addr := net.ParseIP(ip)
+		if strings.Contains(ip, "/") {
+			var err error
+			if addr, _, err = net.ParseCIDR(ip); err != nil {
+				return nil, err
+			}
+		}
+		if addr == nil {
+			return nil, fmt.Errorf("%s is not a valid ip", ip)
+		}
+		family := ipv4Family
+		if addr.To4() == nil {
+			family = ipv6Family
+		}
+		ipRules = append(ipRules, fmt.Sprintf("match %s dst %s", family, ip))
+	}
+	return ipRules, nil
+}
+
+// ruleFamily returns the address family of the ip rule
+func ruleFamily(rule string) string {
+	if strings.HasPrefix(rule, fmt.Sprintf("match %s ", ipv6Family)) {
+		return ipv6Family
+	}
+	return ipv4Family
+}
+
+// rulesFamilies returns the address families of the ip rules, both families if there is no rule
+func rulesFamilies(rules []string) []string {
+	if len(rules) == 0 {
+		return ipFamilies
+	}
+	families := make([]string, 0)
+	for _, family := range ipFamilies {
+		for _, rule := range rules {
+			if ruleFamily(rule) == family {
+				families = append(families, family)
+				break
+			}
+		}
+	}
+	return families
+}
+
+// filterProtocol returns the protocol of the tc filter for the address family
+func filterProtocol(family string) string {
+	if family == ipv6Family {
+		return "ipv6"
 	}
-	return ipRules
+	return "ip"
+}
+
+// filterPrio returns the prio of the tc filter for the address family
+func filterPrio(prio int, family string) int {
+	if family == ipv6Family {
+		return prio + ipv6PrioOffset
+	}
+	return prio
+}
+
+func buildProtocolRule(family, protocol string) string {
+	if protocol == "" {
+		return ""
+	}
+	return fmt.Sprintf(` \
+                                         match %s protocol %s 0xff`, family, protocol)
 }
 
 // executeTargetPortAndIpWithExclude creates class rule in 1:4 queue and add filter to the queue
@@ -266,64 +404,50 @@ func executeTargetPortAndIpWithExclude(ctx context.Context, channel spec.Channel
 	args = buildTargetFilterPortAndIp(localPortRanges, remotePortRanges, destIpRules, excludePorts, excludeIpRules, args, netInterface, protocol)
 	response := channel.Run(ctx, "tc", args)
 	if !response.Success {
-		stopNet(ctx, netInterface, channel)
+		stopRootQdisc(ctx, netInterface, channel)
 		return response
 	}
+	recordRootQdisc(ctx, netInterface, channel)
 	return response
 }
 
 func buildTargetFilterPortAndIp(localPortRanges, remotePortRanges [][]int, destIpRules []string, excludePortRanges [][]int,
 	excludeIpRules []string, args string, netInterface string, protocol string) string {
-	protocolrule := ""
 	if protocol != "" {
 		if len(localPortRanges) == 0 && len(remotePortRanges) == 0 && len(destIpRules) == 0 && len(excludePortRanges) == 0 && len(excludeIpRules) == 0 {
-			args = fmt.Sprintf(
-				`%s && \
-                tc filter add dev %s parent 1: prio 4 protocol ip u32 match ip protocol %s 0xff flowid 1:4`,
-				args, netInterface, protocol)
+			for _, family := range ipFamilies {
+				args = fmt.Sprintf(
+					`%s && \
+                tc filter add dev %s parent 1: prio %d protocol %s u32 match %s protocol %s 0xff flowid 1:4`,
+					args, netInterface, filterPrio(4, family), filterProtocol(family), family, protocol)
+			}
 			return args
-		} else {
-			protocolrule = fmt.Sprintf(` \
-                                         match ip protocol %s 0xff`, protocol)
 		}
 	}
-	if len(localPortRanges) > 0 {
-		for _, localPortRange := range localPortRanges {
-			masks := buildMaskForRange(localPortRange[0], localPortRange[1])
+	// the port filters without destination ip match both address families
+	families := rulesFamilies(destIpRules)
+	portRanges := map[string][][]int{"sport": localPortRanges, "dport": remotePortRanges}
+	for _, direction := range []string{"sport", "dport"} {
+		for _, portRange := range portRanges[direction] {
+			masks := buildMaskForRange(portRange[0], portRange[1])
 			for _, mask := range masks {
 				if len(destIpRules) > 0 {
 					for _, ipRule := range destIpRules {
+						family := ruleFamily(ipRule)
 						args = fmt.Sprintf(
 							`%s && \
-                            tc filter add dev %s parent 1: prio 4 protocol ip u32 %s match ip sport %d %#x %s flowid 1:4`,
-							args, netInterface, ipRule, mask[0], mask[1], protocolrule)
+                            tc filter add dev %s parent 1: prio %d protocol %s u32 %s match %s %s %d %#x %s flowid 1:4`,
+							args, netInterface, filterPrio(4, family), filterProtocol(family), ipRule, family, direction,
+							mask[0], mask[1], buildProtocolRule(family, protocol))
 					}
 				} else {
-					args = fmt.Sprintf(
-						`%s && \
-                        tc filter add dev %s parent 1: prio 4 protocol ip u32 match ip sport %d %#x %s flowid 1:4`,
-						args, netInterface, mask[0], mask[1], protocolrule)
-				}
-			}
-		}
-	}
-
-	if len(remotePortRanges) > 0 {
-		for _, remotePortRange := range remotePortRanges {
-			masks := buildMaskForRange(remotePortRange[0], remotePortRange[1])
-			for _, mask := range masks {
-				if len(destIpRules) > 0 {
-					for _, ipRule := range destIpRules {
+					for _, family := range families {
 						args = fmt.Sprintf(
 							`%s && \
-                            tc filter add dev %s parent 1: prio 4 protocol ip u32 %s match ip dport %d %#x %s flowid 1:4`,
-							args, netInterface, ipRule, mask[0], mask[1], protocolrule)
+                        tc filter add dev %s parent 1: prio %d protocol %s u32 match %s %s %d %#x %s flowid 1:4`,
+							args, netInterface, filterPrio(4, family), filterProtocol(family), family, direction,
+							mask[0], mask[1], buildProtocolRule(family, protocol))
 					}
-				} else {
-					args = fmt.Sprintf(
-						`%s && \
-                        tc filter add dev %s parent 1: prio 4 protocol ip u32 match ip dport %d %#x %s flowid 1:4`,
-						args, netInterface, mask[0], mask[1], protocolrule)
 				}
 			}
 		}
@@ -331,30 +455,33 @@ func buildTargetFilterPortAndIp(localPortRanges, remotePortRanges [][]int, destI
 	if len(localPortRanges) == 0 && len(remotePortRanges) == 0 {
 		// only destIp
 		for _, ipRule := range destIpRules {
+			family := ruleFamily(ipRule)
 			args = fmt.Sprintf(
 				`%s && \
-				tc filter add dev %s parent 1: prio 4 protocol ip u32 %s %s flowid 1:4`,
-				args, netInterface, ipRule, protocolrule)
+				tc filter add dev %s parent 1: prio %d protocol %s u32 %s %s flowid 1:4`,
+				args, netInterface, filterPrio(4, family), filterProtocol(family), ipRule, buildProtocolRule(family, protocol))
 		}
 	}
-	if len(excludeIpRules) > 0 {
-		for _, ipRule := range excludeIpRules {
-			args = fmt.Sprintf(
-				`%s && \
-				tc filter add dev %s parent 1: prio 3 protocol ip u32 %s %s flowid 1:3`,
-				args, netInterface, ipRule, protocolrule)
-		}
+	for _, ipRule := range excludeIpRules {
+		family := ruleFamily(ipRule)
+		args = fmt.Sprintf(
+			`%s && \
+				tc filter add dev %s parent 1: prio %d protocol %s u32 %s %s flowid 1:3`,
+			args, netInterface, filterPrio(3, family), filterProtocol(family), ipRule, buildProtocolRule(family, protocol))
 	}
 
-	if len(excludePortRanges) > 0 {
-		for _, excludePortRange := range excludePortRanges {
-			masks := buildMaskForRange(excludePortRange[0], excludePortRange[1])
-			for _, mask := range masks {
+	// only the families of the targets reach the 1:4 band, so the other family needs no exclusion
+	for _, excludePortRange := range excludePortRanges {
+		masks := buildMaskForRange(excludePortRange[0], excludePortRange[1])
+		for _, mask := range masks {
+			for _, family := range families {
+				protocolRule := buildProtocolRule(family, protocol)
 				args = fmt.Sprintf(
 					`%s && \
-                    tc filter add dev %s parent 1: prio 3 protocol ip u32 match ip dport %d %#x %s flowid 1:3 && \
-                    tc filter add dev %s parent 1: prio 3 protocol ip u32 match ip sport %d %#x %s flowid 1:3`,
-					args, netInterface, mask[0], mask[1], protocolrule, netInterface, mask[0], mask[1], protocolrule)
+                    tc filter add dev %s parent 1: prio %d protocol %s u32 match %s dport %d %#x %s flowid 1:3 && \
+                    tc filter add dev %s parent 1: prio %d protocol %s u32 match %s sport %d %#x %s flowid 1:3`,
+					args, netInterface, filterPrio(3, family), filterProtocol(family), family, mask[0], mask[1], protocolRule,
+					netInterface, filterPrio(3, family), filterProtocol(family), family, mask[0], mask[1], protocolRule)
 			}
 		}
 	}
@@ -368,20 +495,83 @@ func addQdiscForDL(channel spec.Channel, ctx context.Context, netInterface strin
 }
 
 // stopNet
-func stopNet(ctx context.Context, netInterface string, cl spec.Channel) *spec.Response {
+func stopNet(ctx context.Context, netInterface, direction string, cl spec.Channel) *spec.Response {
 	if os.Getuid() != 0 {
 		return spec.ReturnFail(spec.Forbidden, fmt.Sprintf("tc no permission"))
 	}
-	response := cl.Run(ctx, "tc", fmt.Sprintf(`filter show dev %s parent 1: prio 4`, netInterface))
-	if response.Success && response.Result != "" {
-		response = cl.Run(ctx, "tc", fmt.Sprintf(`filter del dev %s parent 1: prio 4`, netInterface))
-		if !response.Success {
-			log.Errorf(ctx, "network-tc-stopNet-tc del filter err, %s", response.Err)
+	response := spec.Success()
+	if direction != DirectionIngress {
+		response = stopRootQdisc(ctx, netInterface, cl)
+	}
+	if direction == DirectionIngress || direction == DirectionBoth {
+		if ingressResponse := removeIngressRedirect(ctx, netInterface, cl); !ingressResponse.Success {
+			response = ingressResponse
+		}
+	}
+	return response
+}
+
+// stopRootQdisc deletes the root qdisc of the device created by the experiment
+func stopRootQdisc(ctx context.Context, netInterface string, cl spec.Channel) *spec.Response {
+	if handle := getRecordedRootQdisc(ctx, netInterface); handle != "" {
+		if current := getRootQdiscHandle(ctx, netInterface, cl); current != handle {
+			log.Warnf(ctx, "network-tc-stopNet-the root qdisc of %s is `%s`, not `%s` created by the experiment, skip deleting it",
+				netInterface, current, handle)
+			return spec.Success()
+		}
+	}
+	for _, family := range ipFamilies {
+		prio := filterPrio(4, family)
+		response := cl.Run(ctx, "tc", fmt.Sprintf(`filter show dev %s parent 1: prio %d`, netInterface, prio))
+		if response.Success && response.Result != "" {
+			response = cl.Run(ctx, "tc", fmt.Sprintf(`filter del dev %s parent 1: prio %d`, netInterface, prio))
+			if !response.Success {
+				log.Errorf(ctx, "network-tc-stopNet-tc del filter err, %s", response.Err)
+			}
 		}
 	}
 	return cl.Run(ctx, "tc", fmt.Sprintf(`qdisc del dev %s root`, netInterface))
 }
 
+// recordRootQdisc records the root qdisc handle created by the experiment
+func recordRootQdisc(ctx context.Context, netInterface string, cl spec.Channel) {
+	handle := getRootQdiscHandle(ctx, netInterface, cl)
+	if handle == "" {
+		return
+	}
+	store.Record(ctx, store.Resource{
+		Kind:  store.ResourceQdisc,
+		Value: netInterface,
+		Attrs: map[string]string{"parent": "root", "handle": handle},
+	})
+}
+
+// getRecordedRootQdisc returns the root qdisc handle of the interface recorded by the experiment
+func getRecordedRootQdisc(ctx context.Context, netInterface string) string {
+	for _, resource := range store.Resources(ctx, store.ResourceQdisc) {
+		if resource.Value == netInterface && resource.Attrs["parent"] == "root" {
+			return resource.Attrs["handle"]
+		}
+	}
+	return ""
+}
+
+// getRootQdiscHandle returns the handle of the root qdisc, for example 1: or 8001:
+func getRootQdiscHandle(ctx context.Context, netInterface string, cl spec.Channel) string {
+	response := cl.Run(ctx, "tc", fmt.Sprintf(`qdisc show dev %s`, netInterface))
+	if !response.Success || util.IsNil(response.Result) {
+		return ""
+	}
+	// qdisc netem 8001: root refcnt 2 limit 1000 delay 3s
+	for _, line := range strings.Split(response.Result.(string), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 3 && fields[0] == "qdisc" && fields[3] == "root" {
+			return fields[2]
+		}
+	}
+	return ""
+}
+
 // getPeerPorts returns all ports communicating with the port
 func getPeerPorts(ctx context.Context, port string, cl spec.Channel) ([]int, error) {
 	if !cl.IsCommandAvailable(ctx, "ss") {
@@ -431,7 +621,8 @@ func getPeerPorts(ctx context.Context, port string, cl spec.Channel) ([]int, err
 	return mappingPorts, nil
 }
 
-func parseIntegerListToPortRanges(flagName string, flagValue string) ([][]int, error) {
+// ParseIntegerListToPortRanges returns the sorted and merged port ranges of the ports, for example 80,8000-8080
+func ParseIntegerListToPortRanges(flagName string, flagValue string) ([][]int, error) {
 	dedup := make(map[int]interface{})
 	commaParts := strings.Split(flagValue, ",")
 	for _, part := range commaParts {
@@ -0,0 +1,122 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tc
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/dryrun"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+)
+
+const (
+	DirectionEgress  = "egress"
+	DirectionIngress = "ingress"
+	DirectionBoth    = "both"
+)
+
+// the handle of the ingress qdisc, tc shows its parent as ffff:fff1
+const (
+	ingressHandle = "ffff:"
+	ingressParent = "ffff:fff1"
+)
+
+// ifbName returns the name of the ifb device for the interface, the length of interface names is limited to 15
+func ifbName(netInterface string) string {
+	name := fmt.Sprintf("ifb-%s", netInterface)
+	if len(name) > 15 {
+		name = fmt.Sprintf("ifb-%08x", crc32.ChecksumIEEE([]byte(netInterface)))
+	}
+	return name
+}
+
+// addIngressRedirect redirects the incoming packets of the interface to the ifb device by the ingress qdisc
+// and a mirred action, and returns the ifb device name
+func addIngressRedirect(ctx context.Context, netInterface string, cl spec.Channel) (string, *spec.Response) {
+	ifb := ifbName(netInterface)
+	if !exec.CheckFilepathExists(ctx, cl, fmt.Sprintf("/sys/class/net/%s", ifb)) {
+		response := cl.Run(ctx, "ip", fmt.Sprintf(`link add %s type ifb`, ifb))
+		if !response.Success {
+			log.Errorf(ctx, "network-tc-addIngressRedirect-add ifb device %s err, %s", ifb, response.Err)
+			return ifb, response
+		}
+	}
+	response := cl.Run(ctx, "ip", fmt.Sprintf(`link set dev %s up`, ifb))
+	if !response.Success {
+		log.Errorf(ctx, "network-tc-addIngressRedirect-set ifb device %s up err, %s", ifb, response.Err)
+		return ifb, response
+	}
+	response = cl.Run(ctx, "tc", fmt.Sprintf(`qdisc add dev %s handle %s ingress`, netInterface, ingressHandle))
+	if !response.Success {
+		log.Errorf(ctx, "network-tc-addIngressRedirect-add ingress qdisc for %s err, %s", netInterface, response.Err)
+		return ifb, response
+	}
+	store.Record(ctx, store.Resource{
+		Kind:  store.ResourceQdisc,
+		Value: netInterface,
+		Attrs: map[string]string{"parent": ingressParent, "handle": ingressHandle},
+	})
+	response = cl.Run(ctx, "tc", fmt.Sprintf(
+		`filter add dev %s parent %s protocol all u32 match u32 0 0 action mirred egress redirect dev %s`,
+		netInterface, ingressHandle, ifb))
+	if !response.Success {
+		log.Errorf(ctx, "network-tc-addIngressRedirect-redirect %s to %s err, %s", netInterface, ifb, response.Err)
+	}
+	return ifb, response
+}
+
+// removeIngressRedirect deletes the ingress qdisc redirecting to the ifb device and the ifb device,
+// the ingress qdisc without the redirect is not created by the experiment, so it is kept
+func removeIngressRedirect(ctx context.Context, netInterface string, cl spec.Channel) *spec.Response {
+	ifb := ifbName(netInterface)
+	// the redirect is not created by dry-run, so its removal is always recorded
+	_, dryRun := cl.(*dryrun.RecordChannel)
+	response := cl.Run(ctx, "tc", fmt.Sprintf(`filter show dev %s parent %s`, netInterface, ingressHandle))
+	if dryRun || response.Success && !util.IsNil(response.Result) && strings.Contains(response.Result.(string), ifb) {
+		response = cl.Run(ctx, "tc", fmt.Sprintf(`qdisc del dev %s ingress`, netInterface))
+		if !response.Success {
+			log.Errorf(ctx, "network-tc-removeIngressRedirect-del ingress qdisc for %s err, %s", netInterface, response.Err)
+			return response
+		}
+	} else {
+		log.Warnf(ctx, "network-tc-removeIngressRedirect-no redirect to %s on %s, skip deleting the ingress qdisc", ifb, netInterface)
+	}
+	if !dryRun && !exec.CheckFilepathExists(ctx, cl, fmt.Sprintf("/sys/class/net/%s", ifb)) {
+		return spec.Success()
+	}
+	response = cl.Run(ctx, "ip", fmt.Sprintf(`link del %s`, ifb))
+	if !response.Success {
+		log.Errorf(ctx, "network-tc-removeIngressRedirect-del ifb device %s err, %s", ifb, response.Err)
+	}
+	return response
+}
+
+// toIngressIpRules matches the source address of the incoming packets instead of the destination address
+func toIngressIpRules(ipRules []string) []string {
+	rules := make([]string, 0, len(ipRules))
+	for _, rule := range ipRules {
+		rules = append(rules, strings.Replace(rule, " dst ", " src ", 1))
+	}
+	return rules
+}
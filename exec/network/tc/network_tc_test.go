@@ -1,9 +1,13 @@
 package tc
 
 import (
+	"context"
 	"math/rand"
 	"reflect"
 	"testing"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/dryrun"
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
 )
 
 type buildtargetfilterparam = struct {
@@ -20,9 +24,9 @@ type buildtargetfilterparam = struct {
 	expect string
 }
 
-func TestbuildTargetFilterPortAndIp(t *testing.T) {
+func TestBuildTargetFilterPortAndIp(t *testing.T) {
 	var tests []buildtargetfilterparam
-	var test1, test2 buildtargetfilterparam
+	var test1, test2, test3 buildtargetfilterparam
 	test1.input.remotePortRanges = append(test1.input.remotePortRanges, []int{6000, 9000})
 	test1.input.destIpRules = append(test1.input.destIpRules, "match ip dst 10.18.2.156")
 	test1.input.args = "qdisc add dev ens33 parent 1:4 handle 40: netem delay 200ms 0ms"
@@ -39,7 +43,14 @@ func TestbuildTargetFilterPortAndIp(t *testing.T) {
 	test2.input.args = "qdisc add dev ens33 parent 1:4 handle 40: netem delay 200ms 0ms"
 	test2.input.netInterface = "ens33"
 	test2.expect = "qdisc add dev ens33 parent 1:4 handle 40: netem delay 200ms 0ms && \\\n                            tc filter add dev ens33 parent 1: prio 4 protocol ip u32 match ip dst 10.18.2.50 match ip sport 6000 0xfffe  \\\n                                         match ip protocol 17 0xff flowid 1:4 && \\\n                            tc filter add dev ens33 parent 1: prio 4 protocol ip u32 match ip dst 10.18.2.50 match ip dport 7000 0xfff8  \\\n                                         match ip protocol 17 0xff flowid 1:4 && \\\n                            tc filter add dev ens33 parent 1: prio 4 protocol ip u32 match ip dst 10.18.2.50 match ip dport 7008 0xfffe  \\\n                                         match ip protocol 17 0xff flowid 1:4 && \\\n                            tc filter add dev ens33 parent 1: prio 4 protocol ip u32 match ip dst 10.18.2.50 match ip dport 7010 0xffff  \\\n                                         match ip protocol 17 0xff flowid 1:4 && \\\n\t\t\t\ttc filter add dev ens33 parent 1: prio 3 protocol ip u32 match ip dst 10.18.1.138  \\\n                                         match ip protocol 17 0xff flowid 1:3 && \\\n                    tc filter add dev ens33 parent 1: prio 3 protocol ip u32 match ip dport 7005 0xffff  \\\n                                         match ip protocol 17 0xff flowid 1:3 && \\\n                    tc filter add dev ens33 parent 1: prio 3 protocol ip u32 match ip sport 7005 0xffff  \\\n                                         match ip protocol 17 0xff flowid 1:3 && \\\n                    tc filter add dev ens33 parent 1: prio 3 protocol ip u32 match ip dport 7006 0xfffe  \\\n                                         match ip protocol 17 0xff flowid 1:3 && \\\n                    tc filter add dev ens33 parent 1: prio 3 protocol ip u32 match ip sport 7006 0xfffe  \\\n                                         match ip protocol 17 0xff flowid 1:3 && \\\n                    tc filter add dev ens33 parent 1: prio 3 protocol ip u32 match ip dport 7008 0xfffe  \\\n                                         match ip protocol 17 0xff flowid 1:3 && \\\n                    tc filter add dev ens33 parent 1: prio 3 protocol ip u32 match ip sport 7008 0xfffe  \\\n                                         match ip protocol 17 0xff flowid 1:3 && \\\n                    tc filter add dev ens33 parent 1: prio 3 protocol ip u32 match ip dport 7010 0xffff  \\\n                                         match ip protocol 17 0xff flowid 1:3 && \\\n                    tc filter add dev ens33 parent 1: prio 3 protocol ip u32 match ip sport 7010 0xffff  \\\n                                         match ip protocol 17 0xff flowid 1:3"
-	tests = append(tests, test1, test2)
+	test3.input.remotePortRanges = append(test3.input.remotePortRanges, []int{80, 80})
+	test3.input.destIpRules = append(test3.input.destIpRules, "match ip dst 10.0.0.1", "match ip6 dst fd00::/64")
+	test3.input.excludePortRanges = append(test3.input.excludePortRanges, []int{22, 22})
+	test3.input.protocol = "6"
+	test3.input.args = "qdisc add dev eth0 parent 1:4 handle 40: netem delay 10ms"
+	test3.input.netInterface = "eth0"
+	test3.expect = "qdisc add dev eth0 parent 1:4 handle 40: netem delay 10ms && \\\n                            tc filter add dev eth0 parent 1: prio 4 protocol ip u32 match ip dst 10.0.0.1 match ip dport 80 0xffff  \\\n                                         match ip protocol 6 0xff flowid 1:4 && \\\n                            tc filter add dev eth0 parent 1: prio 6 protocol ipv6 u32 match ip6 dst fd00::/64 match ip6 dport 80 0xffff  \\\n                                         match ip6 protocol 6 0xff flowid 1:4 && \\\n                    tc filter add dev eth0 parent 1: prio 3 protocol ip u32 match ip dport 22 0xffff  \\\n                                         match ip protocol 6 0xff flowid 1:3 && \\\n                    tc filter add dev eth0 parent 1: prio 3 protocol ip u32 match ip sport 22 0xffff  \\\n                                         match ip protocol 6 0xff flowid 1:3 && \\\n                    tc filter add dev eth0 parent 1: prio 5 protocol ipv6 u32 match ip6 dport 22 0xffff  \\\n                                         match ip6 protocol 6 0xff flowid 1:3 && \\\n                    tc filter add dev eth0 parent 1: prio 5 protocol ipv6 u32 match ip6 sport 22 0xffff  \\\n                                         match ip6 protocol 6 0xff flowid 1:3"
+	tests = append(tests, test1, test2, test3)
 
 	for _, tt := range tests {
 		//localPort, remotePort string, destIpRules, excludePorts, excludeIpRules []string, args string, netInterface, protocol string
@@ -50,6 +61,20 @@ func TestbuildTargetFilterPortAndIp(t *testing.T) {
 	}
 }
 
+func TestGetIpRules(t *testing.T) {
+	rules, err := getIpRules("10.0.0.1, 192.168.0.0/16,fd00::1,2001:db8::/32")
+	if err != nil {
+		t.Fatalf("getIpRules() err, %v", err)
+	}
+	expect := []string{"match ip dst 10.0.0.1", "match ip dst 192.168.0.0/16", "match ip6 dst fd00::1", "match ip6 dst 2001:db8::/32"}
+	if !reflect.DeepEqual(rules, expect) {
+		t.Errorf("getIpRules() = %v, want %v", rules, expect)
+	}
+	if _, err := getIpRules("10.0.0.256"); err == nil {
+		t.Errorf("getIpRules() expected err for illegal ip")
+	}
+}
+
 func TestBuildMaskForRange(t *testing.T) {
 	start := rand.Int31n(65535)
 	end := rand.Int31n(65535)
@@ -133,3 +158,47 @@ func isMatch(masks [][]uint16, target uint16) bool {
 	}
 	return false
 }
+
+func TestBuildTbfClassRule(t *testing.T) {
+	tests := []struct {
+		rate, burst, latency, limit string
+		want                        string
+		fail                        bool
+	}{
+		{rate: "1mbit", want: "tbf rate 1mbit burst 1600 latency 50ms"},
+		{rate: "100mbit", want: "tbf rate 100mbit burst 125000 latency 50ms"},
+		{rate: "512kbit", burst: "64kb", latency: "100", want: "tbf rate 512kbit burst 65536 latency 100ms"},
+		{rate: "1MBps", limit: "1mb", want: "tbf rate 1mbps burst 10000 limit 1048576"},
+		{rate: "fast", fail: true},
+		{rate: "1mbit", latency: "50", limit: "1mb", fail: true},
+	}
+	for _, tt := range tests {
+		got, response := buildTbfClassRule(context.Background(), tt.rate, tt.burst, tt.latency, tt.limit)
+		if tt.fail {
+			if response == nil {
+				t.Errorf("buildTbfClassRule(%s) expected failure, got %s", tt.rate, got)
+			}
+			continue
+		}
+		if response != nil || got != tt.want {
+			t.Errorf("buildTbfClassRule(%s) = %s, want %s", tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveIngressRedirectDryRun(t *testing.T) {
+	recorder := dryrun.NewRecordChannel(channel.NewLocalChannel())
+	if response := removeIngressRedirect(context.Background(), "chaos-none0", recorder); !response.Success {
+		t.Fatalf("removeIngressRedirect() fails in dry-run, %s", response.Err)
+	}
+	expect := []string{"tc qdisc del dev chaos-none0 ingress", "ip link del ifb-chaos-none0"}
+	operations := recorder.Operations()
+	if len(operations) != len(expect) {
+		t.Fatalf("dry-run records %+v, want %v", operations, expect)
+	}
+	for i, operation := range operations {
+		if operation.Command != expect[i] {
+			t.Errorf("operation %d is %s, want %s", i, operation.Command, expect[i])
+		}
+	}
+}
@@ -19,6 +19,7 @@ package process
 import (
 	"context"
 	"fmt"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
 	"github.com/chaosblade-io/chaosblade-spec-go/channel"
 	"github.com/chaosblade-io/chaosblade-spec-go/log"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
@@ -45,6 +46,10 @@ func NewProcessCommandModelSpec() spec.ExpModelCommandSpec {
 				NewKillProcessActionCommandSpec(),
 				NewStopProcessActionCommandSpec(),
 				NewProcessLoadActionCommandSpec(),
+				NewCrashLoopProcessActionCommandSpec(),
+				NewFdExhaustProcessActionCommandSpec(),
+				NewLimitProcessActionCommandSpec(),
+				NewScheduleProcessActionCommandSpec(),
 			},
 		},
 	}
@@ -198,3 +203,32 @@ func checkProcessInvalid(ctx context.Context, process, processCmd, localPorts, p
 	}
 	return nil
 }
+
+// recordPids records the space separated pids returned by getPids to the experiment.
+// The pids in other pid namespace cannot be verified on the host, so they are not recorded.
+func recordPids(ctx context.Context, pids string) {
+	if ctx.Value(channel.NSPidFlagName) == spec.True {
+		return
+	}
+	for _, pid := range strings.Fields(pids) {
+		p, err := strconv.Atoi(pid)
+		if err != nil {
+			continue
+		}
+		store.RecordPid(ctx, p)
+	}
+}
+
+// recordProcessResource records the resource changed on the process with the attrs. The start time of the
+// process is kept to detect pid reuse, unless the pid is in other pid namespace.
+func recordProcessResource(ctx context.Context, kind, pid string, attrs map[string]string) {
+	resource := store.Resource{Kind: kind, Value: pid, Attrs: attrs}
+	if ctx.Value(channel.NSPidFlagName) != spec.True {
+		if p, err := strconv.Atoi(pid); err == nil {
+			if createTime, ok := store.NewProcessResource(kind, p).Attrs["createTime"]; ok {
+				resource.Attrs["createTime"] = createTime
+			}
+		}
+	}
+	store.Record(ctx, resource)
+}
@@ -0,0 +1,312 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+)
+
+const CrashLoopProcessBin = "chaos_crashloopprocess"
+
+// crashLoopPollInterval is the interval of looking for the target processes
+const crashLoopPollInterval = 500 * time.Millisecond
+
+type CrashLoopProcessActionCommandSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewCrashLoopProcessActionCommandSpec() spec.ExpActionCommandSpec {
+	return &CrashLoopProcessActionCommandSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "process",
+					Desc: "Process name, Separate multiple process with commas (,)",
+				},
+				&spec.ExpFlag{
+					Name: "process-cmd",
+					Desc: "Process name in command",
+				},
+				&spec.ExpFlag{
+					Name: "local-port",
+					Desc: "Local service ports. Separate multiple ports with commas (,) or connector representing ranges, for example: 80,8000-8080",
+				},
+				&spec.ExpFlag{
+					Name: "service",
+					Desc: "Systemd service name, the main process of the service is killed",
+				},
+				&spec.ExpFlag{
+					Name: "exclude-process",
+					Desc: "Exclude process",
+				},
+			},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name:    "signal",
+					Desc:    "Killing process signal, such as 9,15",
+					Default: "9",
+				},
+				&spec.ExpFlag{
+					Name: "interval",
+					Desc: "The interval between the kills, the target is killed as soon as it reappears after the interval, such as 10s or 10",
+				},
+				&spec.ExpFlag{
+					Name: "uptime",
+					Desc: "The target is killed after it has been running for the uptime, such as 30s or 30",
+				},
+				&spec.ExpFlag{
+					Name: "iterations",
+					Desc: "The number of the kills, the experiment ends after the last kill, 0 or not set means until destroyed",
+				},
+			},
+			ActionExecutor: &CrashLoopProcessExecutor{},
+			ActionExample: `
+# Kill the nginx process every 10 seconds until the experiment is destroyed
+blade create process crashloop --process nginx --interval 10s
+
+# Kill the main process of the demo service 5 times, each time after it has been running for 30 seconds
+blade create process crashloop --service demo --uptime 30s --iterations 5
+
+# Terminate the process listening on the port 8080 as soon as it restarts
+blade create process crashloop --local-port 8080 --signal 15 --interval 1s`,
+			ActionPrograms:    []string{CrashLoopProcessBin},
+			ActionCategories:  []string{category.SystemProcess},
+			ActionProcessHang: true,
+		},
+	}
+}
+
+func (*CrashLoopProcessActionCommandSpec) Name() string {
+	return "crashloop"
+}
+
+func (*CrashLoopProcessActionCommandSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*CrashLoopProcessActionCommandSpec) ShortDesc() string {
+	return "Kill process repeatedly"
+}
+
+func (c *CrashLoopProcessActionCommandSpec) LongDesc() string {
+	if c.ActionLongDesc != "" {
+		return c.ActionLongDesc
+	}
+	return "Kill the process repeatedly as it restarts, to verify the backoff of the supervisors and the alerting under flapping. " +
+		"Each process is signaled once, the kills are recorded in the experiment status"
+}
+
+func (*CrashLoopProcessActionCommandSpec) Categories() []string {
+	return []string{category.SystemProcess}
+}
+
+// crashLoopKill is a kill of the target processes
+type crashLoopKill struct {
+	Iteration int    `json:"iteration"`
+	Pids      string `json:"pids"`
+	Signal    string `json:"signal"`
+	Time      string `json:"time"`
+}
+
+type CrashLoopProcessExecutor struct {
+	channel spec.Channel
+}
+
+func (cle *CrashLoopProcessExecutor) Name() string {
+	return "crashloop"
+}
+
+func (cle *CrashLoopProcessExecutor) SetChannel(channel spec.Channel) {
+	cle.channel = channel
+}
+
+func (cle *CrashLoopProcessExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if _, ok := spec.IsDestroy(ctx); ok {
+		ctx = context.WithValue(ctx, "bin", CrashLoopProcessBin)
+		return exec.Destroy(ctx, cle.channel, "process crashloop")
+	}
+	signal := model.ActionFlags["signal"]
+	if signal == "" {
+		signal = "9"
+	}
+	interval, err := exec.ParseDuration(model.ActionFlags["interval"])
+	if err != nil {
+		log.Errorf(ctx, "`%s`: process-crashloop-interval is illegal, %v", model.ActionFlags["interval"], err)
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "interval", model.ActionFlags["interval"], err)
+	}
+	uptime, err := exec.ParseDuration(model.ActionFlags["uptime"])
+	if err != nil {
+		log.Errorf(ctx, "`%s`: process-crashloop-uptime is illegal, %v", model.ActionFlags["uptime"], err)
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "uptime", model.ActionFlags["uptime"], err)
+	}
+	if interval == 0 && uptime == 0 {
+		log.Errorf(ctx, "process-crashloop-less interval or uptime")
+		return spec.ResponseFailWithFlags(spec.ParameterLess, "interval|uptime")
+	}
+	iterations := 0
+	if value := model.ActionFlags["iterations"]; value != "" {
+		iterations, err = strconv.Atoi(value)
+		if err != nil || iterations < 0 {
+			log.Errorf(ctx, "`%s`: process-crashloop-iterations is illegal", value)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "iterations", value, "it must be a non-negative integer")
+		}
+	}
+	service := model.ActionFlags["service"]
+	if service == "" && model.ActionFlags["process"] == "" && model.ActionFlags["process-cmd"] == "" && model.ActionFlags["local-port"] == "" {
+		log.Errorf(ctx, "process-crashloop-less process, process-cmd, local-port and service")
+		return spec.ResponseFailWithFlags(spec.ParameterLess, "process|process-cmd|local-port|service")
+	}
+	if service != "" {
+		if !cle.channel.IsCommandAvailable(ctx, "systemctl") {
+			log.Errorf(ctx, spec.CommandSystemctlNotFound.Msg)
+			return spec.ResponseFailWithFlags(spec.CommandSystemctlNotFound)
+		}
+	} else if response := getPids(ctx, cle.channel, model, uid); !response.Success {
+		// the target must be found at creation unless ignore-not-found is set
+		return response
+	}
+	return cle.start(ctx, model, service, signal, interval, uptime, iterations)
+}
+
+// start kills the target processes until the iterations are finished, the kills are returned in the response
+func (cle *CrashLoopProcessExecutor) start(ctx context.Context, model *spec.ExpModel, service, signal string,
+	interval, uptime time.Duration, iterations int) *spec.Response {
+	// the target is looked up again after it is killed, so it is not found for a while
+	flags := make(map[string]string, len(model.ActionFlags)+1)
+	for name, value := range model.ActionFlags {
+		flags[name] = value
+	}
+	flags["ignore-not-found"] = "true"
+	lookupModel := &spec.ExpModel{Target: model.Target, ActionName: model.ActionName, ActionFlags: flags}
+
+	kills := make([]crashLoopKill, 0)
+	// killed are the signaled processes by the pid and the start time, they are not signaled again
+	killed := make(map[string]bool)
+	var lastKill time.Time
+	for iterations == 0 || len(kills) < iterations {
+		if interval > 0 && !lastKill.IsZero() && time.Since(lastKill) < interval {
+			time.Sleep(time.Until(lastKill.Add(interval)))
+			continue
+		}
+		pids, err := cle.lookupPids(ctx, lookupModel, service)
+		if err != nil {
+			log.Warnf(ctx, "process-crashloop-lookup the target err, %v", err)
+		}
+		running := runningProcesses(ctx, cle.channel, pids)
+		targets := make([]string, 0)
+		for _, pid := range pids {
+			p, ok := running[pid]
+			if !ok || killed[pid+"@"+p.start] || p.uptime < uptime {
+				continue
+			}
+			targets = append(targets, pid)
+			killed[pid+"@"+p.start] = true
+		}
+		if len(targets) == 0 {
+			time.Sleep(crashLoopPollInterval)
+			continue
+		}
+		response := cle.channel.Run(ctx, "kill", fmt.Sprintf("-%s %s", signal, strings.Join(targets, " ")))
+		if !response.Success {
+			log.Warnf(ctx, "process-crashloop-kill %v err, %s", targets, response.Err)
+			time.Sleep(crashLoopPollInterval)
+			continue
+		}
+		lastKill = time.Now()
+		kill := crashLoopKill{Iteration: len(kills) + 1, Pids: strings.Join(targets, " "), Signal: signal, Time: lastKill.Format(time.RFC3339)}
+		kills = append(kills, kill)
+		log.Infof(ctx, "process-crashloop-iteration %d kills %s with the signal %s", kill.Iteration, kill.Pids, signal)
+		store.Record(ctx, store.Resource{Kind: store.ResourceKill, Value: kill.Pids, Attrs: map[string]string{
+			"iteration": strconv.Itoa(kill.Iteration),
+			"signal":    signal,
+			"time":      kill.Time,
+		}})
+	}
+	return spec.ReturnSuccess(kills)
+}
+
+// lookupPids returns the pids of the target, the main pid of the service or the pids matched by the process flags
+func (cle *CrashLoopProcessExecutor) lookupPids(ctx context.Context, model *spec.ExpModel, service string) ([]string, error) {
+	if service != "" {
+		response := cle.channel.Run(ctx, "systemctl", fmt.Sprintf(`show --property MainPID --value "%s"`, service))
+		if !response.Success {
+			return nil, fmt.Errorf("%s", response.Err)
+		}
+		pid, _ := response.Result.(string)
+		pid = strings.TrimSpace(pid)
+		if pid == "" || pid == "0" {
+			return nil, nil
+		}
+		return []string{pid}, nil
+	}
+	response := getPids(ctx, cle.channel, model, "")
+	if !response.Success {
+		return nil, fmt.Errorf("%s", response.Err)
+	}
+	// the result is nil if the target is not found
+	pids, _ := response.Result.(string)
+	return strings.Fields(pids), nil
+}
+
+// runningProcess is the process found by ps
+type runningProcess struct {
+	uptime time.Duration
+	// start is the start time of the process, which tells the reused pid
+	start string
+}
+
+// runningProcesses returns the running processes by their pids, the exited processes are absent
+func runningProcesses(ctx context.Context, cl spec.Channel, pids []string) map[string]runningProcess {
+	if len(pids) == 0 {
+		return map[string]runningProcess{}
+	}
+	response := cl.Run(ctx, "ps", fmt.Sprintf("-o pid=,etimes=,lstart= -p %s", strings.Join(pids, ",")))
+	// ps exits with 1 if none of the processes is found
+	if !response.Success {
+		return map[string]runningProcess{}
+	}
+	output, _ := response.Result.(string)
+	return parseRunningProcesses(output)
+}
+
+// parseRunningProcesses parses the pid, etimes and lstart columns of ps, for example
+// 1234     35 Sat Oct 17 02:30:33 2026
+func parseRunningProcesses(output string) map[string]runningProcess {
+	processes := make(map[string]runningProcess)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		seconds, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		processes[fields[0]] = runningProcess{uptime: time.Duration(seconds) * time.Second, start: strings.Join(fields[2:], " ")}
+	}
+	return processes
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+)
+
+func TestParseRunningProcesses(t *testing.T) {
+	processes := parseRunningProcesses("   1234      35 Sat Oct 17 02:30:33 2026\n  88 7 Sat Oct 17 02:31:01 2026\nillegal line\n")
+	if len(processes) != 2 {
+		t.Fatalf("parseRunningProcesses() returns %d processes, want 2", len(processes))
+	}
+	if p := processes["1234"]; p.uptime != 35*time.Second || p.start != "Sat Oct 17 02:30:33 2026" {
+		t.Errorf("process 1234 is %+v", p)
+	}
+	if p := processes["88"]; p.uptime != 7*time.Second {
+		t.Errorf("process 88 is %+v", p)
+	}
+}
+
+// crashLoopChannel returns the scripted outputs of ps for the main pid of the service, and records the kills
+type crashLoopChannel struct {
+	spec.Channel
+	ps    []string
+	calls []string
+	times []time.Time
+}
+
+func (c *crashLoopChannel) Run(ctx context.Context, script, args string) *spec.Response {
+	c.calls = append(c.calls, script+" "+args)
+	c.times = append(c.times, time.Now())
+	switch script {
+	case "systemctl":
+		return spec.ReturnSuccess("100\n")
+	case "ps":
+		if len(c.ps) == 0 {
+			return spec.ReturnFail(spec.OsCmdExecFailed, "the process is not found")
+		}
+		output := c.ps[0]
+		c.ps = c.ps[1:]
+		return spec.ReturnSuccess(output)
+	case "kill":
+		return spec.Success()
+	}
+	return spec.ReturnFail(spec.OsCmdExecFailed, "unexpected command "+script)
+}
+
+func TestCrashLoopStart(t *testing.T) {
+	cl := &crashLoopChannel{ps: []string{
+		// the process is younger than the uptime
+		"100 5 Sat Oct 17 02:30:33 2026",
+		"100 20 Sat Oct 17 02:30:33 2026",
+		// the killed process is not signaled again
+		"100 21 Sat Oct 17 02:30:33 2026",
+		// the pid is reused by the restarted process
+		"100 30 Sat Oct 17 02:31:01 2026",
+	}}
+	experiment := &store.Experiment{Uid: "cl01"}
+	ctx := store.WithExperiment(context.Background(), nil, experiment)
+	executor := &CrashLoopProcessExecutor{channel: cl}
+	interval := 300 * time.Millisecond
+	response := executor.start(ctx, &spec.ExpModel{ActionFlags: map[string]string{}}, "chaos.service", "9", interval, 10*time.Second, 2)
+	if !response.Success {
+		t.Fatalf("start() err, %s", response.Err)
+	}
+	if kills, ok := response.Result.([]crashLoopKill); !ok || len(kills) != 2 {
+		t.Fatalf("start() returns %+v, want 2 kills", response.Result)
+	}
+	if len(cl.ps) != 0 {
+		t.Errorf("%d outputs of ps are not consumed", len(cl.ps))
+	}
+	// the kills follow the second and the fourth ps
+	kills, ps := make([]int, 0), 0
+	for i, call := range cl.calls {
+		switch {
+		case strings.HasPrefix(call, "ps "):
+			ps++
+		case call == "kill -9 100":
+			if ps != 2*(len(kills)+1) {
+				t.Errorf("the kill %d follows the ps %d, the calls are %v", len(kills)+1, ps, cl.calls)
+			}
+			kills = append(kills, i)
+		}
+	}
+	if len(kills) != 2 {
+		t.Fatalf("the calls are %v, want 2 kills", cl.calls)
+	}
+	// the target is not looked up until the interval after the kill
+	if next := kills[0] + 1; cl.times[next].Sub(cl.times[kills[0]]) < interval {
+		t.Errorf("the call %s is %s after the kill, want the interval %s", cl.calls[next], cl.times[next].Sub(cl.times[kills[0]]), interval)
+	}
+	resources := experiment.ResourcesOf(store.ResourceKill)
+	if len(resources) != 2 || resources[0].Attrs["iteration"] != "1" || resources[1].Attrs["iteration"] != "2" ||
+		resources[0].Value != "100" || resources[0].Attrs["signal"] != "9" {
+		t.Errorf("the recorded kills are %+v", resources)
+	}
+}
@@ -0,0 +1,417 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	osExec "os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/dryrun"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+)
+
+const FdExhaustProcessBin = "chaos_fdexhaustprocess"
+
+// ProcessFdHoldMode is the mode of chaos_os holding the descriptors on the host
+const ProcessFdHoldMode = "process-fd-hold"
+
+// fdReserved is the number of descriptors kept for chaos_os itself when its limit is raised
+const fdReserved = 64
+
+type FdExhaustProcessActionCommandSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewFdExhaustProcessActionCommandSpec() spec.ExpActionCommandSpec {
+	return &FdExhaustProcessActionCommandSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "process",
+					Desc: "Process name, Separate multiple process with commas (,)",
+				},
+				&spec.ExpFlag{
+					Name: "process-cmd",
+					Desc: "Process name in command",
+				},
+				&spec.ExpFlag{
+					Name: "local-port",
+					Desc: "Local service ports. Separate multiple ports with commas (,) or connector representing ranges, for example: 80,8000-8080",
+				},
+				&spec.ExpFlag{
+					Name: "pid",
+					Desc: "pid",
+				},
+				&spec.ExpFlag{
+					Name: "exclude-process",
+					Desc: "Exclude process",
+				},
+			},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "limit",
+					Desc: "The soft RLIMIT_NOFILE set to the target processes, default value is the number of the descriptors opened by each process, so that opening a new one fails with EMFILE",
+				},
+				&spec.ExpFlag{
+					Name: "fd-count",
+					Desc: "The number of the file descriptors opened on the host, it is used if no target process is specified",
+				},
+				&spec.ExpFlag{
+					Name: "fd-percent",
+					Desc: "The percentage of fs.file-max that the file handles allocated on the host reach, it is used if no target process is specified",
+				},
+			},
+			ActionExecutor: &FdExhaustProcessExecutor{},
+			ActionExample: `
+# The nginx processes fail to open any new file with "too many open files"
+blade create process fd-exhaust --process nginx
+
+# Limit the process 1234 to 100 open files
+blade create process fd-exhaust --pid 1234 --limit 100
+
+# Open 100000 file descriptors on the host
+blade create process fd-exhaust --fd-count 100000
+
+# Open file descriptors until 95% of fs.file-max is allocated on the host
+blade create process fd-exhaust --fd-percent 95`,
+			ActionPrograms:   []string{FdExhaustProcessBin},
+			ActionCategories: []string{category.SystemProcess},
+		},
+	}
+}
+
+func (*FdExhaustProcessActionCommandSpec) Name() string {
+	return "fd-exhaust"
+}
+
+func (*FdExhaustProcessActionCommandSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*FdExhaustProcessActionCommandSpec) ShortDesc() string {
+	return "Exhaust file descriptors"
+}
+
+func (f *FdExhaustProcessActionCommandSpec) LongDesc() string {
+	if f.ActionLongDesc != "" {
+		return f.ActionLongDesc
+	}
+	return "Exhaust the file descriptors to simulate \"too many open files\". The RLIMIT_NOFILE of the target processes " +
+		"is lowered by prlimit, or the descriptors are held by a detached chaos_os process on the host if no target process is specified. " +
+		"The limits are restored and the descriptors are released when the experiment is destroyed"
+}
+
+func (*FdExhaustProcessActionCommandSpec) Categories() []string {
+	return []string{category.SystemProcess}
+}
+
+type FdExhaustProcessExecutor struct {
+	channel spec.Channel
+}
+
+func (fee *FdExhaustProcessExecutor) Name() string {
+	return "fd-exhaust"
+}
+
+func (fee *FdExhaustProcessExecutor) SetChannel(channel spec.Channel) {
+	fee.channel = channel
+}
+
+func (fee *FdExhaustProcessExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if _, ok := spec.IsDestroy(ctx); ok {
+		// the limits of the target processes are lowered, or the descriptors are held by the detached process
+		if len(store.Resources(ctx, store.ResourceRlimit)) > 0 {
+			return restoreRlimits(ctx, fee.channel)
+		}
+		return stopFdHold(ctx, fee.channel)
+	}
+	targeted := model.ActionFlags["process"] != "" || model.ActionFlags["process-cmd"] != "" ||
+		model.ActionFlags["local-port"] != "" || model.ActionFlags["pid"] != ""
+	fdCount, fdPercent := model.ActionFlags["fd-count"], model.ActionFlags["fd-percent"]
+	if targeted {
+		if fdCount != "" || fdPercent != "" {
+			log.Errorf(ctx, "process-fd-exhaust-fd-count and fd-percent cannot be used with the target process")
+			return spec.ResponseFailWithFlags(spec.ParameterInvalid, "fd-count|fd-percent", fdCount+fdPercent,
+				"cannot be used with the target process")
+		}
+		return fee.limit(ctx, model, uid)
+	}
+	if fdCount != "" && fdPercent != "" {
+		log.Errorf(ctx, "process-fd-exhaust-fd-count and fd-percent cannot be used together")
+		return spec.ResponseFailWithFlags(spec.ParameterInvalid, "fd-count|fd-percent", fdCount+fdPercent, "cannot be used together")
+	}
+	var count int
+	var err error
+	switch {
+	case fdCount != "":
+		count, err = strconv.Atoi(fdCount)
+		if err != nil || count <= 0 {
+			log.Errorf(ctx, "`%s`: process-fd-exhaust-fd-count is illegal", fdCount)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "fd-count", fdCount, "it must be a positive integer")
+		}
+	case fdPercent != "":
+		percent, err := strconv.Atoi(fdPercent)
+		if err != nil || percent <= 0 || percent > 100 {
+			log.Errorf(ctx, "`%s`: process-fd-exhaust-fd-percent is illegal", fdPercent)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "fd-percent", fdPercent, "it must be in 1..100")
+		}
+		count, err = fdCountOfPercent(percent)
+		if err != nil {
+			log.Errorf(ctx, "process-fd-exhaust-%v", err)
+			return spec.ResponseFailWithFlags(spec.ParameterInvalid, "fd-percent", fdPercent, err)
+		}
+	default:
+		log.Errorf(ctx, "process-fd-exhaust-less target process, fd-count and fd-percent")
+		return spec.ResponseFailWithFlags(spec.ParameterLess, "pid|process|process-cmd|local-port|fd-count|fd-percent")
+	}
+	return startFdHold(ctx, fee.channel, uid, count)
+}
+
+// limit lowers the RLIMIT_NOFILE of the target processes, the original limits are recorded and restored by destroy
+func (fee *FdExhaustProcessExecutor) limit(ctx context.Context, model *spec.ExpModel, uid string) *spec.Response {
+	limitValue := model.ActionFlags["limit"]
+	if limitValue != "" {
+		if limit, err := strconv.Atoi(limitValue); err != nil || limit < 0 {
+			log.Errorf(ctx, "`%s`: process-fd-exhaust-limit is illegal", limitValue)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "limit", limitValue, "it must be a non-negative integer")
+		}
+	}
+	if response, ok := fee.channel.IsAllCommandsAvailable(ctx, []string{"prlimit"}); !ok {
+		return response
+	}
+	response := getPids(ctx, fee.channel, model, uid)
+	if !response.Success {
+		return response
+	}
+	pids, _ := response.Result.(string)
+	for _, pid := range strings.Fields(pids) {
+		limit := limitValue
+		if limit == "" {
+			fds := fee.channel.Run(ctx, "ls", fmt.Sprintf("/proc/%s/fd", pid))
+			if !fds.Success {
+				log.Errorf(ctx, "process-fd-exhaust-list the descriptors of the process %s err, %s", pid, fds.Err)
+				restoreRlimits(ctx, fee.channel)
+				return fds
+			}
+			names, _ := fds.Result.(string)
+			limit = strconv.Itoa(len(strings.Fields(names)))
+		}
+		// the hard limit is kept, raising it back requires CAP_SYS_RESOURCE which may be dropped in the containers
+		if changed := changeRlimit(ctx, fee.channel, pid, "nofile", rlimit{Soft: limit}); !changed.Success {
+			restoreRlimits(ctx, fee.channel)
+			return changed
+		}
+	}
+	return spec.ReturnSuccess(pids)
+}
+
+// fdCountOfPercent returns the number of the descriptors to open, so that the allocated file handles reach
+// the percentage of fs.file-max
+func fdCountOfPercent(percent int) (int, error) {
+	fileMax, err := readProcSysInt("/proc/sys/fs/file-max")
+	if err != nil {
+		return 0, err
+	}
+	allocated, err := readProcSysInt("/proc/sys/fs/file-nr")
+	if err != nil {
+		return 0, err
+	}
+	return fdCountOf(fileMax, allocated, percent)
+}
+
+// fdCountOf returns the number of the descriptors to open beyond the allocated file handles. The fs.file-max
+// is 9223372036854775807 by default on the recent kernels, so the percentage is computed without overflow
+// and the number is capped, the descriptors are opened up to the limit of the process anyway.
+func fdCountOf(fileMax, allocated int64, percent int) (int, error) {
+	count := fileMax/100*int64(percent) + fileMax%100*int64(percent)/100 - allocated
+	if count <= 0 {
+		return 0, fmt.Errorf("%d file handles are allocated, they exceed %d%% of fs.file-max %d", allocated, percent, fileMax)
+	}
+	if count > math.MaxInt32 {
+		count = math.MaxInt32
+	}
+	return int(count), nil
+}
+
+// readProcSysInt reads the first number of the file under /proc/sys
+func readProcSysInt(file string) (int64, error) {
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return 0, fmt.Errorf("read %s err, %v", file, err)
+	}
+	fields := strings.Fields(string(bytes))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("%s is empty", file)
+	}
+	return strconv.ParseInt(fields[0], 10, 64)
+}
+
+// startFdHold starts the detached processes holding the descriptors, which survive the caller and are killed by
+// the destroy command. A process holds the descriptors up to its limit, so more processes are started until the
+// count is reached. It fails and kills the processes if the count is not reached.
+func startFdHold(ctx context.Context, cl spec.Channel, uid string, count int) *spec.Response {
+	bin, err := os.Executable()
+	if err != nil {
+		return spec.ResponseFailWithFlags(spec.OsCmdExecFailed, "executable", err)
+	}
+	if recorder, ok := cl.(*dryrun.RecordChannel); ok {
+		recorder.Record(dryrun.Operation{
+			Type:    dryrun.OperationProcess,
+			Command: fmt.Sprintf("%s %s %s %d", bin, ProcessFdHoldMode, uid, count),
+		})
+		return spec.ReturnSuccess(uid)
+	}
+	holders := make([]*os.Process, 0)
+	opened := 0
+	for opened < count {
+		holder, holds, err := startFdHolder(bin, uid, count-opened)
+		if err != nil {
+			log.Warnf(ctx, "process-fd-exhaust-start the fd hold process after %d descriptors err, %v", opened, err)
+			break
+		}
+		log.Infof(ctx, "process-fd-exhaust-the process %d holds %d descriptors", holder.Pid, holds)
+		holders = append(holders, holder)
+		opened += holds
+	}
+	if opened < count {
+		for _, holder := range holders {
+			holder.Kill()
+			holder.Wait()
+		}
+		log.Errorf(ctx, "process-fd-exhaust-%d of %d descriptors are opened", opened, count)
+		return spec.ResponseFailWithFlags(spec.OsCmdExecFailed, ProcessFdHoldMode,
+			fmt.Sprintf("%d of %d descriptors are opened", opened, count))
+	}
+	for _, holder := range holders {
+		store.RecordPid(ctx, holder.Pid)
+		holder.Release()
+	}
+	return spec.ReturnSuccess(uid)
+}
+
+// startFdHolder starts the process holding the descriptors, and returns it with the number of the descriptors
+// it reports
+func startFdHolder(bin, uid string, count int) (*os.Process, int, error) {
+	command := osExec.Command(bin, ProcessFdHoldMode, uid, strconv.Itoa(count))
+	command.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	stdout, err := command.StdoutPipe()
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := command.Start(); err != nil {
+		return nil, 0, err
+	}
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		// the process exits without reporting if it cannot open any descriptor
+		command.Wait()
+		return nil, 0, fmt.Errorf("no descriptor is opened")
+	}
+	opened, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || opened <= 0 {
+		command.Process.Kill()
+		command.Wait()
+		return nil, 0, fmt.Errorf("illegal report %q", line)
+	}
+	return command.Process, opened, nil
+}
+
+// stopFdHold kills the process holding the descriptors, the descriptors are released when it exits
+func stopFdHold(ctx context.Context, cl spec.Channel) *spec.Response {
+	// the process is not started by dry-run, so its pid is unknown
+	if recorder, ok := cl.(*dryrun.RecordChannel); ok && len(store.Resources(ctx, store.ResourcePid)) == 0 {
+		recorder.Record(dryrun.Operation{Type: dryrun.OperationCommand, Command: "kill -9 <pid of the fd hold process>"})
+		return spec.Success()
+	}
+	ctx = context.WithValue(ctx, "bin", FdExhaustProcessBin)
+	return exec.Destroy(ctx, cl, "process fd-exhaust")
+}
+
+// RunFdHold opens the descriptors, reports the number of them to the starter and holds them until it is killed
+// example => process-fd-hold 7c7b0f3a8d1e4c2b 100000
+func RunFdHold(args []string) {
+	util.InitLog(util.Bin)
+	if len(args) < 2 {
+		log.Errorf(context.Background(), "process-fd-hold-uid|count is nil")
+		os.Exit(1)
+	}
+	ctx := context.WithValue(context.Background(), spec.Uid, args[0])
+	count, err := strconv.Atoi(args[1])
+	if err != nil || count <= 0 {
+		log.Errorf(ctx, "`%s`: process-fd-hold-count is illegal", args[1])
+		os.Exit(1)
+	}
+	opened := holdFds(ctx, count)
+	if opened == 0 {
+		os.Exit(1)
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	fmt.Println(opened)
+	<-signals
+	os.Exit(0)
+}
+
+// holdFds opens the descriptors and returns the number of them, they are released when the process exits.
+// The limit of the process is raised to hold all descriptors, but not beyond fs.nr_open.
+// Opening stops at ENFILE, as the host is already exhausted.
+func holdFds(ctx context.Context, count int) int {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		log.Errorf(ctx, "process-fd-hold-get the limit err, %v", err)
+		return 0
+	}
+	if needed := uint64(count + fdReserved); needed > limit.Cur {
+		if nrOpen, err := readProcSysInt("/proc/sys/fs/nr_open"); err == nil && needed > uint64(nrOpen) {
+			needed = uint64(nrOpen)
+		}
+		raised := syscall.Rlimit{Cur: needed, Max: limit.Max}
+		if needed > limit.Max {
+			raised.Max = needed
+		}
+		if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &raised); err != nil {
+			// raising the hard limit requires CAP_SYS_RESOURCE, the descriptors are opened up to the hard limit
+			log.Warnf(ctx, "process-fd-hold-raise the limit to %d err, %v", needed, err)
+			raised = syscall.Rlimit{Cur: limit.Max, Max: limit.Max}
+			if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &raised); err != nil {
+				log.Warnf(ctx, "process-fd-hold-raise the limit to %d err, %v", limit.Max, err)
+			}
+		}
+	}
+	opened := 0
+	for ; opened < count; opened++ {
+		if _, err := syscall.Open(os.DevNull, syscall.O_RDONLY|syscall.O_CLOEXEC, 0); err != nil {
+			log.Warnf(ctx, "process-fd-hold-stop opening at %d descriptors, %v", opened, err)
+			break
+		}
+	}
+	log.Infof(ctx, "process-fd-hold-hold %d descriptors", opened)
+	return opened
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFdCountOf(t *testing.T) {
+	tests := []struct {
+		fileMax, allocated int64
+		percent            int
+		expect             int
+	}{
+		{fileMax: 1000000, allocated: 2000, percent: 50, expect: 498000},
+		{fileMax: 1000001, allocated: 0, percent: 100, expect: 1000001},
+		// the default fs.file-max of the recent kernels
+		{fileMax: math.MaxInt64, allocated: 2000, percent: 95, expect: math.MaxInt32},
+		{fileMax: math.MaxInt64, allocated: 2000, percent: 1, expect: math.MaxInt32},
+	}
+	for _, tt := range tests {
+		count, err := fdCountOf(tt.fileMax, tt.allocated, tt.percent)
+		if err != nil || count != tt.expect {
+			t.Errorf("fdCountOf(%d, %d, %d) = %d, %v, want %d", tt.fileMax, tt.allocated, tt.percent, count, err, tt.expect)
+		}
+	}
+	if _, err := fdCountOf(1000, 900, 50); err == nil {
+		t.Errorf("fdCountOf() expects err if the allocated handles exceed the percentage")
+	}
+}
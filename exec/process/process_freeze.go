@@ -0,0 +1,222 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	osExec "os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/dryrun"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+)
+
+// ProcessFreezeMode is the mode of chaos_os running the freeze loop
+const ProcessFreezeMode = "process-freeze"
+
+// frozenCgroup is the freezer control file of a cgroup with the values to freeze and thaw it
+type frozenCgroup struct {
+	File   string `json:"file"`
+	Freeze string `json:"freeze"`
+	Thaw   string `json:"thaw"`
+}
+
+// freezeLoopConfig is passed to the freeze loop process, which freezes the processes or the cgroups for
+// the freeze duration and resumes them for the interval alternately. The processes keep their start time,
+// so the pids reused by other processes after the targets exit are never signaled.
+type freezeLoopConfig struct {
+	Processes      []store.Resource `json:"processes,omitempty"`
+	Cgroups        []frozenCgroup   `json:"cgroups,omitempty"`
+	FreezeDuration time.Duration    `json:"freezeDuration"`
+	Interval       time.Duration    `json:"interval"`
+}
+
+// startFreezeLoop starts the freeze loop process, which survives the caller and is killed by the destroy command
+func startFreezeLoop(ctx context.Context, cl spec.Channel, uid string, config freezeLoopConfig) *spec.Response {
+	bytes, _ := json.Marshal(config)
+	bin, err := os.Executable()
+	if err != nil {
+		return spec.ResponseFailWithFlags(spec.OsCmdExecFailed, "executable", err)
+	}
+	if recorder, ok := cl.(*dryrun.RecordChannel); ok {
+		recorder.Record(dryrun.Operation{
+			Type:    dryrun.OperationProcess,
+			Command: fmt.Sprintf("%s %s %s '%s'", bin, ProcessFreezeMode, uid, bytes),
+		})
+		return spec.ReturnSuccess(uid)
+	}
+	command := osExec.Command(bin, ProcessFreezeMode, uid, string(bytes))
+	command.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := command.Start(); err != nil {
+		log.Errorf(ctx, "process-startFreezeLoop-start freeze loop err, %v", err)
+		return spec.ResponseFailWithFlags(spec.OsCmdExecFailed, ProcessFreezeMode, err)
+	}
+	store.Record(ctx, store.NewProcessResource(store.ResourceFreezeLoop, command.Process.Pid))
+	command.Process.Release()
+	return spec.ReturnSuccess(uid)
+}
+
+// stopFreezeLoop kills the freeze loop process, the processes or cgroups it may leave frozen are resumed by the caller
+func stopFreezeLoop(ctx context.Context, cl spec.Channel) *spec.Response {
+	resources := store.Resources(ctx, store.ResourceFreezeLoop)
+	if len(resources) == 0 {
+		return spec.Success()
+	}
+	// the loop is not started by dry-run, so its pid is unknown
+	if recorder, ok := cl.(*dryrun.RecordChannel); ok {
+		recorder.Record(dryrun.Operation{Type: dryrun.OperationCommand, Command: "kill -9 <pid of the freeze loop process>"})
+		return spec.Success()
+	}
+	pids := make([]string, 0)
+	for _, resource := range resources {
+		if store.PidAlive(resource) {
+			pids = append(pids, resource.Value)
+		}
+	}
+	if len(pids) == 0 {
+		return spec.Success()
+	}
+	// the loop runs on the host, so it is killed by the host channel
+	return exec.HostChannel(cl).Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
+}
+
+// freezeCgroups freezes the cgroups and records them, so they are thawed by the destroy command
+func freezeCgroups(ctx context.Context, cl spec.Channel, cgroups []frozenCgroup) *spec.Response {
+	// the cgroups are resolved on the host, so the freezer files are written by the host channel
+	cl = exec.HostChannel(cl)
+	for _, cgroup := range cgroups {
+		response := cl.Run(ctx, "echo", fmt.Sprintf("%s > %s", cgroup.Freeze, cgroup.File))
+		if !response.Success {
+			log.Errorf(ctx, "process-freezeCgroups-freeze %s err, %s", cgroup.File, response.Err)
+			thawCgroups(ctx, cl)
+			return response
+		}
+		store.Record(ctx, frozenCgroupResource(cgroup))
+	}
+	return spec.Success()
+}
+
+// recordFrozenCgroups records the cgroups frozen by the freeze loop
+func recordFrozenCgroups(ctx context.Context, cgroups []frozenCgroup) {
+	for _, cgroup := range cgroups {
+		store.Record(ctx, frozenCgroupResource(cgroup))
+	}
+}
+
+func frozenCgroupResource(cgroup frozenCgroup) store.Resource {
+	return store.Resource{Kind: store.ResourceFrozenCgroup, Value: cgroup.File, Attrs: map[string]string{
+		"freeze": cgroup.Freeze,
+		"thaw":   cgroup.Thaw,
+	}}
+}
+
+// thawCgroups thaws the recorded cgroups
+func thawCgroups(ctx context.Context, cl spec.Channel) *spec.Response {
+	cl = exec.HostChannel(cl)
+	response := spec.Success()
+	for _, resource := range store.Resources(ctx, store.ResourceFrozenCgroup) {
+		if !cl.Run(ctx, "test", fmt.Sprintf("-e %s", resource.Value)).Success {
+			log.Warnf(ctx, "process-thawCgroups-cgroup of %s is removed, skip thawing it", resource.Value)
+			continue
+		}
+		if thawed := cl.Run(ctx, "echo", fmt.Sprintf("%s > %s", resource.Attrs["thaw"], resource.Value)); !thawed.Success {
+			log.Errorf(ctx, "process-thawCgroups-thaw %s err, %s", resource.Value, thawed.Err)
+			response = thawed
+		}
+	}
+	return response
+}
+
+// RunFreezeLoop freezes the processes or the cgroups for the freeze duration and resumes them for the interval
+// alternately. It resumes them and exits if it is terminated, or all processes exit.
+func RunFreezeLoop(args []string) {
+	util.InitLog(util.Bin)
+	if len(args) < 2 {
+		log.Errorf(context.Background(), "process-freeze-uid|config is nil")
+		os.Exit(1)
+	}
+	ctx := context.WithValue(context.Background(), spec.Uid, args[0])
+	var config freezeLoopConfig
+	if err := json.Unmarshal([]byte(args[1]), &config); err != nil {
+		log.Errorf(ctx, "process-freeze-unmarshal config %s err, %v", args[1], err)
+		os.Exit(1)
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	log.Infof(ctx, "process-freeze-freezing processes %+v and cgroups %+v for %s every %s",
+		config.Processes, config.Cgroups, config.FreezeDuration, config.Interval)
+	for {
+		alive := config.setFrozen(ctx, true)
+		if !alive && len(config.Cgroups) == 0 {
+			log.Infof(ctx, "process-freeze-all processes exit")
+			os.Exit(0)
+		}
+		select {
+		case <-signals:
+			config.setFrozen(ctx, false)
+			os.Exit(0)
+		case <-time.After(config.FreezeDuration):
+		}
+		config.setFrozen(ctx, false)
+		select {
+		case <-signals:
+			os.Exit(0)
+		case <-time.After(config.Interval):
+		}
+	}
+}
+
+// setFrozen freezes or resumes the processes and the cgroups, it returns false if no process is alive
+func (c freezeLoopConfig) setFrozen(ctx context.Context, frozen bool) bool {
+	sig := syscall.SIGCONT
+	if frozen {
+		sig = syscall.SIGSTOP
+	}
+	alive := false
+	for _, process := range c.Processes {
+		pid, err := strconv.Atoi(process.Value)
+		if err != nil || !store.PidAlive(process) {
+			continue
+		}
+		if err := syscall.Kill(pid, sig); err == nil {
+			alive = true
+		} else if err != syscall.ESRCH {
+			log.Warnf(ctx, "process-freeze-signal %d with %s err, %v", pid, sig, err)
+		}
+	}
+	for _, cgroup := range c.Cgroups {
+		value := cgroup.Thaw
+		if frozen {
+			value = cgroup.Freeze
+		}
+		if err := os.WriteFile(cgroup.File, []byte(value), 0644); err != nil {
+			log.Warnf(ctx, "process-freeze-write %s to %s err, %v", value, cgroup.File, err)
+		}
+	}
+	return alive
+}
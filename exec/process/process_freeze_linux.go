@@ -0,0 +1,34 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+)
+
+// resolveFreezerCgroup returns the freezer control file of the cgroup matched by the cgroup path or the container id
+func resolveFreezerCgroup(root, cgroupPath, containerId string) (frozenCgroup, error) {
+	target, err := exec.ResolveTargetCgroup(root, cgroupPath, containerId)
+	if err != nil {
+		return frozenCgroup{}, err
+	}
+	file, freeze, thaw, err := target.FreezerFile()
+	if err != nil {
+		return frozenCgroup{}, err
+	}
+	return frozenCgroup{File: file, Freeze: freeze, Thaw: thaw}, nil
+}
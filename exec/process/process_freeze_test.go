@@ -0,0 +1,84 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"context"
+	"os"
+	osExec "os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/shirou/gopsutil/process"
+)
+
+func TestFreezeLoopSetFrozen(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "freezer.state")
+	config := freezeLoopConfig{
+		// the pid is beyond the pid_max, so it never exists
+		Processes: []store.Resource{{Kind: store.ResourcePid, Value: strconv.Itoa(1 << 30)}},
+		Cgroups:   []frozenCgroup{{File: file, Freeze: "FROZEN", Thaw: "THAWED"}},
+	}
+	for _, frozen := range []bool{true, false} {
+		if config.setFrozen(context.Background(), frozen) {
+			t.Errorf("setFrozen(%v) returns alive for the exited process", frozen)
+		}
+		expect := "THAWED"
+		if frozen {
+			expect = "FROZEN"
+		}
+		if state, _ := os.ReadFile(file); string(state) != expect {
+			t.Errorf("setFrozen(%v) writes %s, want %s", frozen, state, expect)
+		}
+	}
+}
+
+func TestFreezeLoopSkipReusedPid(t *testing.T) {
+	command := osExec.Command("sleep", "60")
+	if err := command.Start(); err != nil {
+		t.Skipf("start sleep err, %v", err)
+	}
+	defer func() {
+		command.Process.Kill()
+		command.Wait()
+	}()
+	// the recorded start time differs from the running process, as if the target exited and its pid is reused
+	config := freezeLoopConfig{Processes: []store.Resource{{
+		Kind:  store.ResourcePid,
+		Value: strconv.Itoa(command.Process.Pid),
+		Attrs: map[string]string{"createTime": "1"},
+	}}}
+	if config.setFrozen(context.Background(), true) {
+		t.Errorf("setFrozen(true) returns alive for the reused pid")
+	}
+	p, err := process.NewProcess(int32(command.Process.Pid))
+	if err != nil {
+		t.Fatalf("get process err, %v", err)
+	}
+	if status, _ := p.Status(); status == "T" {
+		command.Process.Signal(syscall.SIGCONT)
+		t.Errorf("the process reusing the pid is stopped")
+	}
+
+	config.Processes = []store.Resource{store.NewProcessResource(store.ResourcePid, command.Process.Pid)}
+	if !config.setFrozen(context.Background(), false) {
+		t.Errorf("setFrozen(false) returns not alive for the running process")
+	}
+}
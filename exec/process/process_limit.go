@@ -0,0 +1,182 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+)
+
+const LimitProcessBin = "chaos_limitprocess"
+
+type LimitProcessActionCommandSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewLimitProcessActionCommandSpec() spec.ExpActionCommandSpec {
+	return &LimitProcessActionCommandSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "process",
+					Desc: "Process name, Separate multiple process with commas (,)",
+				},
+				&spec.ExpFlag{
+					Name: "process-cmd",
+					Desc: "Process name in command",
+				},
+				&spec.ExpFlag{
+					Name: "local-port",
+					Desc: "Local service ports. Separate multiple ports with commas (,) or connector representing ranges, for example: 80,8000-8080",
+				},
+				&spec.ExpFlag{
+					Name: "pid",
+					Desc: "pid",
+				},
+				&spec.ExpFlag{
+					Name: "count",
+					Desc: "Limit count, 0 means unlimited",
+				},
+				&spec.ExpFlag{
+					Name: "exclude-process",
+					Desc: "Exclude process",
+				},
+			},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "nofile",
+					Desc: "RLIMIT_NOFILE, the max number of open files. The limits are in the format of prlimit: soft:hard, soft:, :hard or a value for both, the value is a number or unlimited",
+				},
+				&spec.ExpFlag{
+					Name: "nproc",
+					Desc: "RLIMIT_NPROC, the max number of processes of the user of the process, in the same format as nofile",
+				},
+				&spec.ExpFlag{
+					Name: "as",
+					Desc: "RLIMIT_AS, the max size of the address space in bytes, in the same format as nofile",
+				},
+				&spec.ExpFlag{
+					Name: "core",
+					Desc: "RLIMIT_CORE, the max size of the core file in bytes, in the same format as nofile",
+				},
+				&spec.ExpFlag{
+					Name: "stack",
+					Desc: "RLIMIT_STACK, the max size of the stack in bytes, in the same format as nofile",
+				},
+			},
+			ActionExecutor: &LimitProcessExecutor{},
+			ActionExample: `
+# Limit the open files of the nginx processes to 1024
+blade create process limit --process nginx --nofile 1024:
+
+# Disable the core dump of the process 1234 and limit its stack to 1MB
+blade create process limit --pid 1234 --core 0: --stack 1048576:
+
+# Limit the java process to 64 processes and 4GB address space
+blade create process limit --process-cmd java --nproc 64: --as 4294967296:`,
+			ActionPrograms:   []string{LimitProcessBin},
+			ActionCategories: []string{category.SystemProcess},
+		},
+	}
+}
+
+func (*LimitProcessActionCommandSpec) Name() string {
+	return "limit"
+}
+
+func (*LimitProcessActionCommandSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*LimitProcessActionCommandSpec) ShortDesc() string {
+	return "Change the resource limits of process"
+}
+
+func (l *LimitProcessActionCommandSpec) LongDesc() string {
+	if l.ActionLongDesc != "" {
+		return l.ActionLongDesc
+	}
+	return "Change the resource limits of the running processes by prlimit, the original limits are restored when " +
+		"the experiment is destroyed. Raising the hard limit back requires CAP_SYS_RESOURCE, so keep the hard limit " +
+		"by the soft: format if the capability may be dropped"
+}
+
+func (*LimitProcessActionCommandSpec) Categories() []string {
+	return []string{category.SystemProcess}
+}
+
+type LimitProcessExecutor struct {
+	channel spec.Channel
+}
+
+func (lpe *LimitProcessExecutor) Name() string {
+	return "limit"
+}
+
+func (lpe *LimitProcessExecutor) SetChannel(channel spec.Channel) {
+	lpe.channel = channel
+}
+
+func (lpe *LimitProcessExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return restoreRlimits(ctx, lpe.channel)
+	}
+	limits := make(map[string]rlimit)
+	for resource := range rlimitNames {
+		value := model.ActionFlags[resource]
+		if value == "" {
+			continue
+		}
+		limit, err := parseRlimitValue(value)
+		if err != nil {
+			log.Errorf(ctx, "`%s`: process-limit-%s is illegal, %v", value, resource, err)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, resource, value, err)
+		}
+		limits[resource] = limit
+	}
+	if len(limits) == 0 {
+		log.Errorf(ctx, "process-limit-less nofile, nproc, as, core and stack")
+		return spec.ResponseFailWithFlags(spec.ParameterLess, "nofile|nproc|as|core|stack")
+	}
+	if response, ok := lpe.channel.IsAllCommandsAvailable(ctx, []string{"prlimit"}); !ok {
+		return response
+	}
+	response := getPids(ctx, lpe.channel, model, uid)
+	if !response.Success {
+		return response
+	}
+	pids, _ := response.Result.(string)
+	resources := make([]string, 0, len(limits))
+	for resource := range limits {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	for _, pid := range strings.Fields(pids) {
+		for _, resource := range resources {
+			if changed := changeRlimit(ctx, lpe.channel, pid, resource, limits[resource]); !changed.Success {
+				restoreRlimits(ctx, lpe.channel)
+				return changed
+			}
+		}
+	}
+	return spec.ReturnSuccess(pids)
+}
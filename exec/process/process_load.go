@@ -19,20 +19,32 @@ package process
 import (
 	"context"
 	"fmt"
-	"github.com/chaosblade-io/chaosblade-exec-os/exec"
-	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
-	"github.com/chaosblade-io/chaosblade-spec-go/channel"
-	"github.com/chaosblade-io/chaosblade-spec-go/log"
-	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"os"
 	osExec "os/exec"
 	"os/user"
+	"runtime"
+	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/shirou/gopsutil/process"
 )
 
 const ProcessLoadBin = "chaos_processload"
 
+// processLoadRetryInterval is the interval to create the processes again after the creation fails
+const processLoadRetryInterval = 100 * time.Millisecond
+
+// processLoadSleep is the seconds the sleeping children sleep, they are killed by destroy
+const processLoadSleep = "2147483647"
+
 type ProcessLoadActionCommandSpec struct {
 	spec.BaseExpActionCommandSpec
 }
@@ -43,18 +55,41 @@ func NewProcessLoadActionCommandSpec() spec.ExpActionCommandSpec {
 			ActionMatchers: []spec.ExpFlagSpec{
 				&spec.ExpFlag{
 					Name: "count",
-					Desc: "process count, must be a positive integer, 0 or not set means unlimited",
+					Desc: "process count, must be a positive integer, the count or percent flag is required",
 				},
 				&spec.ExpFlag{
 					Name: "user",
-					Desc: "execute process cmd as the specified user",
+					Desc: "execute process cmd as the specified user, it cannot be used with the threads flag",
+				},
+			},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "percent",
+					Desc: "The percentage of the tasks limit to reach, the limit is pids.max of the target cgroup if it is limited, otherwise the less of kernel.pid_max and kernel.threads-max",
+				},
+				&spec.ExpFlag{
+					Name:   "threads",
+					Desc:   "Create the threads in the chaos_os process instead of the sleeping child processes",
+					NoArgs: true,
+				},
+				exec.CgroupPathFlag,
+				exec.ContainerIdFlag,
+				&spec.ExpFlag{
+					Name:    "cgroup-root",
+					Desc:    "cgroup root path, default value /sys/fs/cgroup",
+					Default: "/sys/fs/cgroup",
 				},
 			},
-			ActionFlags:    []spec.ExpFlagSpec{},
 			ActionExecutor: &ProcessLoadExecutor{},
 			ActionExample: `
 # create 10 process as user test
-blade c process load --count 10 --user test`,
+blade c process load --count 10 --user test
+
+# create the processes until 80% of kernel.pid_max or kernel.threads-max is used
+blade c process load --percent 80
+
+# create the threads until 90% of pids.max of the container is used
+blade c process load --percent 90 --threads --container-id 5d9d1bd9d2a4`,
 			ActionPrograms:    []string{ProcessLoadBin},
 			ActionCategories:  []string{category.SystemProcess},
 			ActionProcessHang: true,
@@ -78,7 +113,8 @@ func (l *ProcessLoadActionCommandSpec) LongDesc() string {
 	if l.ActionLongDesc != "" {
 		return l.ActionLongDesc
 	}
-	return "create process load by ping"
+	return "create process load by the sleeping child processes or the threads to exhaust the pids. " +
+		"The children are tagged with the experiment uid and only they are killed by destroy"
 }
 
 func (*ProcessLoadActionCommandSpec) Categories() []string {
@@ -93,107 +129,258 @@ func (pl *ProcessLoadExecutor) Name() string {
 	return "load"
 }
 
-var localChannel = channel.NewLocalChannel()
-
 func (pl *ProcessLoadExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return pl.stop(ctx, uid)
+	}
 	countStr := model.ActionFlags["count"]
+	percentStr := model.ActionFlags["percent"]
 	userName := model.ActionFlags["user"]
+	threads := model.ActionFlags["threads"] == spec.True
 
-	commands := []string{"ping", "ulimit"}
-	if response, ok := pl.channel.IsAllCommandsAvailable(ctx, commands); !ok {
-		return response
-	}
-
+	var credential *syscall.Credential
 	if userName != "" {
-		_, err := user.Lookup(userName)
+		u, err := user.Lookup(userName)
 		if err != nil {
 			log.Errorf(ctx, "could not find user: %v %v\n", userName, err)
 			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "user", userName, "is invalid")
 		}
+		userId, _ := strconv.ParseUint(u.Uid, 10, 32)
+		groupId, _ := strconv.ParseUint(u.Gid, 10, 32)
+		credential = &syscall.Credential{Uid: uint32(userId), Gid: uint32(groupId)}
 	}
 
-	if _, ok := spec.IsDestroy(ctx); ok {
-		return pl.stop(ctx, userName)
+	count := 0
+	if countStr != "" {
+		var err error
+		count, err = strconv.Atoi(countStr)
+		if err != nil {
+			log.Errorf(ctx, "count is not a number")
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "count", countStr, "is not a number")
+		}
+		if count < 0 {
+			log.Errorf(ctx, "count < 0, count is not a illegal parameter")
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "count", count, "must be a positive integer")
+		}
 	}
-
-	response := localChannel.Run(ctx, "ulimit", "-u")
-	if !response.Success {
-		log.Errorf(ctx, "process load, run ulimit err: %s", response.Err)
-		return spec.ResponseFailWithFlags(spec.ActionNotSupport, "command ulimit is not support")
+	percent := 0
+	if percentStr != "" {
+		var err error
+		percent, err = strconv.Atoi(percentStr)
+		if err != nil || percent <= 0 || percent > 100 {
+			log.Errorf(ctx, "`%s`: process-load-percent is illegal", percentStr)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "percent", percentStr, "it must be in 1..100")
+		}
+		if count > 0 {
+			log.Errorf(ctx, "process-load-count and percent cannot be used together")
+			return spec.ResponseFailWithFlags(spec.ParameterInvalid, "count|percent", countStr+percentStr, "cannot be used together")
+		}
 	}
-
-	reStr := strings.TrimSpace(response.Result.(string))
-	if reStr == "unlimited" {
-		log.Errorf(ctx, "proc is unlimited!")
-		return spec.ResponseFailWithFlags(spec.ActionNotSupport, "proc is unlimited!")
+	// the load without limit takes all pids of the host, then even the destroy command cannot be forked,
+	// and the go runtime aborts if it fails to create the thread
+	if count == 0 && percent == 0 {
+		log.Errorf(ctx, "process-load-less count or percent")
+		return spec.ResponseFailWithFlags(spec.ParameterLess, "count|percent")
 	}
-
-	_, err := strconv.Atoi(reStr)
-	if err != nil {
-		return spec.ResponseFailWithFlags(spec.ActionNotSupport, err, "proc is invalid!")
+	// the threads are created in chaos_os itself, they cannot run as other user
+	if threads && userName != "" {
+		log.Errorf(ctx, "process-load-user cannot be used with the threads")
+		return spec.ResponseFailWithFlags(spec.ParameterInvalid, "user", userName, "cannot be used with the threads flag")
 	}
 
-	if countStr == "" {
-		countStr = "0"
-	}
-	count, err := strconv.Atoi(countStr)
-	if err != nil {
-		log.Errorf(ctx, "count is not a number")
-		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "count", count, "is not a number")
+	// the children are created in the target cgroup, so they are limited by its pids.max
+	ctx, response := exec.JoinTargetCgroup(ctx, model.ActionFlags)
+	if response != nil {
+		return response
 	}
-	if count < 0 {
-		log.Errorf(ctx, "count < 0, count is not a illegal parameter")
-		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "count", count, "must be a positive integer")
+	if percent > 0 || threads {
+		limit, current, err := tasksLimit(model.ActionFlags["cgroup-root"], model.ActionFlags[exec.CgroupPathFlag.Name],
+			model.ActionFlags[exec.ContainerIdFlag.Name])
+		if err != nil {
+			log.Errorf(ctx, "process-load-get the tasks limit err, %v", err)
+			if percent > 0 {
+				return spec.ResponseFailWithFlags(spec.ParameterInvalid, "percent", percentStr, err)
+			}
+			return spec.ResponseFailWithFlags(spec.ParameterInvalid, "threads", spec.True, err)
+		}
+		if percent > 0 {
+			count = int(limit*int64(percent)/100 - current)
+			if count <= 0 {
+				log.Errorf(ctx, "process-load-%d tasks are in use, they exceed %d%% of the limit %d", current, percent, limit)
+				return spec.ResponseFailWithFlags(spec.ParameterInvalid, "percent", percentStr,
+					fmt.Sprintf("%d tasks are in use, they exceed %d%% of the limit %d", current, percent, limit))
+			}
+		}
+		if threads {
+			// the go runtime aborts if it fails to create the thread, so the threads are capped below the limit
+			headroom := threadsHeadroom(limit, current)
+			if headroom <= 0 {
+				log.Errorf(ctx, "process-load-%d tasks are in use, no thread can be created under the limit %d", current, limit)
+				return spec.ResponseFailWithFlags(spec.ParameterInvalid, "threads", spec.True,
+					fmt.Sprintf("%d tasks are in use, no thread can be created under the limit %d", current, limit))
+			}
+			if count > headroom {
+				log.Warnf(ctx, "process-load-%d threads exceed the headroom of the limit %d, %d threads are created", count, limit, headroom)
+				count = headroom
+			}
+			return pl.startThreads(ctx, count)
+		}
 	}
-
-	return pl.start(ctx, count, userName)
+	return pl.startProcesses(ctx, uid, count, credential)
 }
 
 func (pl *ProcessLoadExecutor) SetChannel(channel spec.Channel) {
 	pl.channel = channel
 }
 
-func (pl *ProcessLoadExecutor) start(ctx context.Context, count int, userName string) *spec.Response {
-	if count == 0 {
-		if userName != "" {
-			go loopProc(ctx, fmt.Sprintf("su -c 'ping 127.0.0.1 > /dev/null' %s", userName))
-		} else {
-			go loopProc(ctx, fmt.Sprintf("ping 127.0.0.1 > /dev/null"))
+// processLoadTag is the environment variable of the sleeping children of the experiment, which is used to kill
+// them exactly. The command line is not changed, because the applet of busybox is selected by argv[0].
+func processLoadTag(uid string) string {
+	return fmt.Sprintf("CHAOS_PROCESS_LOAD_UID=%s", uid)
+}
+
+// startProcesses creates the sleeping children until the count is reached. The creation is retried if it fails,
+// so the pids released by the other processes are taken, and the children which exit are created again.
+func (pl *ProcessLoadExecutor) startProcesses(ctx context.Context, uid string, count int, credential *syscall.Credential) *spec.Response {
+	sleep, err := osExec.LookPath("sleep")
+	if err != nil {
+		log.Errorf(ctx, "process-load-sleep not found, %v", err)
+		return spec.ResponseFailWithFlags(spec.OsCmdExecFailed, "sleep", err)
+	}
+	tag := processLoadTag(uid)
+	children := &loadChildren{pids: make(map[int]bool)}
+	go children.reap(ctx)
+	reached := false
+	for {
+		if alive := children.alive(); alive >= count {
+			if !reached {
+				log.Infof(ctx, "process-load-%d processes are created", alive)
+				reached = true
+			}
+			time.Sleep(processLoadRetryInterval)
+			continue
+		}
+		reached = false
+		command := &osExec.Cmd{
+			Path:        sleep,
+			Args:        []string{sleep, processLoadSleep},
+			Env:         []string{tag},
+			SysProcAttr: &syscall.SysProcAttr{Credential: credential},
 		}
-		select {}
-	} else {
-		for i := 0; i < count; i++ {
-			if userName != "" {
-				simpleProc(ctx, fmt.Sprintf("su -c 'ping 127.0.0.1 > /dev/null' %s", userName))
-			} else {
-				simpleProc(ctx, fmt.Sprintf("ping 127.0.0.1 > /dev/null"))
+		if err := children.start(command); err != nil {
+			log.Debugf(ctx, "process-load-create the process after %d processes err, %v", children.alive(), err)
+			time.Sleep(processLoadRetryInterval)
+		}
+	}
+}
+
+// loadChildren is the sleeping children which are alive
+type loadChildren struct {
+	mu   sync.Mutex
+	pids map[int]bool
+}
+
+// start starts the child, the lock is held until it is added, so it is not reaped before
+func (c *loadChildren) start(command *osExec.Cmd) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := command.Start(); err != nil {
+		return err
+	}
+	c.pids[command.Process.Pid] = true
+	return nil
+}
+
+func (c *loadChildren) alive() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pids)
+}
+
+// reap reaps the exited children, so they do not hold the pids, and they are not counted as alive.
+// The children are reaped by one goroutine, because each goroutine waiting a child holds an os thread,
+// which takes a pid too.
+func (c *loadChildren) reap(ctx context.Context) {
+	for {
+		var status syscall.WaitStatus
+		pid, err := syscall.Wait4(-1, &status, 0, nil)
+		if err != nil {
+			if err != syscall.EINTR {
+				time.Sleep(processLoadRetryInterval)
 			}
+			continue
+		}
+		c.mu.Lock()
+		child := c.pids[pid]
+		delete(c.pids, pid)
+		c.mu.Unlock()
+		// the sleeping children exit only if they are killed, so the exited child is not created again at once
+		if child && !status.Signaled() {
+			log.Warnf(ctx, "process-load-the child %d exits with %d", pid, status.ExitStatus())
+			time.Sleep(processLoadRetryInterval)
 		}
 	}
-	return spec.ReturnSuccess(ctx.Value(spec.Uid))
 }
 
-func (pl *ProcessLoadExecutor) stop(ctx context.Context, userName string) *spec.Response {
-	if userName != "" {
-		simpleProc(ctx, fmt.Sprintf("su -c \"ps aux | grep 'ping 127' | grep -v grep | awk '{print $2}' | xargs kill -9\" %s", userName))
+// threadsHeadroom returns the threads which can be created in chaos_os under the tasks limit,
+// the threads of the go runtime are reserved, such as the threads running the goroutines and the sysmon
+func threadsHeadroom(limit, current int64) int {
+	return int(limit - current - int64(runtime.GOMAXPROCS(0)) - 8)
+}
+
+// startThreads creates the threads which block until chaos_os is killed
+func (pl *ProcessLoadExecutor) startThreads(ctx context.Context, count int) *spec.Response {
+	debug.SetMaxThreads(count + 10000)
+	block := make(chan struct{})
+	for i := 0; i < count; i++ {
+		go func() {
+			// the thread locked by the blocked goroutine is not reused by the others
+			runtime.LockOSThread()
+			<-block
+		}()
 	}
-	simpleProc(ctx, "ps aux | grep 'ping 127' | grep -v grep | awk '{print $2}' | xargs kill -9")
+	log.Infof(ctx, "process-load-%d threads are created", count)
+	select {}
+}
+
+// stop kills chaos_os and the sleeping children tagged with the uid
+func (pl *ProcessLoadExecutor) stop(ctx context.Context, uid string) *spec.Response {
 	ctx = context.WithValue(ctx, "bin", ProcessLoadBin)
-	return exec.Destroy(ctx, pl.channel, "process load")
+	response := exec.Destroy(ctx, pl.channel, "process load")
+	if !response.Success {
+		return response
+	}
+	pids := processLoadChildren(uid)
+	if len(pids) == 0 {
+		return response
+	}
+	// the children run on the host, so they are killed by the host channel
+	return exec.HostChannel(pl.channel).Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
 }
 
-func simpleProc(ctx context.Context, cmd string) {
-	if err := osExec.Command("/bin/bash", "-c", cmd).Start(); err != nil {
-		log.Errorf(ctx, "exec command: %s failed: %s", cmd, err)
+// processLoadChildren returns the pids of the sleeping children of the experiment
+func processLoadChildren(uid string) []string {
+	pids := make([]string, 0)
+	processes, err := process.Processes()
+	if err != nil {
+		return pids
 	}
+	for _, p := range processes {
+		if environ, err := p.Environ(); err == nil && isProcessLoadChild(environ, uid) && p.Pid != int32(os.Getpid()) {
+			pids = append(pids, strconv.Itoa(int(p.Pid)))
+		}
+	}
+	return pids
 }
 
-func loopProc(ctx context.Context, cmd string) {
-	for {
-		if err := osExec.Command("/bin/bash", "-c", cmd).Start(); err != nil {
-			log.Errorf(ctx, "exec command: %s failed: %s", cmd, err)
-			break
+// isProcessLoadChild returns true if the environment is of the sleeping child of the experiment,
+// it contains the tag with the uid
+func isProcessLoadChild(environ []string, uid string) bool {
+	tag := processLoadTag(uid)
+	for _, env := range environ {
+		if env == tag {
+			return true
 		}
-		time.Sleep(100 * time.Millisecond)
 	}
+	return false
 }
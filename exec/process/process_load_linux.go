@@ -0,0 +1,88 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+)
+
+// tasksLimit returns the max number of the tasks and the tasks in use. The pids.max of the target cgroup is used
+// if it is limited, otherwise the less of kernel.pid_max and kernel.threads-max with all tasks on the host.
+func tasksLimit(cgroupRoot, cgroupPath, containerId string) (int64, int64, error) {
+	if cgroupPath != "" || containerId != "" {
+		target, err := exec.ResolveTargetCgroup(cgroupRoot, cgroupPath, containerId)
+		if err != nil {
+			return 0, 0, err
+		}
+		// the limits of the host are used if the pids controller is not enabled for the cgroup
+		if bytes, err := os.ReadFile(target.PidsFile("pids.max")); err == nil {
+			limit, limited, err := parsePidsMax(string(bytes))
+			if err != nil {
+				return 0, 0, err
+			}
+			if limited {
+				current, err := readProcSysInt(target.PidsFile("pids.current"))
+				return limit, current, err
+			}
+		}
+	}
+	limit, err := readProcSysInt("/proc/sys/kernel/pid_max")
+	if err != nil {
+		return 0, 0, err
+	}
+	threadsMax, err := readProcSysInt("/proc/sys/kernel/threads-max")
+	if err != nil {
+		return 0, 0, err
+	}
+	if threadsMax < limit {
+		limit = threadsMax
+	}
+	bytes, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0, 0, err
+	}
+	current, err := parseLoadavgTasks(string(bytes))
+	return limit, current, err
+}
+
+// parsePidsMax parses the pids.max of the cgroup, the second value is false if it is max, which means not limited
+func parsePidsMax(content string) (int64, bool, error) {
+	value := strings.TrimSpace(content)
+	if value == "max" {
+		return 0, false, nil
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("the pids.max %s is illegal", value)
+	}
+	return limit, true, nil
+}
+
+// parseLoadavgTasks returns the total scheduling entities of the loadavg,
+// the fourth field is the running and total entities, such as 1/234
+func parseLoadavgTasks(content string) (int64, error) {
+	fields := strings.Fields(content)
+	if len(fields) < 4 || !strings.Contains(fields[3], "/") {
+		return 0, fmt.Errorf("the loadavg %s is illegal", content)
+	}
+	return strconv.ParseInt(fields[3][strings.Index(fields[3], "/")+1:], 10, 64)
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import "testing"
+
+func TestParsePidsMax(t *testing.T) {
+	tests := []struct {
+		content string
+		limit   int64
+		limited bool
+	}{
+		{content: "max\n", limit: 0, limited: false},
+		{content: "1024\n", limit: 1024, limited: true},
+		{content: "0", limit: 0, limited: true},
+	}
+	for _, tt := range tests {
+		limit, limited, err := parsePidsMax(tt.content)
+		if err != nil || limit != tt.limit || limited != tt.limited {
+			t.Errorf("parsePidsMax(%q) = %d, %t, %v, want %d, %t", tt.content, limit, limited, err, tt.limit, tt.limited)
+		}
+	}
+	if _, _, err := parsePidsMax("unlimited"); err == nil {
+		t.Errorf("parsePidsMax() expects err for the illegal value")
+	}
+}
+
+func TestParseLoadavgTasks(t *testing.T) {
+	tasks, err := parseLoadavgTasks("0.52 0.58 0.59 3/1234 56789\n")
+	if err != nil || tasks != 1234 {
+		t.Errorf("parseLoadavgTasks() = %d, %v, want 1234", tasks, err)
+	}
+	for _, illegal := range []string{"", "0.52 0.58 0.59", "0.52 0.58 0.59 1234 56789", "0.52 0.58 0.59 3/x 56789"} {
+		if _, err := parseLoadavgTasks(illegal); err == nil {
+			t.Errorf("parseLoadavgTasks(%q) expects err", illegal)
+		}
+	}
+}
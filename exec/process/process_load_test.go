@@ -0,0 +1,69 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+)
+
+func TestIsProcessLoadChild(t *testing.T) {
+	tests := []struct {
+		environ []string
+		expect  bool
+	}{
+		{environ: []string{"CHAOS_PROCESS_LOAD_UID=7c7b0f3a8d1e4c2b"}, expect: true},
+		{environ: []string{"PATH=/usr/bin", "CHAOS_PROCESS_LOAD_UID=7c7b0f3a8d1e4c2b"}, expect: true},
+		{environ: []string{"CHAOS_PROCESS_LOAD_UID=0e4d5e0879b36773"}, expect: false},
+		{environ: []string{"CHAOS_PROCESS_LOAD_UID=7c7b0f3a8d1e4c2b0"}, expect: false},
+		{environ: []string{"CHAOS_PROCESS_LOAD_UID="}, expect: false},
+		{environ: nil, expect: false},
+	}
+	for _, tt := range tests {
+		if got := isProcessLoadChild(tt.environ, "7c7b0f3a8d1e4c2b"); got != tt.expect {
+			t.Errorf("isProcessLoadChild(%v) = %t, want %t", tt.environ, got, tt.expect)
+		}
+	}
+}
+
+func TestProcessLoadIllegalFlags(t *testing.T) {
+	executor := &ProcessLoadExecutor{}
+	for _, flags := range []map[string]string{
+		{},
+		{"threads": spec.True},
+		{"count": "10", "threads": spec.True, "user": "root"},
+		{"count": "10", "percent": "50"},
+	} {
+		response := executor.Exec("7c7b0f3a8d1e4c2b", context.Background(), &spec.ExpModel{ActionFlags: flags})
+		if response.Success {
+			t.Errorf("Exec() with flags %v should fail", flags)
+		}
+	}
+}
+
+func TestThreadsHeadroom(t *testing.T) {
+	reserve := runtime.GOMAXPROCS(0) + 8
+	if headroom := threadsHeadroom(1000, 100); headroom != 900-reserve {
+		t.Errorf("threadsHeadroom(1000, 100) = %d, want %d", headroom, 900-reserve)
+	}
+	if headroom := threadsHeadroom(100, 100); headroom > 0 {
+		t.Errorf("threadsHeadroom(100, 100) = %d, want no headroom", headroom)
+	}
+}
@@ -0,0 +1,331 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+)
+
+const ScheduleProcessBin = "chaos_scheduleprocess"
+
+// schedulePolicies are the scheduling policies of /proc/<pid>/stat, they are named as the options of chrt.
+// SCHED_DEADLINE is not included because its parameters are not in /proc/<pid>/stat.
+var schedulePolicies = map[string]string{
+	"0": "other",
+	"1": "fifo",
+	"2": "rr",
+	"3": "batch",
+	"5": "idle",
+}
+
+type ScheduleProcessActionCommandSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewScheduleProcessActionCommandSpec() spec.ExpActionCommandSpec {
+	return &ScheduleProcessActionCommandSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "process",
+					Desc: "Process name, Separate multiple process with commas (,)",
+				},
+				&spec.ExpFlag{
+					Name: "process-cmd",
+					Desc: "Process name in command",
+				},
+				&spec.ExpFlag{
+					Name: "local-port",
+					Desc: "Local service ports. Separate multiple ports with commas (,) or connector representing ranges, for example: 80,8000-8080",
+				},
+				&spec.ExpFlag{
+					Name: "pid",
+					Desc: "pid",
+				},
+				&spec.ExpFlag{
+					Name: "count",
+					Desc: "Limit count, 0 means unlimited",
+				},
+				&spec.ExpFlag{
+					Name: "exclude-process",
+					Desc: "Exclude process",
+				},
+			},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "nice",
+					Desc: "The nice value of the process, from -20 (the highest priority) to 19 (the lowest priority)",
+				},
+				&spec.ExpFlag{
+					Name: "policy",
+					Desc: "The scheduling policy of the process, idle, batch or other",
+				},
+				&spec.ExpFlag{
+					Name: "cpu-list",
+					Desc: "The CPUs the process is restricted to, such as 0,3 or 1-3",
+				},
+			},
+			ActionExecutor: &ScheduleProcessExecutor{},
+			ActionExample: `
+# Lower the priority of the nginx processes to the lowest
+blade create process schedule --process nginx --nice 19
+
+# Run the java process only when the CPUs are idle
+blade create process schedule --process-cmd java --policy idle
+
+# Restrict the process listening on the port 8080 to the CPU 0
+blade create process schedule --local-port 8080 --cpu-list 0`,
+			ActionPrograms:   []string{ScheduleProcessBin},
+			ActionCategories: []string{category.SystemProcess},
+		},
+	}
+}
+
+func (*ScheduleProcessActionCommandSpec) Name() string {
+	return "schedule"
+}
+
+func (*ScheduleProcessActionCommandSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*ScheduleProcessActionCommandSpec) ShortDesc() string {
+	return "Change the CPU scheduling of process"
+}
+
+func (s *ScheduleProcessActionCommandSpec) LongDesc() string {
+	if s.ActionLongDesc != "" {
+		return s.ActionLongDesc
+	}
+	return "Change the nice value, the scheduling policy or the CPU affinity of all threads of the processes to simulate " +
+		"a misconfigured or noisy host without creating load. The values of the main thread are recorded and restored " +
+		"to all threads when the experiment is destroyed"
+}
+
+func (*ScheduleProcessActionCommandSpec) Categories() []string {
+	return []string{category.SystemProcess}
+}
+
+type ScheduleProcessExecutor struct {
+	channel spec.Channel
+}
+
+func (spe *ScheduleProcessExecutor) Name() string {
+	return "schedule"
+}
+
+func (spe *ScheduleProcessExecutor) SetChannel(channel spec.Channel) {
+	spe.channel = channel
+}
+
+// schedule is the scheduling of the process, the empty value is not changed
+type schedule struct {
+	Nice     string
+	Policy   string
+	Priority string
+	Cpus     string
+}
+
+func (spe *ScheduleProcessExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return restoreSchedules(ctx, spe.channel)
+	}
+	var target schedule
+	commands := make([]string, 0)
+	if nice := model.ActionFlags["nice"]; nice != "" {
+		value, err := strconv.Atoi(nice)
+		if err != nil || value < -20 || value > 19 {
+			log.Errorf(ctx, "`%s`: process-schedule-nice is illegal", nice)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "nice", nice, "it must be in -20..19")
+		}
+		target.Nice = nice
+		commands = append(commands, "renice")
+	}
+	if policy := model.ActionFlags["policy"]; policy != "" {
+		if policy != "idle" && policy != "batch" && policy != "other" {
+			log.Errorf(ctx, "`%s`: process-schedule-policy is illegal", policy)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "policy", policy, "it must be idle, batch or other")
+		}
+		target.Policy, target.Priority = policy, "0"
+		commands = append(commands, "chrt")
+	}
+	if cpuList := model.ActionFlags["cpu-list"]; cpuList != "" {
+		cores, err := util.ParseIntegerListToStringSlice("cpu-list", cpuList)
+		if err != nil || len(cores) == 0 {
+			log.Errorf(ctx, "`%s`: process-schedule-cpu-list is illegal, %v", cpuList, err)
+			return spec.ResponseFailWithFlags(spec.ParameterIllegal, "cpu-list", cpuList, "it must be the CPUs such as 0,3 or 1-3")
+		}
+		target.Cpus = strings.Join(cores, ",")
+		commands = append(commands, "taskset")
+	}
+	if len(commands) == 0 {
+		log.Errorf(ctx, "process-schedule-less nice, policy and cpu-list")
+		return spec.ResponseFailWithFlags(spec.ParameterLess, "nice|policy|cpu-list")
+	}
+	if response, ok := spe.channel.IsAllCommandsAvailable(ctx, commands); !ok {
+		return response
+	}
+	response := getPids(ctx, spe.channel, model, uid)
+	if !response.Success {
+		return response
+	}
+	pids, _ := response.Result.(string)
+	for _, pid := range strings.Fields(pids) {
+		if changed := changeSchedule(ctx, spe.channel, pid, target); !changed.Success {
+			restoreSchedules(ctx, spe.channel)
+			return changed
+		}
+	}
+	return spec.ReturnSuccess(pids)
+}
+
+// readSchedule reads the scheduling of the main thread of the process
+func readSchedule(ctx context.Context, cl spec.Channel, pid string) (schedule, error) {
+	response := cl.Run(ctx, "cat", fmt.Sprintf("/proc/%s/stat", pid))
+	if !response.Success {
+		return schedule{}, fmt.Errorf("read the stat of the process %s err, %s", pid, response.Err)
+	}
+	stat, _ := response.Result.(string)
+	original, err := parseScheduleStat(stat)
+	if err != nil {
+		return original, err
+	}
+	response = cl.Run(ctx, "grep", fmt.Sprintf("Cpus_allowed_list /proc/%s/status", pid))
+	if !response.Success {
+		return schedule{}, fmt.Errorf("read the status of the process %s err, %s", pid, response.Err)
+	}
+	status, _ := response.Result.(string)
+	fields := strings.Fields(status)
+	if len(fields) != 2 {
+		return schedule{}, fmt.Errorf("the cpus allowed of the process %s is not found", pid)
+	}
+	original.Cpus = fields[1]
+	return original, nil
+}
+
+// parseScheduleStat parses the nice, the real-time priority and the policy of /proc/<pid>/stat, which are the fields
+// 19, 40 and 41. The fields are counted from the end of the command name, which may contain spaces.
+func parseScheduleStat(stat string) (schedule, error) {
+	end := strings.LastIndex(stat, ")")
+	if end < 0 {
+		return schedule{}, fmt.Errorf("the stat %s is illegal", stat)
+	}
+	// the first field after the command name is the field 3
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 39 {
+		return schedule{}, fmt.Errorf("the stat %s is illegal", stat)
+	}
+	policy, ok := schedulePolicies[fields[38]]
+	if !ok {
+		return schedule{}, fmt.Errorf("the scheduling policy %s is not supported", fields[38])
+	}
+	return schedule{Nice: fields[16], Priority: fields[37], Policy: policy}, nil
+}
+
+// setSchedule changes the scheduling of all threads of the process
+func setSchedule(ctx context.Context, cl spec.Channel, pid string, s schedule) *spec.Response {
+	if s.Cpus != "" {
+		if response := cl.Run(ctx, "taskset", fmt.Sprintf("-a -p -c %s %s", s.Cpus, pid)); !response.Success {
+			return response
+		}
+	}
+	if s.Policy != "" {
+		if response := cl.Run(ctx, "chrt", fmt.Sprintf("-a --%s -p %s %s", s.Policy, s.Priority, pid)); !response.Success {
+			return response
+		}
+	}
+	if s.Nice != "" {
+		// the nice value is per thread, so all threads are reniced
+		response := cl.Run(ctx, "ls", fmt.Sprintf("/proc/%s/task", pid))
+		if !response.Success {
+			return response
+		}
+		tids, _ := response.Result.(string)
+		return cl.Run(ctx, "renice", fmt.Sprintf("%s -p %s", s.Nice, strings.Join(strings.Fields(tids), " ")))
+	}
+	return spec.Success()
+}
+
+// changeSchedule records the original scheduling of the process and changes it, only the changed values are recorded
+func changeSchedule(ctx context.Context, cl spec.Channel, pid string, target schedule) *spec.Response {
+	original, err := readSchedule(ctx, cl, pid)
+	if err != nil {
+		log.Errorf(ctx, "process-changeSchedule-%v", err)
+		return spec.ResponseFailWithFlags(spec.OsCmdExecFailed, "cat", err)
+	}
+	if response := setSchedule(ctx, cl, pid, target); !response.Success {
+		log.Errorf(ctx, "process-changeSchedule-change the scheduling of the process %s err, %s", pid, response.Err)
+		// the values changed before the failure are restored
+		setSchedule(ctx, cl, pid, restoredSchedule(original, target))
+		return response
+	}
+	attrs := map[string]string{}
+	restored := restoredSchedule(original, target)
+	for name, value := range map[string]string{"nice": restored.Nice, "policy": restored.Policy,
+		"priority": restored.Priority, "cpus": restored.Cpus} {
+		if value != "" {
+			attrs[name] = value
+		}
+	}
+	recordProcessResource(ctx, store.ResourceSchedule, pid, attrs)
+	return spec.Success()
+}
+
+// restoredSchedule returns the original values of the changed ones
+func restoredSchedule(original, target schedule) schedule {
+	var restored schedule
+	if target.Nice != "" {
+		restored.Nice = original.Nice
+	}
+	if target.Policy != "" {
+		restored.Policy, restored.Priority = original.Policy, original.Priority
+	}
+	if target.Cpus != "" {
+		restored.Cpus = original.Cpus
+	}
+	return restored
+}
+
+// restoreSchedules restores the recorded scheduling of the processes which are still running
+func restoreSchedules(ctx context.Context, cl spec.Channel) *spec.Response {
+	response := spec.Success()
+	for _, resource := range store.Resources(ctx, store.ResourceSchedule) {
+		if _, ok := resource.Attrs["createTime"]; ok && !store.PidAlive(resource) {
+			continue
+		}
+		original := schedule{
+			Nice:     resource.Attrs["nice"],
+			Policy:   resource.Attrs["policy"],
+			Priority: resource.Attrs["priority"],
+			Cpus:     resource.Attrs["cpus"],
+		}
+		if restored := setSchedule(ctx, cl, resource.Value, original); !restored.Success {
+			log.Errorf(ctx, "process-restoreSchedules-restore the scheduling of the process %s err, %s", resource.Value, restored.Err)
+			response = restored
+		}
+	}
+	return response
+}
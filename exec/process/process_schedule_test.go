@@ -0,0 +1,31 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import "testing"
+
+func TestParseScheduleStat(t *testing.T) {
+	stat := "4397 (my (app) x) S 1 4397 1 0 -1 4194560 1026 0 0 0 3 1 0 0 20 -5 4 0 1792205 25870336 2361 " +
+		"18446744073709551615 1 1 0 0 0 0 0 16781312 2 0 0 0 17 0 7 3 0 0 0 0 0 0 0 0 0 0 0\n"
+	s, err := parseScheduleStat(stat)
+	if err != nil || s.Nice != "-5" || s.Priority != "7" || s.Policy != "batch" {
+		t.Errorf("parseScheduleStat() = %+v, %v", s, err)
+	}
+	if _, err := parseScheduleStat("4397 (app) S 1 2 3"); err == nil {
+		t.Errorf("parseScheduleStat() expects err for the short stat")
+	}
+}
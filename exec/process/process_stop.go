@@ -19,7 +19,14 @@ package process
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 )
 
@@ -62,7 +69,29 @@ func NewStopProcessActionCommandSpec() spec.ExpActionCommandSpec {
 					Desc: "pid",
 				},
 			},
-			ActionFlags:    []spec.ExpFlagSpec{},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "freeze-duration",
+					Desc: "Freeze the processes for the duration and resume them for the interval alternately until the experiment is destroyed, for example 500ms, the number without unit is seconds",
+				},
+				&spec.ExpFlag{
+					Name: "interval",
+					Desc: "The duration to resume the processes between two freezes, it is required with the freeze-duration flag",
+				},
+				&spec.ExpFlag{
+					Name: "cgroup-path",
+					Desc: "Freeze all processes of the cgroup by the cgroup freezer instead of the processes matched, the path is relative to the cgroup root, for example /kubepods/burstable/pod1/c1",
+				},
+				&spec.ExpFlag{
+					Name: "container-id",
+					Desc: "Freeze all processes of the docker or containerd container by the cgroup freezer",
+				},
+				&spec.ExpFlag{
+					Name:    "cgroup-root",
+					Desc:    "cgroup root path, default value /sys/fs/cgroup",
+					Default: "/sys/fs/cgroup",
+				},
+			},
 			ActionExecutor: &StopProcessExecutor{},
 			ActionExample: `
 # Pause the process that contains the "SimpleHTTPServer" keyword
@@ -72,7 +101,16 @@ blade create process stop --process SimpleHTTPServer,top
 blade create process stop --process-cmd java
 
 # Return success even if the process not found
-blade create process stop --process demo --ignore-not-found`,
+blade create process stop --process demo --ignore-not-found
+
+# Pause the java process for 500ms every 2 seconds to simulate the GC pauses
+blade create process stop --process-cmd java --freeze-duration 500ms --interval 1500ms
+
+# Freeze all processes of the container atomically
+blade create process stop --container-id 5d9d1bd9d2a4
+
+# Freeze the cgroup for 3 seconds every 10 seconds
+blade create process stop --cgroup-path /kubepods/burstable/pod1/c1 --freeze-duration 3s --interval 7s`,
 			ActionPrograms:   []string{StopProcessBin},
 			ActionCategories: []string{category.SystemProcess},
 		},
@@ -107,16 +145,97 @@ func (spe *StopProcessExecutor) Name() string {
 }
 
 func (spe *StopProcessExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
-	resp := getPids(ctx, spe.channel, model, uid)
-	if !resp.Success {
-		return resp
-	}
-	pids := resp.Result.(string)
 	if _, ok := spec.IsDestroy(ctx); ok {
-		return spe.channel.Run(ctx, "kill", fmt.Sprintf("-CONT %s", pids))
+		return spe.stop(ctx, model, uid)
+	}
+	freezeDurationValue := model.ActionFlags["freeze-duration"]
+	intervalValue := model.ActionFlags["interval"]
+	freezeDuration, err := exec.ParseDuration(freezeDurationValue)
+	if err != nil {
+		log.Errorf(ctx, "`%s`: freeze-duration is illegal, %v", freezeDurationValue, err)
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "freeze-duration", freezeDurationValue, err)
+	}
+	interval, err := exec.ParseDuration(intervalValue)
+	if err != nil {
+		log.Errorf(ctx, "`%s`: interval is illegal, %v", intervalValue, err)
+		return spec.ResponseFailWithFlags(spec.ParameterIllegal, "interval", intervalValue, err)
+	}
+	flapping := freezeDuration > 0 || interval > 0
+	if flapping && (freezeDuration == 0 || interval == 0) {
+		log.Errorf(ctx, "freeze-duration and interval must be specified together")
+		return spec.ResponseFailWithFlags(spec.ParameterLess, "freeze-duration|interval")
+	}
+
+	var cgroups []frozenCgroup
+	var pids string
+	cgroupPath, containerId := model.ActionFlags["cgroup-path"], model.ActionFlags["container-id"]
+	if cgroupPath != "" || containerId != "" {
+		cgroup, err := resolveFreezerCgroup(model.ActionFlags["cgroup-root"], cgroupPath, containerId)
+		if err != nil {
+			log.Errorf(ctx, "resolve the freezer cgroup err, %v", err)
+			return spec.ResponseFailWithFlags(spec.ParameterInvalid, "cgroup-path|container-id", cgroupPath+containerId, err)
+		}
+		cgroups = append(cgroups, cgroup)
 	} else {
+		resp := getPids(ctx, spe.channel, model, uid)
+		if !resp.Success {
+			return resp
+		}
+		pids = resp.Result.(string)
+	}
+
+	if !flapping {
+		if len(cgroups) > 0 {
+			return freezeCgroups(ctx, spe.channel, cgroups)
+		}
+		recordPids(ctx, pids)
 		return spe.channel.Run(ctx, "kill", fmt.Sprintf("-STOP %s", pids))
 	}
+	// the freeze loop signals the processes on the host
+	if ctx.Value(channel.NSPidFlagName) == spec.True {
+		log.Errorf(ctx, "freeze-duration is not supported for the processes in the pid namespace of the container")
+		return spec.ResponseFailWithFlags(spec.ParameterInvalid, "freeze-duration", freezeDurationValue,
+			"not supported for the processes in the pid namespace of the container")
+	}
+	config := freezeLoopConfig{Cgroups: cgroups, FreezeDuration: freezeDuration, Interval: interval}
+	for _, pid := range strings.Fields(pids) {
+		p, err := strconv.Atoi(pid)
+		if err != nil {
+			continue
+		}
+		// the process without start time has exited, its pid may be reused during the experiment
+		if process := store.NewProcessResource(store.ResourcePid, p); process.Attrs["createTime"] != "" {
+			config.Processes = append(config.Processes, process)
+		}
+	}
+	recordPids(ctx, pids)
+	recordFrozenCgroups(ctx, cgroups)
+	return startFreezeLoop(ctx, spe.channel, uid, config)
+}
+
+// stop stops the freeze loop and resumes the processes or the cgroups frozen at creation,
+// the processes are resolved again only if nothing is recorded
+func (spe *StopProcessExecutor) stop(ctx context.Context, model *spec.ExpModel, uid string) *spec.Response {
+	if response := stopFreezeLoop(ctx, spe.channel); !response.Success {
+		log.Warnf(ctx, "stop the freeze loop err, %s", response.Err)
+	}
+	cgroups := store.Resources(ctx, store.ResourceFrozenCgroup)
+	response := thawCgroups(ctx, spe.channel)
+	if resources := store.Resources(ctx, store.ResourcePid); len(resources) > 0 {
+		pids := store.AlivePids(ctx)
+		if len(pids) == 0 {
+			return response
+		}
+		return spe.channel.Run(ctx, "kill", fmt.Sprintf("-CONT %s", strings.Join(pids, " ")))
+	}
+	if len(cgroups) > 0 {
+		return response
+	}
+	resp := getPids(ctx, spe.channel, model, uid)
+	if !resp.Success {
+		return resp
+	}
+	return spe.channel.Run(ctx, "kill", fmt.Sprintf("-CONT %s", resp.Result.(string)))
 }
 
 func (spe *StopProcessExecutor) SetChannel(channel spec.Channel) {
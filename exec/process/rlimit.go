@@ -0,0 +1,133 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+)
+
+// rlimitNames are the rows of /proc/<pid>/limits of the resources, the resources are named as the options of prlimit
+var rlimitNames = map[string]string{
+	"nofile": "Max open files",
+	"nproc":  "Max processes",
+	"as":     "Max address space",
+	"core":   "Max core file size",
+	"stack":  "Max stack size",
+}
+
+// rlimit is the soft and hard limit of the resource, the value is a number or unlimited
+type rlimit struct {
+	Soft string
+	Hard string
+}
+
+// readRlimit reads the limit of the resource of the process from /proc/<pid>/limits
+func readRlimit(ctx context.Context, cl spec.Channel, pid, resource string) (rlimit, error) {
+	response := cl.Run(ctx, "cat", fmt.Sprintf("/proc/%s/limits", pid))
+	if !response.Success {
+		return rlimit{}, fmt.Errorf("read the limits of the process %s err, %s", pid, response.Err)
+	}
+	limits, _ := response.Result.(string)
+	return parseRlimit(limits, resource)
+}
+
+// parseRlimit parses the limit of the resource from the content of /proc/<pid>/limits
+func parseRlimit(limits, resource string) (rlimit, error) {
+	name := rlimitNames[resource]
+	for _, line := range strings.Split(limits, "\n") {
+		if !strings.HasPrefix(line, name) {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, name))
+		if len(fields) < 2 {
+			break
+		}
+		return rlimit{Soft: fields[0], Hard: fields[1]}, nil
+	}
+	return rlimit{}, fmt.Errorf("the limit of %s is not found", resource)
+}
+
+// parseRlimitValue parses the limit in the format of prlimit, which is soft:hard, soft: or :hard to change one of them,
+// or a single value to change both of them. The value is a number or unlimited.
+func parseRlimitValue(value string) (rlimit, error) {
+	soft, hard := value, value
+	if index := strings.Index(value, ":"); index >= 0 {
+		soft, hard = value[:index], value[index+1:]
+	}
+	if soft == "" && hard == "" {
+		return rlimit{}, fmt.Errorf("the limit %s is empty", value)
+	}
+	for _, v := range []string{soft, hard} {
+		if v == "" || v == "unlimited" {
+			continue
+		}
+		if _, err := strconv.ParseUint(v, 10, 64); err != nil {
+			return rlimit{}, fmt.Errorf("the limit %s must be a number or unlimited", v)
+		}
+	}
+	return rlimit{Soft: soft, Hard: hard}, nil
+}
+
+// setRlimit changes the limit of the resource of the process by prlimit, the empty soft or hard limit is unchanged
+func setRlimit(ctx context.Context, cl spec.Channel, pid, resource string, limit rlimit) *spec.Response {
+	return cl.Run(ctx, "prlimit", fmt.Sprintf("--pid %s --%s=%s:%s", pid, resource, limit.Soft, limit.Hard))
+}
+
+// changeRlimit records the original limit of the resource of the process and changes it,
+// the original limit is restored by restoreRlimits
+func changeRlimit(ctx context.Context, cl spec.Channel, pid, resource string, limit rlimit) *spec.Response {
+	original, err := readRlimit(ctx, cl, pid, resource)
+	if err != nil {
+		log.Errorf(ctx, "process-changeRlimit-%v", err)
+		return spec.ResponseFailWithFlags(spec.OsCmdExecFailed, "cat", err)
+	}
+	if response := setRlimit(ctx, cl, pid, resource, limit); !response.Success {
+		log.Errorf(ctx, "process-changeRlimit-set the %s limit of the process %s err, %s", resource, pid, response.Err)
+		return response
+	}
+	recordProcessResource(ctx, store.ResourceRlimit, pid, map[string]string{
+		"resource": resource,
+		"soft":     original.Soft,
+		"hard":     original.Hard,
+		"limit":    limit.Soft + ":" + limit.Hard,
+	})
+	return spec.Success()
+}
+
+// restoreRlimits restores the recorded limits of the processes which are still running
+func restoreRlimits(ctx context.Context, cl spec.Channel) *spec.Response {
+	response := spec.Success()
+	for _, resource := range store.Resources(ctx, store.ResourceRlimit) {
+		if _, ok := resource.Attrs["createTime"]; ok && !store.PidAlive(resource) {
+			continue
+		}
+		original := rlimit{Soft: resource.Attrs["soft"], Hard: resource.Attrs["hard"]}
+		if restored := setRlimit(ctx, cl, resource.Value, resource.Attrs["resource"], original); !restored.Success {
+			log.Errorf(ctx, "process-restoreRlimits-restore the %s limit of the process %s err, %s",
+				resource.Attrs["resource"], resource.Value, restored.Err)
+			response = restored
+		}
+	}
+	return response
+}
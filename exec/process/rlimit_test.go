@@ -0,0 +1,54 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package process
+
+import "testing"
+
+func TestParseRlimit(t *testing.T) {
+	limits := `Limit                     Soft Limit           Hard Limit           Units     
+Max cpu time              unlimited            unlimited            seconds   
+Max open files            1024                 1048576              files     
+Max locked memory         8388608              8388608              bytes     
+`
+	limit, err := parseRlimit(limits, "nofile")
+	if err != nil || limit.Soft != "1024" || limit.Hard != "1048576" {
+		t.Errorf("parseRlimit() = %+v, %v", limit, err)
+	}
+	if _, err := parseRlimit("Limit  Soft Limit  Hard Limit  Units\n", "nofile"); err == nil {
+		t.Errorf("parseRlimit() expects err if the limit is not found")
+	}
+}
+
+func TestParseRlimitValue(t *testing.T) {
+	for value, expect := range map[string]rlimit{
+		"1024":            {Soft: "1024", Hard: "1024"},
+		"1024:":           {Soft: "1024"},
+		":4096":           {Hard: "4096"},
+		"0:unlimited":     {Soft: "0", Hard: "unlimited"},
+		"unlimited":       {Soft: "unlimited", Hard: "unlimited"},
+		"1048576:8388608": {Soft: "1048576", Hard: "8388608"},
+	} {
+		if limit, err := parseRlimitValue(value); err != nil || limit != expect {
+			t.Errorf("parseRlimitValue(%s) = %+v, %v, want %+v", value, limit, err, expect)
+		}
+	}
+	for _, illegal := range []string{"", ":", "abc", "-1:", "1:2:3"} {
+		if _, err := parseRlimitValue(illegal); err == nil {
+			t.Errorf("parseRlimitValue(%s) expects err", illegal)
+		}
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"github.com/chaosblade-io/chaosblade-exec-os/exec"
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/dryrun"
 	"github.com/chaosblade-io/chaosblade-spec-go/log"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 	_ "github.com/go-sql-driver/mysql"
@@ -112,6 +113,13 @@ func (sde *ScriptExecuteExecutor) Exec(uid string, ctx context.Context, model *s
 	}
 	downloadUrl := model.ActionFlags["downloadUrl"]
 	uploadUrl := model.ActionFlags["uploadUrl"]
+	// the script is downloaded, extracted and run on the host, its changes can not be recorded
+	if _, ok := sde.channel.(*dryrun.RecordChannel); ok {
+		if _, destroy := spec.IsDestroy(ctx); !destroy || downloadUrl != "" {
+			log.Errorf(ctx, "script-execute-exec-dry-run is not supported")
+			return spec.ResponseFailWithFlags(spec.ActionNotSupport, "script execute with dry-run")
+		}
+	}
 	scriptFile := model.ActionFlags["file"]
 	fileArgs := model.ActionFlags["file-args"]
 	if fileArgs != "" {
@@ -181,7 +189,7 @@ func (sde *ScriptExecuteExecutor) start(ctx context.Context, scriptFile, fileArg
 			scriptMain = tarDistDir + "/recover.py"
 			isPythonFlag = true
 		} else {
-			log.Errorf(ctx, "script-execute-start `%s`,recover script file is not exist in tar")
+			log.Errorf(ctx, "script-execute-start `%s`,recover script file is not exist in tar", tarDistDir)
 			return spec.ResponseFailWithFlags(spec.ParameterInvalid, "recover script file  is not exist in tar")
 		}
 	} else {
@@ -191,7 +199,7 @@ func (sde *ScriptExecuteExecutor) start(ctx context.Context, scriptFile, fileArg
 			scriptMain = tarDistDir + "/main.py"
 			isPythonFlag = true
 		} else {
-			log.Errorf(ctx, "script-execute-start `%s`,script file is not exist in tar")
+			log.Errorf(ctx, "script-execute-start `%s`,script file is not exist in tar", tarDistDir)
 			return spec.ResponseFailWithFlags(spec.ParameterInvalid, "script file  is not exist in tar")
 		}
 	}
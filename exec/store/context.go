@@ -0,0 +1,126 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/shirou/gopsutil/process"
+)
+
+type recordKey struct{}
+
+type record struct {
+	store      *Store
+	experiment *Experiment
+}
+
+// WithExperiment binds the experiment record to the context, so that executors can
+// register the resources they touch and read them back on destroy.
+// The record is kept in memory only if the store is nil.
+func WithExperiment(ctx context.Context, s *Store, experiment *Experiment) context.Context {
+	return context.WithValue(ctx, recordKey{}, &record{store: s, experiment: experiment})
+}
+
+// ExperimentFrom returns the experiment record bound to the context, or nil
+func ExperimentFrom(ctx context.Context) *Experiment {
+	if r, ok := ctx.Value(recordKey{}).(*record); ok {
+		return r.experiment
+	}
+	return nil
+}
+
+// Record appends the resource to the experiment record and persists it immediately,
+// it does nothing if no record is bound to the context
+func Record(ctx context.Context, resource Resource) {
+	r, ok := ctx.Value(recordKey{}).(*record)
+	if !ok {
+		return
+	}
+	if r.store == nil {
+		r.experiment.Resources = append(r.experiment.Resources, resource)
+		return
+	}
+	r.store.mu.Lock()
+	r.experiment.Resources = append(r.experiment.Resources, resource)
+	r.store.mu.Unlock()
+	if err := r.store.Save(r.experiment); err != nil {
+		log.Warnf(ctx, "save %s resource %s for experiment %s err, %v", resource.Kind, resource.Value, r.experiment.Uid, err)
+	}
+}
+
+// Resources returns the recorded resources of the kind
+func Resources(ctx context.Context, kind string) []Resource {
+	experiment := ExperimentFrom(ctx)
+	if experiment == nil {
+		return []Resource{}
+	}
+	return experiment.ResourcesOf(kind)
+}
+
+// RecordPid records the pid together with its start time to detect pid reuse
+func RecordPid(ctx context.Context, pid int) {
+	Record(ctx, NewProcessResource(ResourcePid, pid))
+}
+
+// NewProcessResource returns the process resource of the kind, the start time of the process
+// is kept to detect pid reuse
+func NewProcessResource(kind string, pid int) Resource {
+	resource := Resource{Kind: kind, Value: strconv.Itoa(pid)}
+	if p, err := process.NewProcess(int32(pid)); err == nil {
+		if createTime, err := p.CreateTime(); err == nil {
+			resource.Attrs = map[string]string{"createTime": strconv.FormatInt(createTime, 10)}
+		}
+	}
+	return resource
+}
+
+// AlivePids returns the recorded pids which are still running and not reused by other processes
+func AlivePids(ctx context.Context) []string {
+	pids := make([]string, 0)
+	for _, resource := range Resources(ctx, ResourcePid) {
+		if PidAlive(resource) {
+			pids = append(pids, resource.Value)
+		}
+	}
+	return pids
+}
+
+// PidAlive returns true if the recorded process is still running
+func PidAlive(resource Resource) bool {
+	pid, err := strconv.Atoi(resource.Value)
+	if err != nil {
+		return false
+	}
+	p, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return false
+	}
+	if recorded, ok := resource.Attrs["createTime"]; ok {
+		createTime, err := p.CreateTime()
+		if err != nil || strconv.FormatInt(createTime, 10) != recorded {
+			return false
+		}
+	}
+	// the killed process is a zombie until it is reaped by its parent
+	if status, err := p.Status(); err == nil && status == "Z" {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+)
+
+// ResourceStatus is the recorded resource together with its state on the host
+type ResourceStatus struct {
+	Resource
+	InEffect bool `json:"inEffect"`
+}
+
+// ExperimentStatus is the experiment record together with its state on the host
+type ExperimentStatus struct {
+	Uid        string            `json:"uid"`
+	Target     string            `json:"target"`
+	Action     string            `json:"action"`
+	Flags      map[string]string `json:"flags,omitempty"`
+	Status     string            `json:"status"`
+	Error      string            `json:"error,omitempty"`
+	InEffect   bool              `json:"inEffect"`
+	Resources  []ResourceStatus  `json:"resources,omitempty"`
+	CreateTime string            `json:"createTime"`
+	UpdateTime string            `json:"updateTime"`
+}
+
+// CheckEffect checks whether the resources of the experiment are still present on the host.
+// Only the created or successful experiments are checked, the others are never in effect.
+// An experiment without checkable resources is in effect as long as its status is Success.
+func CheckEffect(ctx context.Context, cl spec.Channel, experiment *Experiment) *ExperimentStatus {
+	status := &ExperimentStatus{
+		Uid:        experiment.Uid,
+		Target:     experiment.Target,
+		Action:     experiment.Action,
+		Flags:      experiment.Flags,
+		Status:     experiment.Status,
+		Error:      experiment.Error,
+		Resources:  make([]ResourceStatus, 0, len(experiment.Resources)),
+		CreateTime: experiment.CreateTime,
+		UpdateTime: experiment.UpdateTime,
+	}
+	active := experiment.Status == StatusSuccess || experiment.Status == StatusCreated
+	checked := false
+	for _, resource := range experiment.Resources {
+		inEffect, ok := false, true
+		if active {
+			inEffect, ok = resourceInEffect(ctx, cl, resource)
+		}
+		status.Resources = append(status.Resources, ResourceStatus{Resource: resource, InEffect: inEffect})
+		if ok && active {
+			checked = true
+			status.InEffect = status.InEffect || inEffect
+		}
+	}
+	if !checked {
+		status.InEffect = experiment.Status == StatusSuccess
+	}
+	return status
+}
+
+// resourceInEffect returns whether the resource is still present on the host,
+// the second value is false if the kind of resource cannot tell the experiment state
+func resourceInEffect(ctx context.Context, cl spec.Channel, resource Resource) (bool, bool) {
+	switch resource.Kind {
+	case ResourcePid, ResourceFreezeLoop:
+		return PidAlive(resource), true
+	case ResourceRlimit, ResourceSchedule:
+		// the limit or the scheduling is changed until the process exits, the process in other pid namespace cannot be verified
+		if _, ok := resource.Attrs["createTime"]; !ok {
+			return false, false
+		}
+		return PidAlive(resource), true
+	case ResourceFrozenCgroup:
+		response := cl.Run(ctx, "cat", resource.Value)
+		if !response.Success || util.IsNil(response.Result) {
+			return false, true
+		}
+		// the v1 freezer state is FREEZING until all processes are frozen
+		state := strings.TrimSpace(response.Result.(string))
+		return state == resource.Attrs["freeze"] || state == "FREEZING", true
+	case ResourceQdisc:
+		response := cl.Run(ctx, "tc", fmt.Sprintf(`qdisc show dev %s`, resource.Value))
+		if !response.Success || util.IsNil(response.Result) {
+			return false, true
+		}
+		for _, line := range strings.Split(response.Result.(string), "\n") {
+			fields := strings.Fields(line)
+			// qdisc prio 1: root refcnt 2 bands 4 or qdisc netem 40: parent 1:4 limit 1000
+			if len(fields) < 4 || fields[0] != "qdisc" || fields[2] != resource.Attrs["handle"] {
+				continue
+			}
+			if fields[3] == resource.Attrs["parent"] || (len(fields) > 4 && fields[4] == resource.Attrs["parent"]) {
+				return true, true
+			}
+		}
+		return false, true
+	case ResourceIptables:
+		command := resource.Attrs["command"]
+		if command == "" {
+			command = "iptables"
+		}
+		return cl.Run(ctx, command, fmt.Sprintf(`-C %s`, resource.Value)).Success, true
+	case ResourceNftChain:
+		return cl.Run(ctx, "nft", fmt.Sprintf(`list chain %s`, resource.Value)).Success, true
+	case ResourceHostsEntry:
+		return cl.Run(ctx, "grep", fmt.Sprintf(`-qxF "%s" %s`, resource.Value, resource.Attrs["file"])).Success, true
+	case ResourceMovedFile:
+		return cl.Run(ctx, "test", fmt.Sprintf(`-e "%s" -a ! -e "%s"`, resource.Attrs["target"], resource.Value)).Success, true
+	case ResourceDmDevice:
+		return cl.Run(ctx, "dmsetup", fmt.Sprintf(`info %s`, resource.Value)).Success, true
+	case ResourcePermission:
+		response := cl.Run(ctx, "stat", fmt.Sprintf(`-c "%%a" "%s"`, resource.Value))
+		if !response.Success || util.IsNil(response.Result) {
+			return false, true
+		}
+		return strings.TrimSpace(response.Result.(string)) != resource.Attrs["mode"], true
+	}
+	return false, false
+}
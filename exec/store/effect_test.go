@@ -0,0 +1,73 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+)
+
+func TestCheckEffectOfProcess(t *testing.T) {
+	command := exec.Command("true")
+	if err := command.Start(); err != nil {
+		t.Skipf("start process err, %v", err)
+	}
+	deadPid := NewProcessResource(ResourcePid, command.Process.Pid)
+	if err := command.Wait(); err != nil {
+		t.Fatalf("wait process err, %v", err)
+	}
+	livePid := NewProcessResource(ResourcePid, os.Getpid())
+	reusedPid := Resource{Kind: ResourcePid, Value: livePid.Value, Attrs: map[string]string{"createTime": "1"}}
+
+	tests := []struct {
+		name     string
+		status   string
+		resource Resource
+		expect   bool
+	}{
+		{"live pid", StatusSuccess, livePid, true},
+		{"dead pid", StatusSuccess, deadPid, false},
+		{"reused pid", StatusSuccess, reusedPid, false},
+		{"destroyed record with live pid", StatusDestroyed, livePid, false},
+		{"failed record with live pid", StatusError, livePid, false},
+	}
+	cl := channel.NewLocalChannel()
+	for _, tt := range tests {
+		experiment := &Experiment{Uid: "7c7b0f3a8d1e4c2b", Status: tt.status, Resources: []Resource{tt.resource}}
+		status := CheckEffect(context.Background(), cl, experiment)
+		if status.InEffect != tt.expect {
+			t.Errorf("%s: in effect is %t, want %t", tt.name, status.InEffect, tt.expect)
+		}
+		if status.Status != tt.status {
+			t.Errorf("%s: status is %s, want %s", tt.name, status.Status, tt.status)
+		}
+	}
+}
+
+func TestCheckEffectWithoutCheckableResource(t *testing.T) {
+	cl := channel.NewLocalChannel()
+	for status, expect := range map[string]bool{StatusSuccess: true, StatusCreated: false, StatusDestroyed: false} {
+		experiment := &Experiment{Uid: "7c7b0f3a8d1e4c2b", Status: status, Resources: []Resource{{Kind: ResourceBackupFile, Value: "/tmp/x"}}}
+		if got := CheckEffect(context.Background(), cl, experiment).InEffect; got != expect {
+			t.Errorf("experiment of status %s: in effect is %t, want %t", status, got, expect)
+		}
+	}
+}
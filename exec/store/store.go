@@ -0,0 +1,235 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+)
+
+// ExperimentDir is the directory under the program path which holds the experiment records
+const ExperimentDir = "experiments"
+
+const (
+	StatusCreated   = "Created"
+	StatusSuccess   = "Success"
+	StatusError     = "Error"
+	StatusDestroyed = "Destroyed"
+)
+
+// DestroyedRetention is how long the record of a destroyed experiment is kept for the
+// status and list queries, the expired records are pruned when a new experiment is created
+const DestroyedRetention = 7 * 24 * time.Hour
+
+// Resource kinds recorded by the executors
+const (
+	ResourcePid          = "pid"
+	ResourceQdisc        = "qdisc"
+	ResourceIptables     = "iptables"
+	ResourceBackupFile   = "backup-file"
+	ResourcePermission   = "permission"
+	ResourceMovedFile    = "moved-file"
+	ResourceHostsEntry   = "hosts-entry"
+	ResourceWatchdog     = "watchdog"
+	ResourceDmDevice     = "dm-device"
+	ResourceLoopDevice   = "loop-device"
+	ResourceNftChain     = "nft-chain"
+	ResourceKill         = "kill"
+	ResourceFreezeLoop   = "freeze-loop"
+	ResourceFrozenCgroup = "frozen-cgroup"
+	ResourceRlimit       = "rlimit"
+	ResourceSchedule     = "schedule"
+)
+
+// Resource is a concrete host resource touched by an experiment,
+// for example a pid, a qdisc handle or an iptables rule
+type Resource struct {
+	Kind  string            `json:"kind"`
+	Value string            `json:"value"`
+	Attrs map[string]string `json:"attrs,omitempty"`
+}
+
+// Experiment is the persistent record of an experiment created by chaos_os
+type Experiment struct {
+	Uid        string            `json:"uid"`
+	Target     string            `json:"target"`
+	Action     string            `json:"action"`
+	Flags      map[string]string `json:"flags,omitempty"`
+	Resources  []Resource        `json:"resources,omitempty"`
+	Status     string            `json:"status"`
+	Error      string            `json:"error,omitempty"`
+	CreateTime string            `json:"createTime"`
+	UpdateTime string            `json:"updateTime"`
+}
+
+// ResourcesOf returns the resources of the kind
+func (e *Experiment) ResourcesOf(kind string) []Resource {
+	resources := make([]Resource, 0)
+	for _, r := range e.Resources {
+		if r.Kind == kind {
+			resources = append(resources, r)
+		}
+	}
+	return resources
+}
+
+// Store persists the experiment records as json files, one file per uid
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore returns a store which keeps the records under the dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// NewDefaultStore returns the store under the chaos_os program path
+func NewDefaultStore() *Store {
+	return NewStore(path.Join(util.GetProgramPath(), ExperimentDir))
+}
+
+func (s *Store) recordFile(uid string) string {
+	return path.Join(s.dir, fmt.Sprintf("%s.json", uid))
+}
+
+// checkUid rejects the uid which can not be used as the record file name,
+// so that the record never points outside the store directory
+func checkUid(uid string) error {
+	if uid == "" {
+		return fmt.Errorf("uid is empty")
+	}
+	if strings.ContainsAny(uid, `/\`) || strings.Contains(uid, "..") {
+		return fmt.Errorf("uid %s is illegal, it must not contain path separator or '..'", uid)
+	}
+	return nil
+}
+
+// Get returns the experiment record by uid
+func (s *Store) Get(uid string) (*Experiment, error) {
+	if err := checkUid(uid); err != nil {
+		return nil, err
+	}
+	bytes, err := os.ReadFile(s.recordFile(uid))
+	if err != nil {
+		return nil, err
+	}
+	var experiment Experiment
+	if err := json.Unmarshal(bytes, &experiment); err != nil {
+		return nil, fmt.Errorf("unmarshal %s experiment record err, %v", uid, err)
+	}
+	return &experiment, nil
+}
+
+// Save writes the record atomically, the create time is filled if absent
+func (s *Store) Save(experiment *Experiment) error {
+	if err := checkUid(experiment.Uid); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().Format(time.RFC3339)
+	if experiment.CreateTime == "" {
+		experiment.CreateTime = now
+	}
+	experiment.UpdateTime = now
+	bytes, err := json.MarshalIndent(experiment, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	file := s.recordFile(experiment.Uid)
+	tmpFile := file + ".tmp"
+	if err := os.WriteFile(tmpFile, bytes, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, file)
+}
+
+// Remove deletes the record by uid
+func (s *Store) Remove(uid string) error {
+	if err := checkUid(uid); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := os.Remove(s.recordFile(uid))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List returns all records ordered by create time
+func (s *Store) List() ([]*Experiment, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Experiment{}, nil
+		}
+		return nil, err
+	}
+	experiments := make([]*Experiment, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		experiment, err := s.Get(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			continue
+		}
+		experiments = append(experiments, experiment)
+	}
+	sort.SliceStable(experiments, func(i, j int) bool {
+		return experiments[i].CreateTime < experiments[j].CreateTime
+	})
+	return experiments, nil
+}
+
+// Prune removes the records of the experiments destroyed before the retention,
+// the records of the experiments in effect or failed are always kept
+func (s *Store) Prune(retention time.Duration) error {
+	experiments, err := s.List()
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(-retention)
+	for _, experiment := range experiments {
+		if experiment.Status != StatusDestroyed {
+			continue
+		}
+		updateTime, err := time.Parse(time.RFC3339, experiment.UpdateTime)
+		if err != nil || updateTime.After(deadline) {
+			continue
+		}
+		if err := s.Remove(experiment.Uid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStoreSaveGetList(t *testing.T) {
+	s := NewStore(t.TempDir())
+	experiment := &Experiment{
+		Uid:    "9b2b9b0c8f2a4d6e",
+		Target: "network",
+		Action: "drop",
+		Flags:  map[string]string{"destination-port": "80"},
+		Status: StatusCreated,
+	}
+	if err := s.Save(experiment); err != nil {
+		t.Fatalf("save err, %v", err)
+	}
+	ctx := WithExperiment(context.Background(), s, experiment)
+	Record(ctx, Resource{Kind: ResourceIptables, Value: "INPUT -p tcp --dport 80 -j DROP"})
+	Record(ctx, Resource{Kind: ResourceQdisc, Value: "1:", Attrs: map[string]string{"device": "eth0"}})
+
+	got, err := s.Get(experiment.Uid)
+	if err != nil {
+		t.Fatalf("get err, %v", err)
+	}
+	if got.Target != "network" || got.Action != "drop" || got.Flags["destination-port"] != "80" {
+		t.Errorf("unexpected record %+v", got)
+	}
+	if rules := got.ResourcesOf(ResourceIptables); len(rules) != 1 || rules[0].Value != "INPUT -p tcp --dport 80 -j DROP" {
+		t.Errorf("unexpected iptables resources %+v", rules)
+	}
+	if qdiscs := got.ResourcesOf(ResourceQdisc); len(qdiscs) != 1 || qdiscs[0].Attrs["device"] != "eth0" {
+		t.Errorf("unexpected qdisc resources %+v", qdiscs)
+	}
+
+	experiments, err := s.List()
+	if err != nil || len(experiments) != 1 {
+		t.Fatalf("list returns %d records, err %v", len(experiments), err)
+	}
+	if err := s.Remove(experiment.Uid); err != nil {
+		t.Fatalf("remove err, %v", err)
+	}
+	if _, err := s.Get(experiment.Uid); err == nil {
+		t.Errorf("record should be removed")
+	}
+}
+
+func TestRecordWithoutExperiment(t *testing.T) {
+	ctx := context.Background()
+	Record(ctx, Resource{Kind: ResourcePid, Value: "1"})
+	if resources := Resources(ctx, ResourcePid); len(resources) != 0 {
+		t.Errorf("unexpected resources %+v", resources)
+	}
+}
+
+func TestStoreRejectIllegalUid(t *testing.T) {
+	s := NewStore(t.TempDir())
+	for _, uid := range []string{"", "../../etc/x", "a/b", `a\b`, ".."} {
+		if err := s.Save(&Experiment{Uid: uid, Status: StatusCreated}); err == nil {
+			t.Errorf("save with uid %q should fail", uid)
+		}
+		if _, err := s.Get(uid); err == nil {
+			t.Errorf("get with uid %q should fail", uid)
+		}
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	s := NewStore(t.TempDir())
+	for _, experiment := range []*Experiment{
+		{Uid: "expired", Status: StatusDestroyed},
+		{Uid: "recent", Status: StatusDestroyed},
+		{Uid: "running", Status: StatusSuccess},
+	} {
+		if err := s.Save(experiment); err != nil {
+			t.Fatalf("save err, %v", err)
+		}
+	}
+	expired, _ := s.Get("expired")
+	running, _ := s.Get("running")
+	// backdate the records, Save always refreshes the update time
+	for _, experiment := range []*Experiment{expired, running} {
+		experiment.UpdateTime = time.Now().Add(-2 * DestroyedRetention).Format(time.RFC3339)
+		bytes, _ := json.Marshal(experiment)
+		if err := os.WriteFile(s.recordFile(experiment.Uid), bytes, 0644); err != nil {
+			t.Fatalf("write err, %v", err)
+		}
+	}
+	if err := s.Prune(DestroyedRetention); err != nil {
+		t.Fatalf("prune err, %v", err)
+	}
+	if _, err := s.Get("expired"); err == nil {
+		t.Errorf("expired destroyed record should be pruned")
+	}
+	for _, uid := range []string{"recent", "running"} {
+		if _, err := s.Get(uid); err != nil {
+			t.Errorf("record %s should be kept, %v", uid, err)
+		}
+	}
+}
@@ -4,18 +4,36 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/dryrun"
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/model"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/network"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/process"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
 	"github.com/chaosblade-io/chaosblade-spec-go/channel"
 	"github.com/chaosblade-io/chaosblade-spec-go/log"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 	"github.com/chaosblade-io/chaosblade-spec-go/util"
-	"os"
 )
 
 var executors = model.GetAllOsExecutors()
 var models = model.GetAllExpModels()
 var modelMap = make(map[string]spec.ExpModelCommandSpec)
 var modelActionFlags = make(map[string][]spec.ExpFlag)
+var experimentStore = store.NewDefaultStore()
+
+const (
+	statusMode = "status"
+	listMode   = "list"
+	queryMode  = "query"
+	// childMode creates the child started by the experiment, such as the cpu burning a core of the cpu list,
+	// the child is recorded by its parent experiment, so it has no record of its own
+	childMode = "start"
+)
 
 func init() {
 	for _, commandSpec := range models {
@@ -49,6 +67,8 @@ func init() {
 			modelActionFlags[commandSpec.Name()+modelAction.Name()] = append(
 				append(flags, append(xes, matchers...)...),
 				model.UidFlag,
+				model.TimeoutFlag,
+				model.DryRunFlag,
 				model.ChannelFlag,
 				model.NsTargetFlag,
 				model.NsPidFlag,
@@ -62,91 +82,370 @@ func init() {
 
 func main() {
 	args := os.Args
-	if len(args) < 4 {
+	if len(args) > 1 {
+		switch args[1] {
+		case statusMode, listMode, queryMode:
+			util.InitLog(util.Bin)
+			exitAndPrint(queryExperiments(args[1], args[2:]), 0)
+		case watchdogMode:
+			watchdog(args[2:])
+		case network.DnsStubMode:
+			network.RunDnsStub(args[2:])
+		case process.ProcessFreezeMode:
+			process.RunFreezeLoop(args[2:])
+		case process.ProcessFdHoldMode:
+			process.RunFdHold(args[2:])
+		}
+	}
+	if len(args) < 3 {
 		exitAndPrint(spec.ReturnFail(spec.OsCmdExecFailed, fmt.Sprintf("invalid parameter, %v", args)), 0)
+	}
+	mode := args[1]
+	child := mode == childMode && len(args) > 2 && args[2] == spec.Create
+	if child {
+		args = append(args[:1:1], args[2:]...)
+		mode = spec.Create
+	}
+	if mode != spec.Create && mode != spec.Destroy {
+		exitAndPrint(spec.ReturnFail(spec.OsCmdExecFailed, fmt.Sprintf("invalid parameter, %v", args)), 0)
+	}
+
+	// example => create cpu load cpu-percent=60
+	// example => destroy --uid 7c7b0f3a8d1e4c2b
+	var experiment *store.Experiment
+	var target, action string
+	var flagArgs []string
+	if mode == spec.Destroy && strings.HasPrefix(args[2], "-") {
+		experiment = getExperimentByUid(args[2:])
+		target, action, flagArgs = experiment.Target, experiment.Action, args[2:]
 	} else {
-		// example => create cpu load cpu-percent=60
-		mode := args[1]
-		target := args[2]
-		action := args[3]
+		if len(args) < 4 {
+			exitAndPrint(spec.ReturnFail(spec.OsCmdExecFailed, fmt.Sprintf("invalid parameter, %v", args)), 0)
+		}
+		target, action, flagArgs = args[2], args[3], args[4:]
+	}
 
-		// get expModel
-		expModel := &spec.ExpModel{
-			Target:     target,
-			ActionName: action,
-			ActionFlags: func() map[string]string {
-				flagsx := modelActionFlags[target+action]
+	actionFlags, specifiedFlags := parseActionFlags(target+action, flagArgs)
+	uid := actionFlags[model.UidFlag.Name]
 
-				flagsValues := make(map[string]*string, len(flagsx))
+	if mode == spec.Destroy {
+		if experiment == nil {
+			experiment, _ = experimentStore.Get(uid)
+		}
+		if experiment != nil {
+			// the flags not specified in destroy command are restored from the record
+			for k, v := range experiment.Flags {
+				if _, ok := specifiedFlags[k]; !ok {
+					actionFlags[k] = v
+				}
+			}
+		}
+	} else if uid == "" {
+		uid, _ = util.GenerateUid()
+		actionFlags[model.UidFlag.Name] = uid
+	}
 
-				cmd := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	// get expModel
+	expModel := &spec.ExpModel{
+		Target:      target,
+		ActionName:  action,
+		ActionFlags: actionFlags,
+	}
 
-				for _, f := range flagsx {
-					s := cmd.String(f.Name, f.Default, f.Desc)
-					flagsValues[f.Name] = s
-				}
+	ctx := context.WithValue(context.Background(), spec.Uid, uid)
+	if mode == spec.Destroy {
+		ctx = spec.SetDestroyFlag(ctx, uid)
+	}
 
-				if err := cmd.Parse(os.Args[4:]); err != nil {
-					exitAndPrint(spec.ReturnFail(spec.OsCmdExecFailed, fmt.Sprintf("invalid parameter, %v", err)), 0)
-				}
+	if expModel.ActionFlags[model.DebugFlag.Name] == spec.True {
+		util.Debug = true
+	}
+	util.InitLog(util.Bin)
+	log.Infof(ctx, "main-mode: %s, target: %s, action: %s, flags %v", mode, target, action, expModel.ActionFlags)
 
-				actionFlags := make(map[string]string, len(flagsx))
-				for k, v := range flagsValues {
-					actionFlags[k] = *v
-				}
-				return actionFlags
-			}(),
+	key := expModel.Target + expModel.ActionName
+	executor := executors[key]
+	if executor == nil {
+		exitAndPrint(spec.ReturnFail(spec.OsCmdExecFailed, fmt.Sprintf("not found executor, target: %s, action: %s", target, action)), 0)
+	}
+	ctx, cl := buildChannel(ctx, expModel.ActionFlags)
+	executor.SetChannel(cl)
+
+	if expModel.ActionFlags[model.DryRunFlag.Name] == spec.True {
+		exitAndPrint(dryRun(ctx, cl, executor, mode, expModel, experiment), 0)
+	}
+
+	if mode == spec.Create && !child {
+		experiment = &store.Experiment{
+			Uid:    uid,
+			Target: target,
+			Action: action,
+			Flags:  recordFlags(actionFlags),
+			Status: store.StatusCreated,
 		}
+		if err := experimentStore.Prune(store.DestroyedRetention); err != nil {
+			log.Warnf(ctx, "prune destroyed experiment records err, %v", err)
+		}
+	}
+	if experiment == nil {
+		exitAndPrint(executor.Exec(uid, ctx, expModel), 0)
+	}
+	ctx = store.WithExperiment(ctx, experimentStore, experiment)
+	if mode == spec.Destroy {
+		response := executor.Exec(uid, ctx, expModel)
+		if response.Success {
+			stopWatchdog(ctx)
+		}
+		updateExperiment(ctx, experiment, mode, response)
+		exitAndPrint(response, 0)
+	}
 
-		ctx := context.Background()
-		if mode != spec.Create && mode != spec.Destroy {
-			exitAndPrint(spec.ReturnFail(spec.OsCmdExecFailed, fmt.Sprintf("invalid parameter, %v", args)), 0)
+	timeout, err := exec.ParseDuration(actionFlags[model.TimeoutFlag.Name])
+	if err != nil {
+		log.Errorf(ctx, spec.ParameterIllegal.Sprintf(model.TimeoutFlag.Name, actionFlags[model.TimeoutFlag.Name], err))
+		exitAndPrint(spec.ResponseFailWithFlags(spec.ParameterIllegal, model.TimeoutFlag.Name, actionFlags[model.TimeoutFlag.Name], err), 0)
+	}
+	if isProcessHang(target, action) {
+		// the experiment is in effect as long as the current process is alive
+		experiment.Status = store.StatusSuccess
+		store.RecordPid(ctx, os.Getpid())
+		if timeout > 0 {
+			if err := startWatchdog(ctx, uid, timeout); err != nil {
+				log.Errorf(ctx, "start watchdog err, %v", err)
+				exitAndPrint(spec.ReturnFail(spec.OsCmdExecFailed, fmt.Sprintf("start watchdog err, %v", err)), 0)
+			}
 		}
+		response := executor.Exec(uid, ctx, expModel)
+		updateExperiment(ctx, experiment, mode, response)
+		exitAndPrint(response, 0)
+	}
+	response := executor.Exec(uid, ctx, expModel)
+	if response.Success && timeout > 0 {
+		if err := startWatchdog(ctx, uid, timeout); err != nil {
+			log.Errorf(ctx, "start watchdog err, %v", err)
+			response = spec.ReturnFail(spec.OsCmdExecFailed, fmt.Sprintf("experiment is created but start watchdog err, %v", err))
+		}
+	}
+	updateExperiment(ctx, experiment, mode, response)
+	exitAndPrint(response, 0)
+}
 
-		uid := expModel.ActionFlags[model.UidFlag.Name]
+// buildChannel returns the channel specified by the flags, the namespace flags are set to the context for nsexec channel
+func buildChannel(ctx context.Context, flags map[string]string) (context.Context, spec.Channel) {
+	if flags[model.ChannelFlag.Name] != spec.NSExecBin {
+		return ctx, channel.NewLocalChannel()
+	}
+	ctx = context.WithValue(ctx, model.NsTargetFlag.Name, flags[model.NsTargetFlag.Name])
 
-		ctx = context.WithValue(ctx, spec.Uid, uid)
-		if mode == spec.Destroy {
-			ctx = spec.SetDestroyFlag(ctx, uid)
+	if flags[model.NsPidFlag.Name] == spec.True {
+		ctx = context.WithValue(ctx, model.NsPidFlag.Name, spec.True)
+	}
+	if flags[model.NsMntFlag.Name] == spec.True {
+		ctx = context.WithValue(ctx, model.NsMntFlag.Name, spec.True)
+	}
+	if flags[model.NsNetFlag.Name] == spec.True {
+		ctx = context.WithValue(ctx, model.NsNetFlag.Name, spec.True)
+	}
+	return ctx, channel.NewNSExecChannel()
+}
+
+// queryExperiments reports the recorded experiments and whether they are still in effect
+// example => status 7c7b0f3a8d1e4c2b
+// example => list
+// example => query network delay
+func queryExperiments(mode string, args []string) *spec.Response {
+	switch mode {
+	case statusMode:
+		if len(args) < 1 {
+			return spec.ResponseFailWithFlags(spec.ParameterLess, model.UidFlag.Name)
+		}
+		experiment, err := experimentStore.Get(args[0])
+		if err != nil {
+			return spec.ResponseFailWithFlags(spec.DataNotFound, args[0])
+		}
+		return spec.ReturnSuccess(checkEffect(experiment))
+	case queryMode:
+		if len(args) < 2 {
+			return spec.ResponseFailWithFlags(spec.ParameterLess, "target|action")
+		}
+	}
+	experiments, err := experimentStore.List()
+	if err != nil {
+		return spec.ReturnFail(spec.OsCmdExecFailed, fmt.Sprintf("list experiments err, %v", err))
+	}
+	statuses := make([]*store.ExperimentStatus, 0, len(experiments))
+	for _, experiment := range experiments {
+		if mode == queryMode && (experiment.Target != args[0] || experiment.Action != args[1]) {
+			continue
+		}
+		statuses = append(statuses, checkEffect(experiment))
+	}
+	return spec.ReturnSuccess(statuses)
+}
+
+func checkEffect(experiment *store.Experiment) *store.ExperimentStatus {
+	ctx := context.WithValue(context.Background(), spec.Uid, experiment.Uid)
+	ctx, cl := buildChannel(ctx, experiment.Flags)
+	return store.CheckEffect(ctx, cl, experiment)
+}
+
+// dryRun returns the ordered operations of the experiment without executing them,
+// the destroy operations are returned as well for create mode
+// example => create network delay --time 3000 --interface eth0 --dry-run=true
+func dryRun(ctx context.Context, cl spec.Channel, executor spec.Executor, mode string,
+	expModel *spec.ExpModel, experiment *store.Experiment) *spec.Response {
+	uid := expModel.ActionFlags[model.UidFlag.Name]
+	if experiment == nil {
+		experiment = &store.Experiment{
+			Uid:    uid,
+			Target: expModel.Target,
+			Action: expModel.ActionName,
+			Flags:  recordFlags(expModel.ActionFlags),
+		}
+	}
+	// the record is not persisted
+	ctx = store.WithExperiment(ctx, nil, experiment)
+	result := make(map[string][]dryrun.Operation)
+	if mode == spec.Create {
+		recorder := dryrun.NewRecordChannel(cl)
+		if isProcessHang(expModel.Target, expModel.ActionName) {
+			// the hanging experiment runs in the chaos_os process itself
+			recorder.Record(dryrun.Operation{
+				Type:    dryrun.OperationProcess,
+				Command: fmt.Sprintf("%s %s %s %s %s", os.Args[0], spec.Create, expModel.Target, expModel.ActionName, formatFlags(experiment.Flags)),
+			})
+			result[spec.Create] = recorder.Operations()
+			result[spec.Destroy] = []dryrun.Operation{{
+				Type:    dryrun.OperationCommand,
+				Command: "kill -9 <pid of the chaos_os process>",
+			}}
+			return spec.ReturnSuccess(result)
 		} else {
-			if uid == "" {
-				uid, _ = util.GenerateUid()
+			executor.SetChannel(recorder)
+			if response := executor.Exec(uid, ctx, expModel); !response.Success {
+				return response
 			}
 		}
+		result[spec.Create] = recorder.Operations()
+		ctx = spec.SetDestroyFlag(ctx, uid)
+	}
+	recorder := dryrun.NewRecordChannel(cl)
+	executor.SetChannel(recorder)
+	if response := executor.Exec(uid, ctx, expModel); !response.Success {
+		return response
+	}
+	result[spec.Destroy] = recorder.Operations()
+	return spec.ReturnSuccess(result)
+}
 
-		if expModel.ActionFlags[model.DebugFlag.Name] == spec.True {
-			util.Debug = true
+func formatFlags(flags map[string]string) string {
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		if name != model.DryRunFlag.Name {
+			names = append(names, name)
 		}
-		util.InitLog(util.Bin)
-		log.Infof(ctx, "main-mode: %s, target: %s, action: %s, flags %v", mode, target, action, expModel.ActionFlags)
+	}
+	sort.Strings(names)
+	args := make([]string, len(names))
+	for i, name := range names {
+		args[i] = fmt.Sprintf(`--%s="%s"`, name, flags[name])
+	}
+	return strings.Join(args, " ")
+}
 
-		key := expModel.Target + expModel.ActionName
-		executor := executors[key]
-		if executor == nil {
-			exitAndPrint(spec.ReturnFail(spec.OsCmdExecFailed, fmt.Sprintf("not found executor, target: %s, action: %s", target, action)), 0)
-		} else {
-			if expModel.ActionFlags[model.ChannelFlag.Name] == spec.LocalChannel {
-				executor.SetChannel(channel.NewLocalChannel())
-			} else if expModel.ActionFlags[model.ChannelFlag.Name] == spec.NSExecBin {
+// parseActionFlags returns all action flags with the default values and the flags specified in the command line
+func parseActionFlags(key string, args []string) (map[string]string, map[string]struct{}) {
+	flagsx := modelActionFlags[key]
 
-				ctx = context.WithValue(ctx, model.NsTargetFlag.Name, expModel.ActionFlags[model.NsTargetFlag.Name])
+	flagsValues := make(map[string]*string, len(flagsx))
 
-				if expModel.ActionFlags[model.NsPidFlag.Name] == spec.True {
-					ctx = context.WithValue(ctx, model.NsPidFlag.Name, spec.True)
-				}
-				if expModel.ActionFlags[model.NsMntFlag.Name] == spec.True {
-					ctx = context.WithValue(ctx, model.NsMntFlag.Name, spec.True)
-				}
-				if expModel.ActionFlags[model.NsNetFlag.Name] == spec.True {
-					ctx = context.WithValue(ctx, model.NsNetFlag.Name, spec.True)
-				}
+	cmd := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 
-				executor.SetChannel(channel.NewNSExecChannel())
-			} else {
-				executor.SetChannel(channel.NewLocalChannel())
-			}
-			exitAndPrint(executor.Exec(uid, ctx, expModel), 0)
+	for _, f := range flagsx {
+		s := cmd.String(f.Name, f.Default, f.Desc)
+		flagsValues[f.Name] = s
+	}
+
+	if err := cmd.Parse(args); err != nil {
+		exitAndPrint(spec.ReturnFail(spec.OsCmdExecFailed, fmt.Sprintf("invalid parameter, %v", err)), 0)
+	}
+
+	actionFlags := make(map[string]string, len(flagsx))
+	for k, v := range flagsValues {
+		actionFlags[k] = *v
+	}
+	specifiedFlags := make(map[string]struct{})
+	cmd.Visit(func(f *flag.Flag) {
+		specifiedFlags[f.Name] = struct{}{}
+	})
+	return actionFlags, specifiedFlags
+}
+
+// getExperimentByUid loads the experiment record by the --uid flag in the args
+func getExperimentByUid(args []string) *store.Experiment {
+	uid := ""
+	for i, arg := range args {
+		name := strings.TrimLeft(arg, "-")
+		if name == model.UidFlag.Name && i+1 < len(args) {
+			uid = args[i+1]
+			break
 		}
+		if strings.HasPrefix(name, model.UidFlag.Name+"=") {
+			uid = strings.TrimPrefix(name, model.UidFlag.Name+"=")
+			break
+		}
+	}
+	if uid == "" {
+		exitAndPrint(spec.ResponseFailWithFlags(spec.ParameterLess, model.UidFlag.Name), 0)
+	}
+	experiment, err := experimentStore.Get(uid)
+	if err != nil {
+		exitAndPrint(spec.ResponseFailWithFlags(spec.DataNotFound, uid), 0)
+	}
+	return experiment
+}
+
+func isProcessHang(target, action string) bool {
+	commandSpec, ok := modelMap[target]
+	if !ok {
+		return false
+	}
+	for _, actionSpec := range commandSpec.Actions() {
+		if actionSpec.Name() == action {
+			return actionSpec.ProcessHang()
+		}
+	}
+	return false
+}
+
+// recordFlags returns the flags with value which are needed to rebuild the experiment
+func recordFlags(actionFlags map[string]string) map[string]string {
+	flags := make(map[string]string, len(actionFlags))
+	for k, v := range actionFlags {
+		if v == "" || k == model.DebugFlag.Name {
+			continue
+		}
+		flags[k] = v
+	}
+	return flags
+}
+
+func updateExperiment(ctx context.Context, experiment *store.Experiment, mode string, response *spec.Response) {
+	if response.Success {
+		experiment.Error = ""
+		if mode == spec.Destroy {
+			experiment.Status = store.StatusDestroyed
+		} else {
+			experiment.Status = store.StatusSuccess
+		}
+	} else {
+		experiment.Error = response.Err
+		if mode == spec.Create {
+			experiment.Status = store.StatusError
+		}
+	}
+	if err := experimentStore.Save(experiment); err != nil {
+		log.Warnf(ctx, "save experiment %s record err, %v", experiment.Uid, err)
 	}
 }
 
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+)
+
+func TestQueryExperiments(t *testing.T) {
+	defaultStore := experimentStore
+	defer func() { experimentStore = defaultStore }()
+	experimentStore = store.NewStore(t.TempDir())
+
+	for _, experiment := range []*store.Experiment{
+		{Uid: "running", Target: "cpu", Action: "fullload", Status: store.StatusSuccess,
+			Resources: []store.Resource{store.NewProcessResource(store.ResourcePid, os.Getpid())}},
+		{Uid: "destroyed", Target: "cpu", Action: "fullload", Status: store.StatusDestroyed,
+			Resources: []store.Resource{store.NewProcessResource(store.ResourcePid, os.Getpid())}},
+		{Uid: "delay", Target: "network", Action: "delay", Status: store.StatusSuccess},
+	} {
+		if err := experimentStore.Save(experiment); err != nil {
+			t.Fatalf("save err, %v", err)
+		}
+	}
+
+	for uid, expect := range map[string]bool{"running": true, "destroyed": false} {
+		response := queryExperiments(statusMode, []string{uid})
+		if !response.Success {
+			t.Fatalf("status %s failed, %s", uid, response.Err)
+		}
+		if inEffect := response.Result.(*store.ExperimentStatus).InEffect; inEffect != expect {
+			t.Errorf("status %s: in effect is %t, want %t", uid, inEffect, expect)
+		}
+	}
+	if response := queryExperiments(statusMode, []string{"missing"}); response.Success || response.Code != spec.DataNotFound.Code {
+		t.Errorf("status of missing uid returns %+v", response)
+	}
+	if response := queryExperiments(statusMode, nil); response.Success {
+		t.Errorf("status without uid should fail")
+	}
+
+	response := queryExperiments(listMode, nil)
+	if statuses := response.Result.([]*store.ExperimentStatus); len(statuses) != 3 {
+		t.Errorf("list returns %d experiments, want 3", len(statuses))
+	}
+	response = queryExperiments(queryMode, []string{"cpu", "fullload"})
+	if statuses := response.Result.([]*store.ExperimentStatus); len(statuses) != 2 {
+		t.Errorf("query cpu fullload returns %d experiments, want 2", len(statuses))
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"os"
+	osexec "os/exec"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/store"
+	"github.com/chaosblade-io/chaosblade-spec-go/log"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+)
+
+const watchdogMode = "watchdog"
+
+// startWatchdog starts a detached process which destroys the experiment when the timeout expires,
+// the watchdog survives the caller because it runs in a new session
+func startWatchdog(ctx context.Context, uid string, timeout time.Duration) error {
+	bin, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	command := osexec.Command(bin, watchdogMode, uid, timeout.String())
+	command.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := command.Start(); err != nil {
+		return err
+	}
+	store.Record(ctx, store.NewProcessResource(store.ResourceWatchdog, command.Process.Pid))
+	return command.Process.Release()
+}
+
+// stopWatchdog kills the watchdog of the experiment if it is destroyed by others
+func stopWatchdog(ctx context.Context) {
+	for _, resource := range store.Resources(ctx, store.ResourceWatchdog) {
+		pid, err := strconv.Atoi(resource.Value)
+		if err != nil || pid == os.Getppid() || !store.PidAlive(resource) {
+			continue
+		}
+		if process, err := os.FindProcess(pid); err == nil {
+			if err := process.Kill(); err != nil {
+				log.Warnf(ctx, "kill watchdog %d err, %v", pid, err)
+			}
+		}
+	}
+}
+
+// watchdog waits for the timeout and destroys the experiment if it is still not destroyed
+// example => watchdog 7c7b0f3a8d1e4c2b 10m0s
+func watchdog(args []string) {
+	util.InitLog(util.Bin)
+	if len(args) < 2 {
+		exitAndPrint(spec.ResponseFailWithFlags(spec.ParameterLess, "uid|timeout"), 1)
+	}
+	uid := args[0]
+	ctx := context.WithValue(context.Background(), spec.Uid, uid)
+	timeout, err := time.ParseDuration(args[1])
+	if err != nil {
+		exitAndPrint(spec.ResponseFailWithFlags(spec.ParameterIllegal, "timeout", args[1], err), 1)
+	}
+	time.Sleep(timeout)
+
+	experiment, err := experimentStore.Get(uid)
+	if err != nil {
+		log.Errorf(ctx, "watchdog-get experiment %s err, %v", uid, err)
+		os.Exit(1)
+	}
+	if experiment.Status == store.StatusDestroyed {
+		os.Exit(0)
+	}
+	bin, err := os.Executable()
+	if err != nil {
+		log.Errorf(ctx, "watchdog-get executable err, %v", err)
+		os.Exit(1)
+	}
+	output, err := osexec.Command(bin, spec.Destroy, "--uid", uid).CombinedOutput()
+	if err != nil {
+		log.Errorf(ctx, "watchdog-destroy experiment %s err, %v, output: %s", uid, err, string(output))
+		os.Exit(1)
+	}
+	log.Infof(ctx, "watchdog-destroy experiment %s after %s, result: %s", uid, timeout, string(output))
+	os.Exit(0)
+}